@@ -0,0 +1,270 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FormatVersion is the archive layout written by Backup. Restore accepts
+// archives up to this version.
+const FormatVersion = 1
+
+const manifestName = "manifest.json"
+
+// Collections are the collections saved in an archive. Charts are left out
+// because they are recomputed from the plays.
+var Collections = []string{"users", "tracks", "albums", "reviews", "plays", "playlists", "playback_states", "audit_log",
+	"webhooks", "webhook_deliveries", "jobs"}
+
+// Manifest describes the content of an archive. It is the first entry.
+type Manifest struct {
+	FormatVersion int            `json:"format_version"`
+	CreatedAt     time.Time      `json:"created_at"`
+	Since         *time.Time     `json:"since,omitempty"`
+	Until         *time.Time     `json:"until,omitempty"`
+	Collections   map[string]int `json:"collections"`
+	Media         []MediaFile    `json:"media"`
+}
+
+// MediaFile is an audio file stored in the archive for a track.
+type MediaFile struct {
+	TrackId     string `json:"track_id"`
+	Path        string `json:"path"`
+	ArchivePath string `json:"archive_path"`
+	Size        int64  `json:"size"`
+}
+
+type Options struct {
+	// Since and Until keep only documents last modified in that range.
+	Since *time.Time
+	Until *time.Time
+	// NoMedia leaves the audio files out of a backup or restore.
+	NoMedia bool
+	// DryRun reports what a restore would do without writing anything.
+	DryRun bool
+	// MediaDir restores the audio files below this directory, under their
+	// original path, instead of in place in the library roots. The tracks
+	// keep their original file_name.
+	MediaDir string
+}
+
+// keep reports whether a document modified at t passes the time filter.
+func (o *Options) keep(t time.Time) bool {
+	if o.Since != nil && t.Before(*o.Since) {
+		return false
+	}
+	if o.Until != nil && t.After(*o.Until) {
+		return false
+	}
+	return true
+}
+
+// modifiedAt is the best known modification time of a document: its
+// updated_at, stamped on every write, or its created_at field, or else the
+// creation time of its id.
+func modifiedAt(doc bson.M) time.Time {
+	for _, field := range []string{"updated_at", "created_at", "played_at", "at"} {
+		if t, ok := doc[field].(primitive.DateTime); ok {
+			return t.Time()
+		}
+	}
+	if id, ok := doc["_id"].(primitive.ObjectID); ok {
+		return id.Timestamp()
+	}
+	if hex, ok := doc["_id"].(string); ok {
+		if id, err := primitive.ObjectIDFromHex(hex); err == nil {
+			return id.Timestamp()
+		}
+	}
+	return time.Time{}
+}
+
+// Backup writes the collections of db, and the audio files of the tracks,
+// to w as a gzipped tar archive.
+func Backup(ctx context.Context, db *mongo.Database, w io.Writer, opts Options) (*Manifest, error) {
+	manifest := &Manifest{
+		FormatVersion: FormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		Since:         opts.Since,
+		Until:         opts.Until,
+		Collections:   map[string]int{},
+		Media:         []MediaFile{},
+	}
+
+	// Documents are staged in temporary files because tar needs the size
+	// of every entry up front, and the manifest goes first.
+	dir, err := os.MkdirTemp("", "musiclib-backup")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range Collections {
+		count, media, err := dumpCollection(ctx, db.Collection(name), filepath.Join(dir, name+".jsonl"), &opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		manifest.Collections[name] = count
+		if name == "tracks" && !opts.NoMedia {
+			manifest.Media = media
+		}
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeEntry(tw, manifestName, int64(len(data)), bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	for _, name := range Collections {
+		if err := writeFile(tw, path.Join("collections", name+".jsonl"), filepath.Join(dir, name+".jsonl")); err != nil {
+			return nil, err
+		}
+	}
+	for _, media := range manifest.Media {
+		if err := writeFile(tw, media.ArchivePath, media.Path); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, gz.Close()
+}
+
+// dumpCollection writes the documents of collection passing the time filter
+// as canonical extended JSON, one per line. For tracks it also returns the
+// audio files found on disk.
+func dumpCollection(ctx context.Context, collection *mongo.Collection, file string, opts *Options) (int, []MediaFile, error) {
+	f, err := os.Create(file)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+	out := bufio.NewWriter(f)
+
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	count := 0
+	media := []MediaFile{}
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return 0, nil, err
+		}
+		if !opts.keep(modifiedAt(doc)) {
+			continue
+		}
+		line, err := bson.MarshalExtJSON(cursor.Current, true, false)
+		if err != nil {
+			return 0, nil, err
+		}
+		out.Write(line)
+		out.WriteByte('\n')
+		count++
+
+		if file, ok := trackMedia(doc); ok {
+			media = append(media, file)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return 0, nil, err
+	}
+	return count, media, out.Flush()
+}
+
+// trackMedia returns the audio file of a track document if it is on disk.
+func trackMedia(doc bson.M) (MediaFile, bool) {
+	name, _ := doc["file_name"].(string)
+	if name == "" {
+		return MediaFile{}, false
+	}
+	info, err := os.Stat(name)
+	if err != nil || !info.Mode().IsRegular() {
+		return MediaFile{}, false
+	}
+	var id string
+	switch v := doc["_id"].(type) {
+	case primitive.ObjectID:
+		id = v.Hex()
+	case string:
+		id = v
+	default:
+		return MediaFile{}, false
+	}
+	return MediaFile{
+		TrackId:     id,
+		Path:        name,
+		ArchivePath: path.Join("media", id+filepath.Ext(name)),
+		Size:        info.Size(),
+	}, true
+}
+
+func writeFile(tw *tar.Writer, name string, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return writeEntry(tw, name, info.Size(), f)
+}
+
+func writeEntry(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    size,
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := io.CopyN(tw, r, size)
+	return err
+}
+
+// readManifest reads the first entry of an archive.
+func readManifest(tr *tar.Reader) (*Manifest, error) {
+	header, err := tr.Next()
+	if err != nil {
+		return nil, err
+	}
+	if header.Name != manifestName {
+		return nil, errors.New("not a musiclib backup: manifest missing")
+	}
+	var manifest Manifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, err
+	}
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("unsupported backup format version %d", manifest.FormatVersion)
+	}
+	return &manifest, nil
+}
@@ -0,0 +1,199 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"musiclib/helper"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxDocumentLine bounds a single extended JSON line; Mongo documents are
+// at most 16MB of BSON.
+const maxDocumentLine = 64 << 20
+
+// Report counts what a restore did, or would do on a dry run.
+type Report struct {
+	Manifest  *Manifest
+	Inserted  map[string]int
+	Replaced  map[string]int
+	Filtered  map[string]int
+	MediaUp   int
+	MediaKept int
+}
+
+func (r *Report) String() string {
+	var b strings.Builder
+	for _, name := range Collections {
+		fmt.Fprintf(&b, "%s: %d inserted, %d replaced, %d filtered\n", name, r.Inserted[name], r.Replaced[name], r.Filtered[name])
+	}
+	fmt.Fprintf(&b, "media: %d written, %d already present", r.MediaUp, r.MediaKept)
+	return b.String()
+}
+
+// Restore loads an archive written by Backup into db. Documents are upserted
+// by _id; documents missing from the archive are left alone.
+func Restore(ctx context.Context, db *mongo.Database, r io.Reader, opts Options) (*Report, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	manifest, err := readManifest(tr)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{
+		Manifest: manifest,
+		Inserted: map[string]int{},
+		Replaced: map[string]int{},
+		Filtered: map[string]int{},
+	}
+	media := map[string]MediaFile{}
+	if !opts.NoMedia {
+		for _, file := range manifest.Media {
+			target, err := mediaTarget(file.Path, &opts)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file.Path, err)
+			}
+			file.Path = target
+			media[file.ArchivePath] = file
+		}
+	}
+	known := map[string]bool{}
+	for _, name := range Collections {
+		known[name] = true
+	}
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return report, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch dir, file := path.Split(header.Name); dir {
+		case "collections/":
+			name := strings.TrimSuffix(file, ".jsonl")
+			if !known[name] {
+				return nil, fmt.Errorf("unknown collection %s in backup", name)
+			}
+			if err := restoreCollection(ctx, db.Collection(name), tr, &opts, report); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		case "media/":
+			file, ok := media[header.Name]
+			if !ok {
+				continue
+			}
+			if err := restoreMedia(tr, &file, &opts, report); err != nil {
+				return nil, fmt.Errorf("%s: %w", file.Path, err)
+			}
+		}
+	}
+}
+
+func restoreCollection(ctx context.Context, collection *mongo.Collection, r io.Reader, opts *Options, report *Report) error {
+	name := collection.Name()
+	lines := bufio.NewScanner(r)
+	lines.Buffer(make([]byte, 0, 64<<10), maxDocumentLine)
+	for lines.Scan() {
+		var doc bson.D
+		if err := bson.UnmarshalExtJSON(lines.Bytes(), true, &doc); err != nil {
+			return err
+		}
+		fields := doc.Map()
+		if !opts.keep(modifiedAt(fields)) {
+			report.Filtered[name]++
+			continue
+		}
+		filter := bson.M{"_id": fields["_id"]}
+		if opts.DryRun {
+			count, err := collection.CountDocuments(ctx, filter)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				report.Replaced[name]++
+			} else {
+				report.Inserted[name]++
+			}
+			continue
+		}
+		result, err := collection.ReplaceOne(ctx, filter, doc, options.Replace().SetUpsert(true))
+		if err != nil {
+			return err
+		}
+		if result.UpsertedCount > 0 {
+			report.Inserted[name]++
+		} else {
+			report.Replaced[name]++
+		}
+	}
+	return lines.Err()
+}
+
+// mediaTarget is where an audio file saved from path is restored: path
+// itself, which must lie in a library root, or path below opts.MediaDir.
+// Relative paths and paths with .. components are refused, so that an
+// archive cannot write elsewhere. It is checked for every file before
+// anything is written.
+func mediaTarget(path string, opts *Options) (string, error) {
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("media path is not absolute")
+	}
+	if slices.Contains(strings.Split(filepath.ToSlash(path), "/"), "..") {
+		return "", fmt.Errorf("media path has .. components")
+	}
+	if opts.MediaDir == "" {
+		if !helper.InLibrary(path) {
+			return "", fmt.Errorf("media path is outside the library roots, restore with a media directory")
+		}
+		return path, nil
+	}
+	target := filepath.Join(opts.MediaDir, path)
+	if !helper.InDir(target, opts.MediaDir) {
+		return "", fmt.Errorf("media path is outside %s", opts.MediaDir)
+	}
+	return target, nil
+}
+
+// restoreMedia writes an audio file back to its target path unless a file
+// of the same size is already there.
+func restoreMedia(r io.Reader, file *MediaFile, opts *Options, report *Report) error {
+	if info, err := os.Stat(file.Path); err == nil && info.Size() == file.Size {
+		report.MediaKept++
+		return nil
+	}
+	report.MediaUp++
+	if opts.DryRun {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(file.Path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(file.Path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
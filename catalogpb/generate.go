@@ -0,0 +1,4 @@
+// Package catalogpb holds the Go code generated from proto/catalog.proto.
+package catalogpb
+
+//go:generate protoc -I ../proto --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative catalog.proto
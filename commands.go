@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"musiclib/backup"
+	"musiclib/connect"
+	"musiclib/helper"
+	"musiclib/migrations"
+	"musiclib/scanner"
+	"os"
+	"time"
+)
+
+const usage = `usage: musiclib [command]
+
+Without a command the HTTP server is started.
+
+commands:
+  scan <dir>                  import the audio files below dir into the library
+  backup [flags] <file>       write users, tracks, albums and media to an archive
+  restore [flags] <file>      load an archive written by backup
+  migrate [-dry-run]          apply the pending schema migrations
+  migrate status              list the schema migrations and when they were applied
+
+backup and restore flags:
+  -since <RFC3339 time>       only documents modified at or after this time
+  -until <RFC3339 time>       only documents modified at or before this time
+  -no-media                   leave out the audio files
+  -dry-run                    (restore) report what would change, write nothing
+  -media-dir <dir>            (restore) write the audio files below dir, not in place`
+
+// runCommand runs a musiclib subcommand and returns the exit code.
+func runCommand(args []string) int {
+	switch args[0] {
+	case "scan":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, "usage: musiclib scan <dir>")
+			return 2
+		}
+		return scanLibrary(args[1])
+	case "backup", "restore":
+		return runBackup(args[0], args[1:])
+	case "migrate":
+		return runMigrate(args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+}
+
+func scanLibrary(dir string) int {
+	if !helper.InLibrary(dir) {
+		fmt.Fprintln(os.Stderr, "scan failed:", dir, "is outside the library roots (LIBRARY_ROOTS)")
+		return 1
+	}
+	summary, err := scanner.NewScanner(trackService, albumService).Scan(context.Background(), dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "scan failed:", err)
+		return 1
+	}
+	for _, failure := range summary.Failures {
+		fmt.Fprintln(os.Stderr, "failed:", failure)
+	}
+	fmt.Println(summary)
+	if summary.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// timeFlag is an optional RFC 3339 time given on the command line.
+type timeFlag struct{ t *time.Time }
+
+func (f *timeFlag) String() string {
+	if f.t == nil {
+		return ""
+	}
+	return f.t.Format(time.RFC3339)
+}
+
+func (f *timeFlag) Set(value string) error {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return err
+	}
+	f.t = &t
+	return nil
+}
+
+func runBackup(command string, args []string) int {
+	var since, until timeFlag
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.Var(&since, "since", "only documents modified at or after this RFC3339 time")
+	flags.Var(&until, "until", "only documents modified at or before this RFC3339 time")
+	noMedia := flags.Bool("no-media", false, "leave out the audio files")
+	dryRun := flags.Bool("dry-run", false, "report what restore would change without writing")
+	mediaDir := flags.String("media-dir", "", "restore the audio files below this directory instead of in place")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "usage: musiclib %s [flags] <file>\n", command)
+		return 2
+	}
+	opts := backup.Options{Since: since.t, Until: until.t, NoMedia: *noMedia, DryRun: *dryRun, MediaDir: *mediaDir}
+	file := flags.Arg(0)
+
+	if command == "backup" {
+		f, err := os.Create(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "backup failed:", err)
+			return 1
+		}
+		manifest, err := backup.Backup(context.Background(), connect.Ng.Database, f, opts)
+		if err == nil {
+			err = f.Close()
+		}
+		if err != nil {
+			f.Close()
+			os.Remove(file)
+			fmt.Fprintln(os.Stderr, "backup failed:", err)
+			return 1
+		}
+		for _, name := range backup.Collections {
+			fmt.Printf("%s: %d\n", name, manifest.Collections[name])
+		}
+		fmt.Printf("media: %d\n", len(manifest.Media))
+		return 0
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "restore failed:", err)
+		return 1
+	}
+	defer f.Close()
+	report, err := backup.Restore(context.Background(), connect.Ng.Database, f, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "restore failed:", err)
+		return 1
+	}
+	if opts.DryRun {
+		fmt.Println("dry run, nothing was written")
+	}
+	fmt.Printf("backup from %s (format %d)\n", report.Manifest.CreatedAt.Format(time.RFC3339), report.Manifest.FormatVersion)
+	fmt.Println(report)
+	return 0
+}
+
+func runMigrate(args []string) int {
+	ctx := context.Background()
+	db := connect.Ng.Database
+	if len(args) == 1 && args[0] == "status" {
+		statuses, err := migrations.List(ctx, db)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "migrate status failed:", err)
+			return 1
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied != nil {
+				state = "applied " + status.Applied.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%4d  %-40s %s\n", status.Migration.Version, status.Migration.Name, state)
+		}
+		return 0
+	}
+
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "list the pending migrations without applying them")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "usage: musiclib migrate [-dry-run] | musiclib migrate status")
+		return 2
+	}
+	if *dryRun {
+		pending, err := migrations.Pending(ctx, db)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "migrate failed:", err)
+			return 1
+		}
+		for _, migration := range pending {
+			fmt.Printf("would apply %d %s\n", migration.Version, migration.Name)
+		}
+		fmt.Printf("%d pending migrations\n", len(pending))
+		return 0
+	}
+	done, err := migrations.Up(ctx, db)
+	for _, migration := range done {
+		fmt.Printf("applied %d %s\n", migration.Version, migration.Name)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "migrate failed:", err)
+		return 1
+	}
+	fmt.Printf("schema is at version %d\n", migrations.Latest())
+	return 0
+}
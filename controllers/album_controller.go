@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"musiclib/dto"
 	"musiclib/models"
 	"musiclib/services"
 	"net/http"
@@ -18,11 +19,19 @@ func NewAlbumController(albumService services.AlbumService) *AlbumController {
 		albumService: albumService,
 	}
 }
-func CheckValidAlbum(album *models.Album) bool {
-	if album.AlbumCover == "" || album.Title == "" {
-		return false
+
+// albumFromDto maps a validated request body to an album without tracks.
+func albumFromDto(body *dto.AlbumDto) *models.Album {
+	return &models.Album{
+		Title:      body.Title,
+		AlbumCover: body.AlbumCover,
+		Tracks:     []models.Track{},
 	}
-	return true
+}
+
+// albumToDto is the request body an album would be created from.
+func albumToDto(album *models.Album) dto.AlbumDto {
+	return dto.AlbumDto{Title: album.Title, AlbumCover: album.AlbumCover}
 }
 
 // CreateAlbum	godoc
@@ -36,18 +45,13 @@ func CheckValidAlbum(album *models.Album) bool {
 // @Param        album   body     dto.AlbumDto  true  "Album data to create"
 // @Router       /album/create [post]
 func (a *AlbumController) CreateAlbum(ctx *gin.Context) {
-	var album *models.Album
-	if err := ctx.ShouldBindJSON(&album); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var body dto.AlbumDto
+	if !bindJSON(ctx, &body) {
 		return
 	}
-
-	if !CheckValidAlbum(album) {
-		ctx.JSON(http.StatusBadRequest, gin.H{"message": "wrong input structure"})
-		return
-	}
-	if err := a.albumService.CreateAlbum(album); err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	album := albumFromDto(&body)
+	if err := a.albumService.CreateAlbum(ctx.Request.Context(), album); err != nil {
+		ctx.Error(err)
 		return
 	}
 	ctx.JSON(http.StatusCreated, album)
@@ -59,12 +63,20 @@ func (a *AlbumController) CreateAlbum(ctx *gin.Context) {
 // @Tags         album
 // @Accept       json
 // @Produce      json
+// @Param        sort  query  string  false  "Sort by title, rating or rating_count"
 // @Success      200  {array}   models.Album
 // @Router       /album/getAll [get]
 func (a *AlbumController) GetAlbums(ctx *gin.Context) {
-	albums, err := a.albumService.GetAlbums()
+	sort := ctx.Query("sort")
+	switch sort {
+	case "", services.AlbumSortTitle, services.AlbumSortRating, services.AlbumSortRatingCount:
+	default:
+		badRequest(ctx, "unsupported sort key")
+		return
+	}
+	albums, err := a.albumService.GetAlbums(ctx.Request.Context(), &sort)
 	if err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 	ctx.JSON(http.StatusOK, albums)
@@ -80,43 +92,101 @@ func (a *AlbumController) GetAlbums(ctx *gin.Context) {
 // @Param        album   body     dto.AlbumDto  true  "Album data to update"
 // @Security ApiKeyAuth
 // @param Authorization header string true "Authorization"
+// @Param        If-Match  header  string  false  "ETag of the version this change is based on"
 // @Router       /album/update/{id} [put]
 func (a *AlbumController) UpdateAlbum(ctx *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(ctx, err.Error())
+		return
+	}
+	version, ok := ifMatch(ctx)
+	if !ok {
 		return
 	}
-	var album models.Album
-	if err := ctx.ShouldBindJSON(&album); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var body dto.AlbumDto
+	if !bindJSON(ctx, &body) {
 		return
 	}
-	if err := a.albumService.UpdateAlbum(&id, &album); err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	album := albumFromDto(&body)
+	if err := a.albumService.UpdateAlbum(ctx.Request.Context(), &id, album, version); err != nil {
+		ctx.Error(err)
 		return
 	}
+	updatedETag(ctx, version)
+	ctx.JSON(http.StatusOK, album)
+}
+
+// PatchAlbum 	godoc
+// @Summary      PatchAlbum
+// @Description  Change the title or cover of an album with a JSON merge patch. Tracks are left alone.
+// @Tags         album
+// @Accept       application/merge-patch+json
+// @Produce      json
+// @Param        id  path  string  true  "Update by Album ID"
+// @Param        patch   body     dto.AlbumDto  true  "Fields to change"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      200  {object}   models.Album
+// @Param        If-Match  header  string  false  "ETag of the version this change is based on"
+// @Router       /album/update/{id} [patch]
+func (a *AlbumController) PatchAlbum(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	version, ok := ifMatch(ctx)
+	if !ok {
+		return
+	}
+	album, err := a.albumService.FindAlbum(ctx.Request.Context(), &id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	body := albumToDto(album)
+	fields, ok := mergePatch(ctx, &body)
+	if !ok {
+		return
+	}
+	if len(fields) > 0 {
+		if err := a.albumService.PatchAlbum(ctx.Request.Context(), &id, albumFromDto(&body), fields, version); err != nil {
+			ctx.Error(err)
+			return
+		}
+		if album, err = a.albumService.FindAlbum(ctx.Request.Context(), &id); err != nil {
+			ctx.Error(err)
+			return
+		}
+	}
+	ctx.Header("ETag", etag(album.Version))
 	ctx.JSON(http.StatusOK, album)
 }
 
 // DeleteAlbum 	godoc
 // @Summary      DeleteAlbum
-// @Description  delete a album
+// @Description  move a album to the trash
 // @Tags         album
 // @Accept       json
 // @Produce      json
 // @Param        id  path  string  true  "Delete by Album ID"
 // @Security ApiKeyAuth
 // @param Authorization header string true "Authorization"
+// @Param        If-Match  header  string  false  "ETag of the version this change is based on"
 // @Router       /album/delete/{id} [delete]
 func (a *AlbumController) DeleteAlbum(ctx *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(ctx, err.Error())
 		return
 	}
-	if err := a.albumService.DeleteAlbum(&id); err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	version, ok := ifMatch(ctx)
+	if !ok {
+		return
+	}
+	if err := a.albumService.DeleteAlbum(ctx.Request.Context(), &id, version); err != nil {
+		ctx.Error(err)
 		return
 	}
 	ctx.JSON(http.StatusOK, gin.H{"message": "Album deleted successfully"})
@@ -130,16 +200,20 @@ func (a *AlbumController) DeleteAlbum(ctx *gin.Context) {
 // @Produce      json
 // @Param        id  path  string  true  "Find by album ID"
 // @Success      200  {object}   models.Album
+// @Param        If-None-Match  header  string  false  "ETag of a cached copy"
 // @Router       /album/get/{id} [get]
 func (a *AlbumController) FindAlbum(ctx *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(ctx, err.Error())
 		return
 	}
-	album, err := a.albumService.FindAlbum(&id)
+	album, err := a.albumService.FindAlbum(ctx.Request.Context(), &id)
 	if err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		ctx.Error(err)
+		return
+	}
+	if notModified(ctx, album.Version) {
 		return
 	}
 	ctx.JSON(http.StatusOK, album)
@@ -155,9 +229,9 @@ func (a *AlbumController) FindAlbum(ctx *gin.Context) {
 // @Router       /album/search [get]
 func (a *AlbumController) FindTracksAndAlbums(ctx *gin.Context) {
 	keyword := ctx.Query("keyword")
-	albums, tracks, err := a.albumService.FindTracksAndAlbums(&keyword)
+	albums, tracks, err := a.albumService.FindTracksAndAlbums(ctx.Request.Context(), &keyword)
 	if err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 	ctx.JSON(http.StatusOK, gin.H{"albums": albums, "tracks": tracks})
@@ -177,16 +251,16 @@ func (a *AlbumController) FindTracksAndAlbums(ctx *gin.Context) {
 func (a *AlbumController) AddTrackToAlbum(ctx *gin.Context) {
 	albumId, err := primitive.ObjectIDFromHex(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(ctx, err.Error())
 		return
 	}
-	var track models.Track
-	if err := ctx.ShouldBindJSON(&track); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var body dto.TrackDto
+	if !bindJSON(ctx, &body) {
 		return
 	}
-	if err := a.albumService.AddTrackToAlbum(&albumId, &track); err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	track := trackFromDto(&body)
+	if err := a.albumService.AddTrackToAlbum(ctx.Request.Context(), &albumId, track); err != nil {
+		ctx.Error(err)
 		return
 	}
 	ctx.JSON(http.StatusOK, track)
@@ -206,16 +280,16 @@ func (a *AlbumController) AddTrackToAlbum(ctx *gin.Context) {
 func (a *AlbumController) RemoveTrackFromAlbum(ctx *gin.Context) {
 	albumId, err := primitive.ObjectIDFromHex(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(ctx, err.Error())
 		return
 	}
 	trackId, err := primitive.ObjectIDFromHex(ctx.Param("trackId"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(ctx, err.Error())
 		return
 	}
-	if err := a.albumService.RemoveTrackFromAlbum(&albumId, &trackId); err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	if err := a.albumService.RemoveTrackFromAlbum(ctx.Request.Context(), &albumId, &trackId); err != nil {
+		ctx.Error(err)
 		return
 	}
 	ctx.JSON(http.StatusOK, gin.H{"message": "track removed from album"})
@@ -227,6 +301,7 @@ func (a *AlbumController) RegisterAlbumRouter(rt *gin.RouterGroup) {
 	router.GET("/getAll", a.GetAlbums)
 	router.GET("/find/:id", a.FindAlbum)
 	router.PUT("/update/:id", a.UpdateAlbum)
+	router.PATCH("/update/:id", a.PatchAlbum)
 	router.DELETE("/delete/:id", a.DeleteAlbum)
 	router.GET("/search", a.FindTracksAndAlbums)
 	router.POST("/add_track/:id", a.AddTrackToAlbum)
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"musiclib/models"
+	"musiclib/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Actor has the changes of a request recorded in the audit log as made by
+// the authenticated user, if any, from the client IP. It goes after the
+// middleware that authenticates the request.
+func Actor() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		setActor(ctx)
+		ctx.Next()
+	}
+}
+
+// setActor passes the actor of the request on to the services.
+func setActor(ctx *gin.Context) {
+	actor := services.Actor{UserId: currentUserId(ctx), IP: ctx.ClientIP()}
+	ctx.Request = ctx.Request.WithContext(services.WithActor(ctx.Request.Context(), actor))
+}
+
+type AuditController struct {
+	auditService services.AuditService
+}
+
+func NewAuditController(auditService services.AuditService) *AuditController {
+	return &AuditController{
+		auditService: auditService,
+	}
+}
+
+// GetAuditEntries godoc
+// @Summary      GetAuditEntries
+// @Description  Query the audit log of catalog and account changes, newest first (admin only)
+// @Tags         admin
+// @Produce      json
+// @Param        resource  query  string  false  "track, album or user"
+// @Param        resource_id  query  string  false  "Resource ID"
+// @Param        actor  query  string  false  "User ID of the actor"
+// @Param        action  query  string  false  "create, update, delete, restore, add-track, remove-track or merge"
+// @Param        from  query  string  false  "RFC 3339 time, inclusive"
+// @Param        to  query  string  false  "RFC 3339 time, inclusive"
+// @Param        limit  query  int  false  "Maximum number of entries (default 100, at most 1000)"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      200  {array}   models.AuditEntry
+// @Router       /admin/audit [get]
+func (a *AuditController) GetAuditEntries(ctx *gin.Context) {
+	filter := models.AuditFilter{
+		Resource:   ctx.Query("resource"),
+		ResourceId: ctx.Query("resource_id"),
+		ActorId:    ctx.Query("actor"),
+		Action:     ctx.Query("action"),
+	}
+	var fields []FieldError
+	for _, param := range []struct {
+		name string
+		t    **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		value := ctx.Query(param.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			fields = append(fields, FieldError{Field: param.name, Code: "time", Message: "must be an RFC 3339 time"})
+			continue
+		}
+		*param.t = &t
+	}
+	if value := ctx.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			fields = append(fields, FieldError{Field: "limit", Code: "min", Message: "must be a positive number"})
+		}
+		filter.Limit = limit
+	}
+	if len(fields) > 0 {
+		invalidFields(ctx, fields)
+		return
+	}
+	entries, err := a.auditService.FindEntries(ctx.Request.Context(), &filter)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, entries)
+}
+
+func (a *AuditController) RegisterAuditRouter(admin *gin.RouterGroup) {
+	admin.GET("/audit", a.GetAuditEntries)
+}
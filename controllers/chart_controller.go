@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"musiclib/services"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ChartController struct {
+	chartService services.ChartService
+}
+
+func NewChartController(chartService services.ChartService) *ChartController {
+	return &ChartController{
+		chartService: chartService,
+	}
+}
+
+// GetChart 	godoc
+// @Summary      GetChart
+// @Description  Get a precomputed chart with rank movement against the previous period
+// @Tags         chart
+// @Accept       json
+// @Produce      json
+// @Param        kind  path  string  true  "tracks, albums or artists"
+// @Param        window  path  string  true  "day, week, month or all-time"
+// @Success      200  {object}   models.Chart
+// @Router       /chart/{kind}/{window} [get]
+func (c *ChartController) GetChart(ctx *gin.Context) {
+	kind := ctx.Param("kind")
+	window := ctx.Param("window")
+	if !slices.Contains(services.ChartKinds, kind) || !slices.Contains(services.ChartWindows, window) {
+		badRequest(ctx, "unknown chart")
+		return
+	}
+	chart, err := c.chartService.GetChart(ctx.Request.Context(), &kind, &window)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, chart)
+}
+
+func (c *ChartController) RegisterChartRouter(rt *gin.RouterGroup) {
+	router := rt.Group("/chart")
+	router.GET("/:kind/:window", c.GetChart)
+}
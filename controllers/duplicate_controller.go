@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"errors"
+	"musiclib/dto"
+	"musiclib/jobs"
+	"musiclib/models"
+	"musiclib/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DuplicateController struct {
+	duplicateService services.DuplicateService
+	jobService       services.JobService
+}
+
+func NewDuplicateController(duplicateService services.DuplicateService, jobService services.JobService) *DuplicateController {
+	return &DuplicateController{
+		duplicateService: duplicateService,
+		jobService:       jobService,
+	}
+}
+
+// FindDuplicateTracks godoc
+// @Summary      FindDuplicateTracks
+// @Description  Queue a search for clusters of likely duplicate tracks, which reads the whole catalog, and answer the job; its result, once done, are the clusters. A search already queued or running is answered instead of queuing another (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      202  {object}   models.Job
+// @Router       /admin/track/duplicates [post]
+func (d *DuplicateController) FindDuplicateTracks(ctx *gin.Context) {
+	job := models.Job{Type: jobs.TypeDuplicates, Key: jobs.TypeDuplicates}
+	if err := d.jobService.Enqueue(ctx.Request.Context(), &job); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusAccepted, job)
+}
+
+// GetDuplicateTracks godoc
+// @Summary      GetDuplicateTracks
+// @Description  The clusters of likely duplicate tracks found by the last search that succeeded, with the time it finished in Last-Modified; 404 until a search queued with POST has finished (admin only)
+// @Tags         admin
+// @Produce      json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      200  {array}   models.DuplicateCluster
+// @Router       /admin/track/duplicates [get]
+func (d *DuplicateController) GetDuplicateTracks(ctx *gin.Context) {
+	jobType := jobs.TypeDuplicates
+	job, err := d.jobService.FindLatestSucceeded(ctx.Request.Context(), &jobType)
+	if errors.Is(err, services.ErrNotFound) {
+		ctx.Error(services.NotFound("no duplicate search has finished yet"))
+		return
+	}
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	if job.FinishedAt != nil {
+		ctx.Header("Last-Modified", job.FinishedAt.UTC().Format(http.TimeFormat))
+	}
+	ctx.Data(http.StatusOK, "application/json; charset=utf-8", job.Result)
+}
+
+// MergeTracks godoc
+// @Summary      MergeTracks
+// @Description  Merge duplicate tracks into a survivor and move them to the trash, in one transaction, so MongoDB must run as a replica set (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        merge   body     dto.MergeTracksDto  true  "Survivor and duplicates"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Router       /admin/track/merge [post]
+func (d *DuplicateController) MergeTracks(ctx *gin.Context) {
+	var merge dto.MergeTracksDto
+	if !bindJSON(ctx, &merge) {
+		return
+	}
+	survivorId, err := primitive.ObjectIDFromHex(merge.SurvivorId)
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	duplicateIds := make([]primitive.ObjectID, 0, len(merge.DuplicateIds))
+	for _, hex := range merge.DuplicateIds {
+		id, err := primitive.ObjectIDFromHex(hex)
+		if err != nil {
+			badRequest(ctx, err.Error())
+			return
+		}
+		duplicateIds = append(duplicateIds, id)
+	}
+	if err := d.duplicateService.MergeTracks(ctx.Request.Context(), &survivorId, duplicateIds); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "tracks merged"})
+}
+
+func (d *DuplicateController) RegisterDuplicateRouter(admin *gin.RouterGroup) {
+	router := admin.Group("/track")
+	router.POST("/duplicates", d.FindDuplicateTracks)
+	router.GET("/duplicates", d.GetDuplicateTracks)
+	router.POST("/merge", d.MergeTracks)
+}
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"musiclib/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const problemContentType = "application/problem+json"
+
+// statusClientClosedRequest is the status, borrowed from nginx, of a
+// request the client gave up on. Nobody is left to read it; it is only
+// for the logs.
+const statusClientClosedRequest = 499
+
+// Problem is an RFC 7807 problem details body. Errors lists the invalid
+// fields of a 422.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+func writeProblem(ctx *gin.Context, problem Problem) {
+	problem.Type = "about:blank"
+	problem.Title = http.StatusText(problem.Status)
+	problem.Instance = ctx.Request.URL.Path
+	ctx.Header("Content-Type", problemContentType)
+	ctx.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// badRequest answers a malformed request, such as a path id that is not an
+// ObjectID, with a 400 problem.
+func badRequest(ctx *gin.Context, detail string) {
+	writeProblem(ctx, Problem{Status: http.StatusBadRequest, Detail: detail})
+}
+
+// ErrorHandler answers the last error a handler recorded with ctx.Error as
+// a problem: 422 for rejected input, 404, 409, 412, 403, 503 and 504 for
+// the matching service error kinds, and 500 for anything else. A request
+// the client canceled gets no body.
+func ErrorHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+		if len(ctx.Errors) == 0 || ctx.Writer.Written() {
+			return
+		}
+		err := ctx.Errors.Last().Err
+		var validationErr *services.ValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			invalidFields(ctx, []FieldError{{Field: validationErr.Field, Code: "invalid", Message: validationErr.Message}})
+		case errors.Is(err, services.ErrNotFound):
+			writeProblem(ctx, Problem{Status: http.StatusNotFound, Detail: err.Error()})
+		case errors.Is(err, services.ErrConflict):
+			writeProblem(ctx, Problem{Status: http.StatusConflict, Detail: err.Error()})
+		case errors.Is(err, services.ErrPreconditionFailed):
+			writeProblem(ctx, Problem{Status: http.StatusPreconditionFailed, Detail: err.Error()})
+		case errors.Is(err, services.ErrForbidden):
+			writeProblem(ctx, Problem{Status: http.StatusForbidden, Detail: err.Error()})
+		case errors.Is(err, services.ErrUnavailable):
+			log.Println(ctx.Request.Method, ctx.Request.URL.Path, errors.Unwrap(err))
+			writeProblem(ctx, Problem{Status: http.StatusServiceUnavailable, Detail: err.Error()})
+		case errors.Is(err, services.ErrTimeout):
+			log.Println(ctx.Request.Method, ctx.Request.URL.Path, err)
+			writeProblem(ctx, Problem{Status: http.StatusGatewayTimeout, Detail: err.Error()})
+		case errors.Is(err, services.ErrCanceled):
+			ctx.AbortWithStatus(statusClientClosedRequest)
+		default:
+			log.Println(ctx.Request.Method, ctx.Request.URL.Path, err)
+			writeProblem(ctx, Problem{Status: http.StatusInternalServerError})
+		}
+	}
+}
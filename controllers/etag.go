@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etag is the entity tag of a resource at version.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// notModified sets the ETag of a resource at version, and answers 304 when
+// If-None-Match already names it. It reports whether it answered.
+func notModified(ctx *gin.Context, version int64) bool {
+	tag := etag(version)
+	ctx.Header("ETag", tag)
+	header := ctx.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		// If-None-Match uses the weak comparison.
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == tag || candidate == "*" {
+			ctx.AbortWithStatus(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatch returns the version named by If-Match, or nil when the request is
+// not conditional. When the header names no version of a resource it
+// answers 412 and returns false.
+func ifMatch(ctx *gin.Context) (*int64, bool) {
+	header := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil, true
+	}
+	if len(header) > 2 && strings.HasPrefix(header, `"`) && strings.HasSuffix(header, `"`) {
+		if version, err := strconv.ParseInt(header[1:len(header)-1], 10, 64); err == nil {
+			return &version, true
+		}
+	}
+	writeProblem(ctx, Problem{Status: http.StatusPreconditionFailed, Detail: "If-Match must be a single ETag of this resource"})
+	return nil, false
+}
+
+// updatedETag sets the ETag after a write conditional on version.
+func updatedETag(ctx *gin.Context, version *int64) {
+	if version != nil {
+		ctx.Header("ETag", etag(*version+1))
+	}
+}
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"musiclib/models"
+	"musiclib/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// feedKeepAlive is how often an idle event stream sends a comment, so
+// proxies do not close it.
+const feedKeepAlive = 15 * time.Second
+
+type FeedController struct {
+	feedService services.FeedService
+}
+
+func NewFeedController(feedService services.FeedService) *FeedController {
+	return &FeedController{
+		feedService: feedService,
+	}
+}
+
+// pump reads stream until ctx is done or the stream fails. The error
+// channel receives the failure.
+func pump(ctx context.Context, stream services.ChangeStream) (<-chan *models.Change, <-chan error) {
+	changes := make(chan *models.Change)
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			change, err := stream.Next(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case changes <- change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return changes, errs
+}
+
+// Events 	godoc
+// @Summary      Events
+// @Description  Server-Sent Events stream of track and album changes. The id of every event is a token; reconnect with it in Last-Event-ID, or in the token query parameter, to resume after that event.
+// @Tags         feed
+// @Produce      text/event-stream
+// @Param        token  query  string  false  "Resume after the event with this id"
+// @Param        Last-Event-ID  header  string  false  "Resume after the event with this id"
+// @Router       /feed/events [get]
+func (f *FeedController) Events(ctx *gin.Context) {
+	token := ctx.GetHeader("Last-Event-ID")
+	if token == "" {
+		token = ctx.Query("token")
+	}
+	request := ctx.Request.Context()
+	stream, err := f.feedService.Watch(request, token)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	defer stream.Close(context.Background())
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	changes, errs := pump(request, stream)
+	keepAlive := time.NewTicker(feedKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case change := <-changes:
+			data, err := json.Marshal(change)
+			if err != nil {
+				log.Println("err feed event", err)
+				return
+			}
+			fmt.Fprintf(ctx.Writer, "id: %s\nevent: %s\ndata: %s\n\n", change.Token, change.Type, data)
+		case <-keepAlive.C:
+			io.WriteString(ctx.Writer, ": keep-alive\n\n")
+		case err := <-errs:
+			if request.Err() == nil {
+				log.Println("err feed", err)
+			}
+			return
+		case <-request.Done():
+			return
+		}
+		ctx.Writer.Flush()
+	}
+}
+
+// Socket 	godoc
+// @Summary      Socket
+// @Description  WebSocket stream of track and album changes, one JSON message per change. Reconnect with the token of the last message to resume after it.
+// @Tags         feed
+// @Param        token  query  string  false  "Resume after the change with this token"
+// @Router       /feed/ws [get]
+func (f *FeedController) Socket(ctx *gin.Context) {
+	request, cancel := context.WithCancel(ctx.Request.Context())
+	defer cancel()
+	stream, err := f.feedService.Watch(request, ctx.Query("token"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	defer stream.Close(context.Background())
+
+	server := websocket.Server{
+		// The feed is as public as the catalog and needs no cookie, so
+		// any origin may connect.
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			// Clients only listen; a read ends when they go away.
+			go func() {
+				io.Copy(io.Discard, ws)
+				cancel()
+			}()
+			changes, errs := pump(request, stream)
+			for {
+				select {
+				case change := <-changes:
+					if err := websocket.JSON.Send(ws, change); err != nil {
+						return
+					}
+				case err := <-errs:
+					if request.Err() == nil {
+						log.Println("err feed", err)
+					}
+					return
+				case <-request.Done():
+					return
+				}
+			}
+		},
+	}
+	server.ServeHTTP(ctx.Writer, ctx.Request)
+}
+
+func (f *FeedController) RegisterFeedRouter(rt *gin.RouterGroup) {
+	router := rt.Group("/feed")
+	router.GET("/events", f.Events)
+	router.GET("/ws", f.Socket)
+}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"musiclib/dto"
+	"musiclib/graph"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+type GraphQLController struct {
+	schema *graph.Schema
+	auth   gin.HandlerFunc
+}
+
+func NewGraphQLController(schema *graph.Schema) *GraphQLController {
+	return &GraphQLController{
+		schema: schema,
+	}
+}
+
+// Query 	godoc
+// @Summary      Query
+// @Description  Run a GraphQL query over tracks, albums, reviews, the public fields of users and search. Mutations must be POSTed.
+// @Tags         graphql
+// @Produce      json
+// @Param        query  query  string  true  "GraphQL query"
+// @Param        operationName  query  string  false  "Operation to run when the query holds several"
+// @Param        variables  query  string  false  "Variables as a JSON object"
+// @Router       /graphql [get]
+func (g *GraphQLController) Query(ctx *gin.Context) {
+	body := dto.GraphQLDto{
+		Query:         ctx.Query("query"),
+		OperationName: ctx.Query("operationName"),
+	}
+	if body.Query == "" {
+		invalidFields(ctx, []FieldError{{Field: "query", Code: "required", Message: "is required"}})
+		return
+	}
+	if variables := ctx.Query("variables"); variables != "" {
+		if err := json.Unmarshal([]byte(variables), &body.Variables); err != nil {
+			invalidFields(ctx, []FieldError{{Field: "variables", Code: "json", Message: "must be a JSON object"}})
+			return
+		}
+	}
+	g.run(ctx, &body, false)
+}
+
+// Execute 	godoc
+// @Summary      Execute
+// @Description  Run a GraphQL query or mutation. Queries are public; mutations (addTrackToAlbum, removeTrackFromAlbum) need a token. Operations nested more than 8 levels deep or too costly are refused with 400.
+// @Tags         graphql
+// @Accept       json
+// @Produce      json
+// @Param        request   body     dto.GraphQLDto  true  "Query, operation name and variables"
+// @param Authorization header string false "Authorization"
+// @Router       /graphql [post]
+func (g *GraphQLController) Execute(ctx *gin.Context) {
+	var body dto.GraphQLDto
+	if !bindJSON(ctx, &body) {
+		return
+	}
+	g.run(ctx, &body, true)
+}
+
+func (g *GraphQLController) run(ctx *gin.Context, body *dto.GraphQLDto, post bool) {
+	operation, errs := g.schema.Prepare(body.Query, body.OperationName)
+	if errs != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"errors": errs})
+		return
+	}
+	if operation.Mutation {
+		if !post {
+			ctx.Header("Allow", http.MethodPost)
+			ctx.JSON(http.StatusMethodNotAllowed, gin.H{"errors": gqlerrors.FormatErrors(errors.New("mutations must be sent with POST"))})
+			return
+		}
+		if g.auth(ctx); ctx.IsAborted() {
+			return
+		}
+		setActor(ctx)
+	}
+	result := g.schema.Execute(ctx.Request.Context(), operation, body.Variables)
+	ctx.JSON(http.StatusOK, result)
+}
+
+// RegisterGraphQLRouter registers the GraphQL endpoint. Queries are as
+// public as the rest of the catalog, so auth only guards mutations.
+func (g *GraphQLController) RegisterGraphQLRouter(rt *gin.RouterGroup, auth gin.HandlerFunc) {
+	g.auth = auth
+	rt.GET("/graphql", g.Query)
+	rt.POST("/graphql", g.Execute)
+}
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"musiclib/dto"
+	"musiclib/jobs"
+	"musiclib/models"
+	"musiclib/services"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type JobController struct {
+	jobService services.JobService
+	runner     *jobs.Runner
+}
+
+func NewJobController(jobService services.JobService, runner *jobs.Runner) *JobController {
+	return &JobController{
+		jobService: jobService,
+		runner:     runner,
+	}
+}
+
+// Enqueue godoc
+// @Summary      Enqueue
+// @Description  Queue a background job: scan {"dir": "..."} imports the audio files below dir, charts recomputes the charts and duplicates finds duplicate tracks. A job with a key is not queued twice: while one with the same key is queued or running, that one is returned. (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        job   body     dto.JobDto  true  "Type, payload and options"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      202  {object}   models.Job
+// @Router       /admin/jobs [post]
+func (j *JobController) Enqueue(ctx *gin.Context) {
+	var body dto.JobDto
+	if !bindJSON(ctx, &body) {
+		return
+	}
+	if !j.runner.Handles(body.Type) {
+		invalidFields(ctx, []FieldError{{Field: "type", Code: "oneof", Message: "must be one of " + strings.Join(j.runner.Types(), ", ")}})
+		return
+	}
+	job := models.Job{
+		Type:        body.Type,
+		Key:         body.Key,
+		Payload:     body.Payload,
+		MaxAttempts: body.MaxAttempts,
+	}
+	if body.RunAt != nil {
+		job.RunAt = *body.RunAt
+	}
+	if err := j.jobService.Enqueue(ctx.Request.Context(), &job); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusAccepted, job)
+}
+
+// GetJobs godoc
+// @Summary      GetJobs
+// @Description  List the latest 100 jobs, newest first. Failed jobs that ran out of attempts are dead. (admin only)
+// @Tags         admin
+// @Produce      json
+// @Param        status  query  string  false  "queued, running, succeeded or dead"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      200  {array}   models.Job
+// @Router       /admin/jobs [get]
+func (j *JobController) GetJobs(ctx *gin.Context) {
+	status := ctx.Query("status")
+	if status != "" && !slices.Contains(models.JobStatuses, status) {
+		invalidFields(ctx, []FieldError{{Field: "status", Code: "oneof", Message: "must be one of " + strings.Join(models.JobStatuses, ", ")}})
+		return
+	}
+	jobList, err := j.jobService.GetJobs(ctx.Request.Context(), &status)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, jobList)
+}
+
+// FindJob godoc
+// @Summary      FindJob
+// @Description  Get a job with its status, attempts, last error and result (admin only)
+// @Tags         admin
+// @Produce      json
+// @Param        id  path  string  true  "Job ID"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      200  {object}   models.Job
+// @Router       /admin/jobs/{id} [get]
+func (j *JobController) FindJob(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	job, err := j.jobService.FindJob(ctx.Request.Context(), &id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, job)
+}
+
+// RetryJob godoc
+// @Summary      RetryJob
+// @Description  Queue a dead job again with fresh attempts (admin only)
+// @Tags         admin
+// @Produce      json
+// @Param        id  path  string  true  "Job ID"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      202  {object}   models.Job
+// @Router       /admin/jobs/{id}/retry [post]
+func (j *JobController) RetryJob(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	job, err := j.jobService.RetryJob(ctx.Request.Context(), &id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusAccepted, job)
+}
+
+func (j *JobController) RegisterJobRouter(admin *gin.RouterGroup) {
+	router := admin.Group("/jobs")
+	router.POST("", j.Enqueue)
+	router.GET("", j.GetJobs)
+	router.GET("/:id", j.FindJob)
+	router.POST("/:id/retry", j.RetryJob)
+}
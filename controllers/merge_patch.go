@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io"
+	"musiclib/helper"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+const mergePatchContentType = "application/merge-patch+json"
+
+// maxPatchSize limits the size of merge patch bodies.
+const maxPatchSize = 1 << 20
+
+// mergePatch applies the JSON merge patch (RFC 7396) in the request body to
+// dto, which holds the current state of the resource, and validates the
+// result with the binding tags of dto. It returns the fields the patch
+// changed. On failure it answers the request and returns false.
+func mergePatch(ctx *gin.Context, dto interface{}) ([]string, bool) {
+	if ctx.ContentType() != mergePatchContentType {
+		writeProblem(ctx, Problem{Status: http.StatusUnsupportedMediaType, Detail: "content type must be " + mergePatchContentType})
+		return nil, false
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxPatchSize))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return nil, false
+	}
+	var patch map[string]interface{}
+	if err := json.Unmarshal(data, &patch); err != nil || patch == nil {
+		badRequest(ctx, "patch must be a JSON object")
+		return nil, false
+	}
+
+	known := jsonFields(dto)
+	fields := make([]string, 0, len(patch))
+	unknown := []FieldError{}
+	for field := range patch {
+		if !known[field] {
+			unknown = append(unknown, FieldError{Field: field, Code: "unknown", Message: "cannot be changed"})
+			continue
+		}
+		fields = append(fields, field)
+	}
+	if len(unknown) > 0 {
+		sort.Slice(unknown, func(i, j int) bool { return unknown[i].Field < unknown[j].Field })
+		invalidFields(ctx, unknown)
+		return nil, false
+	}
+	sort.Strings(fields)
+
+	current, err := json.Marshal(dto)
+	if err != nil {
+		ctx.Error(err)
+		return nil, false
+	}
+	var document interface{}
+	if err := json.Unmarshal(current, &document); err != nil {
+		ctx.Error(err)
+		return nil, false
+	}
+	merged, err := json.Marshal(helper.MergePatch(document, patch))
+	if err != nil {
+		ctx.Error(err)
+		return nil, false
+	}
+	// Fields the patch removed must end up empty, not keep their old value.
+	value := reflect.ValueOf(dto).Elem()
+	value.Set(reflect.Zero(value.Type()))
+	if err := json.Unmarshal(merged, dto); err != nil {
+		checkBody(ctx, err)
+		return nil, false
+	}
+	if !checkBody(ctx, binding.Validator.ValidateStruct(dto)) {
+		return nil, false
+	}
+	return fields, true
+}
+
+// jsonFields returns the json names of the fields of the struct dto points to.
+func jsonFields(dto interface{}) map[string]bool {
+	fields := map[string]bool{}
+	t := reflect.TypeOf(dto).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}
@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"errors"
+	"musiclib/dto"
+	"musiclib/models"
+	"musiclib/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PlaybackController struct {
+	playbackService services.PlaybackService
+}
+
+func NewPlaybackController(playbackService services.PlaybackService) *PlaybackController {
+	return &PlaybackController{
+		playbackService: playbackService,
+	}
+}
+
+// GetPlaybackState 	godoc
+// @Summary      GetPlaybackState
+// @Description  Get the play queue and position of the current user
+// @Tags         playback
+// @Accept       json
+// @Produce      json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      200  {object}   models.PlaybackState
+// @Router       /playback [get]
+func (p *PlaybackController) GetPlaybackState(ctx *gin.Context) {
+	userId := currentUserId(ctx)
+	state, err := p.playbackService.GetPlaybackState(ctx.Request.Context(), &userId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, state)
+}
+
+// SavePlaybackState 	godoc
+// @Summary      SavePlaybackState
+// @Description  Save the play queue and position of the current user. The version must be the one last read; on a conflict the current state is returned with 409.
+// @Tags         playback
+// @Accept       json
+// @Produce      json
+// @Param        state   body     dto.PlaybackStateDto  true  "Playback state"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      200  {object}   models.PlaybackState
+// @Failure      409  {object}   models.PlaybackState
+// @Router       /playback [put]
+func (p *PlaybackController) SavePlaybackState(ctx *gin.Context) {
+	var body dto.PlaybackStateDto
+	if !bindJSON(ctx, &body) {
+		return
+	}
+	state := models.PlaybackState{
+		UserId:         currentUserId(ctx),
+		Queue:          body.Queue,
+		CurrentTrackId: body.CurrentTrackId,
+		PositionMs:     body.PositionMs,
+		Shuffle:        body.Shuffle,
+		Repeat:         body.Repeat,
+		Device:         body.Device,
+		Version:        body.Version,
+	}
+	err := p.playbackService.SavePlaybackState(ctx.Request.Context(), &state)
+	if errors.Is(err, services.ErrVersionConflict) {
+		current, err := p.playbackService.GetPlaybackState(ctx.Request.Context(), &state.UserId)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		ctx.JSON(http.StatusConflict, current)
+		return
+	}
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, state)
+}
+
+// RegisterPlaybackRouter registers the playback routes. The state is
+// private, so reads go through auth as well.
+func (p *PlaybackController) RegisterPlaybackRouter(rt *gin.RouterGroup, auth gin.HandlerFunc) {
+	router := rt.Group("/playback")
+	router.GET("", auth, p.GetPlaybackState)
+	router.PUT("", p.SavePlaybackState)
+}
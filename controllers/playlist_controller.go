@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"musiclib/dto"
+	"musiclib/models"
+	"musiclib/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PlaylistController struct {
+	playlistService services.PlaylistService
+}
+
+func NewPlaylistController(playlistService services.PlaylistService) *PlaylistController {
+	return &PlaylistController{
+		playlistService: playlistService,
+	}
+}
+
+// CreatePlaylist	godoc
+// @Summary      CreatePlaylist
+// @Description  Save a smart playlist defined by rules
+// @Tags         playlist
+// @Accept       json
+// @Produce      json
+// @Param        playlist   body     dto.PlaylistDto  true  "Playlist name and rules"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      201  {object}   models.Playlist
+// @Router       /playlist/create [post]
+func (p *PlaylistController) CreatePlaylist(ctx *gin.Context) {
+	var body dto.PlaylistDto
+	if !bindJSON(ctx, &body) {
+		return
+	}
+	playlist := models.Playlist{Name: body.Name, Rules: body.Rules}
+	playlist.UserId = currentUserId(ctx)
+	if err := p.playlistService.CreatePlaylist(ctx.Request.Context(), &playlist); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, playlist)
+}
+
+// PreviewPlaylist	godoc
+// @Summary      PreviewPlaylist
+// @Description  Evaluate smart playlist rules without saving them
+// @Tags         playlist
+// @Accept       json
+// @Produce      json
+// @Param        rules   body     models.SmartRules  true  "Rules to evaluate"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      200  {array}   models.Track
+// @Router       /playlist/preview [post]
+func (p *PlaylistController) PreviewPlaylist(ctx *gin.Context) {
+	var rules models.SmartRules
+	if !bindJSON(ctx, &rules) {
+		return
+	}
+	tracks, err := p.playlistService.PreviewPlaylist(ctx.Request.Context(), &rules)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, tracks)
+}
+
+// FindPlaylist 	godoc
+// @Summary      FindPlaylist
+// @Description  Get a smart playlist and the tracks its rules currently select
+// @Tags         playlist
+// @Accept       json
+// @Produce      json
+// @Param        id  path  string  true  "Find by Playlist ID"
+// @Router       /playlist/get/{id} [get]
+func (p *PlaylistController) FindPlaylist(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	playlist, tracks, err := p.playlistService.FindPlaylist(ctx.Request.Context(), &id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"playlist": playlist, "tracks": tracks})
+}
+
+// GetUserPlaylists godoc
+// @Summary      List user playlists
+// @Description  get the smart playlists of a user
+// @Tags         playlist
+// @Accept       json
+// @Produce      json
+// @Param        userId  path  string  true  "User ID"
+// @Success      200  {array}   models.Playlist
+// @Router       /playlist/user/{userId} [get]
+func (p *PlaylistController) GetUserPlaylists(ctx *gin.Context) {
+	userId := ctx.Param("userId")
+	playlists, err := p.playlistService.GetUserPlaylists(ctx.Request.Context(), &userId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, playlists)
+}
+
+// UpdatePlaylist 	godoc
+// @Summary      UpdatePlaylist
+// @Description  Update your own smart playlist
+// @Tags         playlist
+// @Accept       json
+// @Produce      json
+// @Param        id  path  string  true  "Update by Playlist ID"
+// @Param        playlist   body     dto.PlaylistDto  true  "Playlist name and rules"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      200  {object}   models.Playlist
+// @Router       /playlist/update/{id} [put]
+func (p *PlaylistController) UpdatePlaylist(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	var body dto.PlaylistDto
+	if !bindJSON(ctx, &body) {
+		return
+	}
+	playlist := models.Playlist{Name: body.Name, Rules: body.Rules}
+	userId := currentUserId(ctx)
+	if err := p.playlistService.UpdatePlaylist(ctx.Request.Context(), &id, &userId, &playlist); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, playlist)
+}
+
+// DeletePlaylist 	godoc
+// @Summary      DeletePlaylist
+// @Description  delete your own smart playlist
+// @Tags         playlist
+// @Accept       json
+// @Produce      json
+// @Param        id  path  string  true  "Delete by Playlist ID"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Router       /playlist/delete/{id} [delete]
+func (p *PlaylistController) DeletePlaylist(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	userId := currentUserId(ctx)
+	if err := p.playlistService.DeletePlaylist(ctx.Request.Context(), &id, &userId); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "playlist deleted"})
+}
+
+func (p *PlaylistController) RegisterPlaylistRouter(rt *gin.RouterGroup) {
+	router := rt.Group("/playlist")
+	router.POST("/create", p.CreatePlaylist)
+	router.POST("/preview", p.PreviewPlaylist)
+	router.GET("/get/:id", p.FindPlaylist)
+	router.GET("/user/:userId", p.GetUserPlaylists)
+	router.PUT("/update/:id", p.UpdatePlaylist)
+	router.DELETE("/delete/:id", p.DeletePlaylist)
+}
@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"musiclib/models"
+	"musiclib/playlistfile"
+	"musiclib/services"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxPlaylistFileSize limits the size of imported playlist files.
+const maxPlaylistFileSize = 5 << 20
+
+type PlaylistFileController struct {
+	trackService    services.TrackService
+	albumService    services.AlbumService
+	playlistService services.PlaylistService
+	streamPath      string
+}
+
+// NewPlaylistFileController creates the import/export controller. streamPath
+// is the URL path tracks are streamed from, followed by the track id.
+func NewPlaylistFileController(trackService services.TrackService, albumService services.AlbumService, playlistService services.PlaylistService, streamPath string) *PlaylistFileController {
+	return &PlaylistFileController{
+		trackService:    trackService,
+		albumService:    albumService,
+		playlistService: playlistService,
+		streamPath:      streamPath,
+	}
+}
+
+// streamURL is the absolute stream URL of a track, based on PUBLIC_URL or
+// else on the host the request was sent to.
+func (p *PlaylistFileController) streamURL(ctx *gin.Context, trackId string) string {
+	base := strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/")
+	if base == "" {
+		scheme := "http"
+		if ctx.Request.TLS != nil {
+			scheme = "https"
+		}
+		base = scheme + "://" + ctx.Request.Host
+	}
+	return base + p.streamPath + trackId
+}
+
+func (p *PlaylistFileController) writePlaylist(ctx *gin.Context, title string, tracks []models.Track) {
+	format := strings.ToLower(ctx.DefaultQuery("format", playlistfile.M3U8))
+	entries := make([]models.PlaylistEntry, 0, len(tracks))
+	for _, track := range tracks {
+		entries = append(entries, models.PlaylistEntry{
+			Location:        p.streamURL(ctx, track.TrackId),
+			Title:           track.Title,
+			Artist:          track.Artist,
+			DurationSeconds: track.DurationSeconds,
+		})
+	}
+	var body bytes.Buffer
+	if err := playlistfile.Encode(&body, format, title, entries); err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	filename := strings.NewReplacer(`"`, "", "/", "-", "\\", "-").Replace(title) + "." + format
+	ctx.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	ctx.Data(http.StatusOK, playlistfile.ContentType(format), body.Bytes())
+}
+
+// ExportAlbum 	godoc
+// @Summary      ExportAlbum
+// @Description  Download an album as an M3U8, PLS or XSPF playlist of stream URLs
+// @Tags         playlist file
+// @Produce      octet-stream
+// @Param        id  path  string  true  "Album ID"
+// @Param        format  query  string  false  "m3u8 (default), pls or xspf"
+// @Router       /export/album/{id} [get]
+func (p *PlaylistFileController) ExportAlbum(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	album, err := p.albumService.FindAlbum(ctx.Request.Context(), &id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	p.writePlaylist(ctx, album.Title, album.Tracks)
+}
+
+// ExportPlaylist 	godoc
+// @Summary      ExportPlaylist
+// @Description  Download the current tracks of a smart playlist as an M3U8, PLS or XSPF playlist
+// @Tags         playlist file
+// @Produce      octet-stream
+// @Param        id  path  string  true  "Playlist ID"
+// @Param        format  query  string  false  "m3u8 (default), pls or xspf"
+// @Router       /export/playlist/{id} [get]
+func (p *PlaylistFileController) ExportPlaylist(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	playlist, tracks, err := p.playlistService.FindPlaylist(ctx.Request.Context(), &id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	p.writePlaylist(ctx, playlist.Name, tracks)
+}
+
+// ImportAlbum 	godoc
+// @Summary      ImportAlbum
+// @Description  Create an album from an M3U8, PLS or XSPF file. Entries are matched by path, then by title, artist and duration; unmatched entries are reported.
+// @Tags         playlist file
+// @Accept       plain
+// @Produce      json
+// @Param        format  query  string  true  "m3u8, pls or xspf"
+// @Param        title  query  string  false  "Album title, defaults to the title in the file"
+// @Param        file  body  string  true  "Playlist file"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Router       /import/album [post]
+func (p *PlaylistFileController) ImportAlbum(ctx *gin.Context) {
+	format := strings.ToLower(ctx.Query("format"))
+	body := http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxPlaylistFileSize)
+	title, entries, err := playlistfile.Decode(body, format)
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	if t := ctx.Query("title"); t != "" {
+		title = t
+	}
+	if title == "" {
+		title = "Imported playlist"
+	}
+
+	album := models.Album{Title: title, Tracks: []models.Track{}}
+	unmatched := []models.PlaylistEntry{}
+	for i := range entries {
+		track, err := p.trackService.MatchPlaylistEntry(ctx.Request.Context(), &entries[i])
+		if errors.Is(err, services.ErrNotFound) {
+			unmatched = append(unmatched, entries[i])
+			continue
+		}
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		album.Tracks = append(album.Tracks, *track)
+	}
+	if err := p.albumService.CreateAlbum(ctx.Request.Context(), &album); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"album": album, "matched": len(album.Tracks), "unmatched": unmatched})
+}
+
+func (p *PlaylistFileController) RegisterPlaylistFileRouter(rt *gin.RouterGroup) {
+	export := rt.Group("/export")
+	export.GET("/album/:id", p.ExportAlbum)
+	export.GET("/playlist/:id", p.ExportPlaylist)
+	rt.POST("/import/album", p.ImportAlbum)
+}
@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"musiclib/models"
+	"musiclib/radio"
+	"musiclib/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RadioController struct {
+	albumService    services.AlbumService
+	playlistService services.PlaylistService
+	hub             *radio.Hub
+}
+
+func NewRadioController(albumService services.AlbumService, playlistService services.PlaylistService, hub *radio.Hub) *RadioController {
+	return &RadioController{
+		albumService:    albumService,
+		playlistService: playlistService,
+		hub:             hub,
+	}
+}
+
+// AlbumStation 	godoc
+// @Summary      AlbumStation
+// @Description  Endless MP3 stream of an album, played on a loop like an Icecast station; all listeners hear the same moment. Send Icy-MetaData: 1 to get the title of the track playing every icy-metaint bytes. Only the MP3 tracks are played.
+// @Tags         radio
+// @Produce      audio/mpeg
+// @Param        id  path  string  true  "Album ID"
+// @Param        Icy-MetaData  header  string  false  "1 to interleave ICY metadata"
+// @Router       /radio/album/{id} [get]
+func (r *RadioController) AlbumStation(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	r.listen(ctx, "album/"+id.Hex(), func(load context.Context) (string, []models.Track, error) {
+		album, err := r.albumService.FindAlbum(load, &id)
+		if err != nil {
+			return "", nil, err
+		}
+		return album.Title, album.Tracks, nil
+	})
+}
+
+// PlaylistStation 	godoc
+// @Summary      PlaylistStation
+// @Description  Endless MP3 stream of the tracks a smart playlist selects, played on a loop like an Icecast station; all listeners hear the same moment. Send Icy-MetaData: 1 to get the title of the track playing every icy-metaint bytes. Only the MP3 tracks are played.
+// @Tags         radio
+// @Produce      audio/mpeg
+// @Param        id  path  string  true  "Playlist ID"
+// @Param        Icy-MetaData  header  string  false  "1 to interleave ICY metadata"
+// @Router       /radio/playlist/{id} [get]
+func (r *RadioController) PlaylistStation(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	r.listen(ctx, "playlist/"+id.Hex(), func(load context.Context) (string, []models.Track, error) {
+		playlist, tracks, err := r.playlistService.FindPlaylist(load, &id)
+		if err != nil {
+			return "", nil, err
+		}
+		return playlist.Name, tracks, nil
+	})
+}
+
+// listen streams the station key to the client until it disconnects or
+// the station stops.
+func (r *RadioController) listen(ctx *gin.Context, key string, source radio.Source) {
+	listener, err := r.hub.Listen(ctx.Request.Context(), key, source)
+	if errors.Is(err, radio.ErrNoTracks) {
+		ctx.Error(services.NotFound(err.Error()))
+		return
+	}
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	defer listener.Close()
+
+	metadata := ctx.GetHeader("Icy-MetaData") == "1"
+	ctx.Header("Content-Type", "audio/mpeg")
+	ctx.Header("Cache-Control", "no-cache, no-store")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Header("icy-name", listener.Name)
+	ctx.Header("icy-pub", "0")
+	if metadata {
+		ctx.Header("icy-metaint", strconv.Itoa(radio.MetaInt))
+	}
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	icy := radio.NewIcyWriter(ctx.Writer)
+	request := ctx.Request.Context()
+	for {
+		select {
+		case chunk, ok := <-listener.C:
+			if !ok {
+				return
+			}
+			if metadata {
+				err = icy.Write(chunk)
+			} else {
+				_, err = ctx.Writer.Write(chunk.Data)
+			}
+			if err != nil {
+				return
+			}
+			ctx.Writer.Flush()
+		case <-request.Done():
+			return
+		}
+	}
+}
+
+// GetStations 	godoc
+// @Summary      GetStations
+// @Description  List the radio stations on air, with their listeners and the track playing
+// @Tags         radio
+// @Produce      json
+// @Success      200  {array}   radio.StationInfo
+// @Router       /radio/stations [get]
+func (r *RadioController) GetStations(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, r.hub.Stations())
+}
+
+func (r *RadioController) RegisterRadioRouter(rt *gin.RouterGroup) {
+	router := rt.Group("/radio")
+	router.GET("/album/:id", r.AlbumStation)
+	router.GET("/playlist/:id", r.PlaylistStation)
+	router.GET("/stations", r.GetStations)
+}
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"musiclib/dto"
+	"musiclib/models"
+	"musiclib/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ReviewController struct {
+	reviewService services.ReviewService
+}
+
+func NewReviewController(reviewService services.ReviewService) *ReviewController {
+	return &ReviewController{
+		reviewService: reviewService,
+	}
+}
+
+// reviewFromDto maps a validated request body to a review.
+func reviewFromDto(body *dto.ReviewDto) *models.Review {
+	return &models.Review{Rating: body.Rating, Text: body.Text}
+}
+
+// currentUserId returns the id of the user authenticated by the JWT middleware.
+func currentUserId(ctx *gin.Context) string {
+	user, ok := ctx.Get("userId")
+	if !ok {
+		return ""
+	}
+	return user.(*models.User).UserId
+}
+
+// CreateReview	godoc
+// @Summary      CreateReview
+// @Description  Rate and review an album, once per user
+// @Tags         review
+// @Accept       json
+// @Produce      json
+// @Param        albumId  path  string  true  "Album ID"
+// @Param        review   body     dto.ReviewDto  true  "Rating (1-5) and optional text"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      201  {object}   models.Review
+// @Router       /review/create/{albumId} [post]
+func (r *ReviewController) CreateReview(ctx *gin.Context) {
+	albumId, err := primitive.ObjectIDFromHex(ctx.Param("albumId"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	var body dto.ReviewDto
+	if !bindJSON(ctx, &body) {
+		return
+	}
+	review := reviewFromDto(&body)
+	review.AlbumId = albumId.Hex()
+	review.UserId = currentUserId(ctx)
+	if err := r.reviewService.CreateReview(ctx.Request.Context(), review); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, review)
+}
+
+// GetAlbumReviews godoc
+// @Summary      List album reviews
+// @Description  get the reviews of an album
+// @Tags         review
+// @Accept       json
+// @Produce      json
+// @Param        albumId  path  string  true  "Album ID"
+// @Success      200  {array}   models.Review
+// @Router       /review/album/{albumId} [get]
+func (r *ReviewController) GetAlbumReviews(ctx *gin.Context) {
+	albumId, err := primitive.ObjectIDFromHex(ctx.Param("albumId"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	reviews, err := r.reviewService.GetAlbumReviews(ctx.Request.Context(), &albumId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, reviews)
+}
+
+// UpdateReview 	godoc
+// @Summary      UpdateReview
+// @Description  Update your own review
+// @Tags         review
+// @Accept       json
+// @Produce      json
+// @Param        id  path  string  true  "Update by Review ID"
+// @Param        review   body     dto.ReviewDto  true  "Rating (1-5) and optional text"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      200  {object}   models.Review
+// @Router       /review/update/{id} [put]
+func (r *ReviewController) UpdateReview(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	var body dto.ReviewDto
+	if !bindJSON(ctx, &body) {
+		return
+	}
+	review := reviewFromDto(&body)
+	userId := currentUserId(ctx)
+	if err := r.reviewService.UpdateReview(ctx.Request.Context(), &id, &userId, review); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, review)
+}
+
+// DeleteReview 	godoc
+// @Summary      DeleteReview
+// @Description  delete your own review
+// @Tags         review
+// @Accept       json
+// @Produce      json
+// @Param        id  path  string  true  "Delete by Review ID"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Router       /review/delete/{id} [delete]
+func (r *ReviewController) DeleteReview(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	userId := currentUserId(ctx)
+	if err := r.reviewService.DeleteReview(ctx.Request.Context(), &id, &userId); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "review deleted"})
+}
+
+func (r *ReviewController) RegisterReviewRouter(rt *gin.RouterGroup) {
+	router := rt.Group("/review")
+	router.POST("/create/:albumId", r.CreateReview)
+	router.GET("/album/:albumId", r.GetAlbumReviews)
+	router.PUT("/update/:id", r.UpdateReview)
+	router.DELETE("/delete/:id", r.DeleteReview)
+}
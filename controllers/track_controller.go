@@ -1,9 +1,12 @@
 package controllers
 
 import (
+	"musiclib/dto"
+	"musiclib/helper"
 	"musiclib/models"
 	"musiclib/services"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -18,11 +21,29 @@ func NewTrackController(trackService services.TrackService) *TrackController {
 		trackService: trackService,
 	}
 }
-func CheckValidTrack(track *models.Track) bool {
-	if track.Title == "" || track.Artist == "" || track.Genre == "" || track.Duration == "" || track.FileName == "" || track.ReleaseYear == "" {
-		return false
+
+// trackFromDto maps a validated request body to a track.
+func trackFromDto(body *dto.TrackDto) *models.Track {
+	return &models.Track{
+		Title:       body.Title,
+		Artist:      body.Artist,
+		Genre:       body.Genre,
+		ReleaseYear: body.ReleaseYear,
+		Duration:    body.Duration,
+		FileName:    body.FileName,
+	}
+}
+
+// trackToDto is the request body a track would be created from.
+func trackToDto(track *models.Track) dto.TrackDto {
+	return dto.TrackDto{
+		Title:       track.Title,
+		Artist:      track.Artist,
+		Genre:       track.Genre,
+		ReleaseYear: track.ReleaseYear,
+		Duration:    track.Duration,
+		FileName:    track.FileName,
 	}
-	return true
 }
 
 // CreateTrack 	godoc
@@ -36,17 +57,13 @@ func CheckValidTrack(track *models.Track) bool {
 // @param Authorization header string true "Authorization"
 // @Router       /track/create [post]
 func (t *TrackController) CreateTrack(ctx *gin.Context) {
-	var track models.Track
-	if err := ctx.ShouldBindJSON(&track); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-	if !CheckValidTrack(&track) {
-		ctx.JSON(http.StatusBadRequest, gin.H{"message": "wrong input structure"})
+	var body dto.TrackDto
+	if !bindJSON(ctx, &body) {
 		return
 	}
-	if err := t.trackService.CreateTrack(&track); err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	track := trackFromDto(&body)
+	if err := t.trackService.CreateTrack(ctx.Request.Context(), track); err != nil {
+		ctx.Error(err)
 		return
 	}
 	ctx.JSON(http.StatusCreated, track)
@@ -61,9 +78,9 @@ func (t *TrackController) CreateTrack(ctx *gin.Context) {
 // @Success      200  {array}   models.Track
 // @Router       /track/getAll [get]
 func (t *TrackController) GetTracks(ctx *gin.Context) {
-	tracks, err := t.trackService.GetTracks()
+	tracks, err := t.trackService.GetTracks(ctx.Request.Context())
 	if err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 	ctx.JSON(http.StatusOK, tracks)
@@ -79,47 +96,101 @@ func (t *TrackController) GetTracks(ctx *gin.Context) {
 // @Param        track   body     dto.TrackDto  true  "Track data to update"
 // @Security ApiKeyAuth
 // @param Authorization header string true "Authorization"
+// @Param        If-Match  header  string  false  "ETag of the version this change is based on"
 // @Router       /track/update/{id} [put]
 func (t *TrackController) UpdateTrack(ctx *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(ctx, err.Error())
 		return
 	}
-	var track models.Track
-	if err := ctx.ShouldBindJSON(&track); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	version, ok := ifMatch(ctx)
+	if !ok {
 		return
 	}
-	if !CheckValidTrack(&track) {
-		ctx.JSON(http.StatusBadRequest, gin.H{"message": "wrong input structure"})
+	var body dto.TrackDto
+	if !bindJSON(ctx, &body) {
 		return
 	}
-	if err := t.trackService.UpdateTrack(&id, &track); err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	track := trackFromDto(&body)
+	if err := t.trackService.UpdateTrack(ctx.Request.Context(), &id, track, version); err != nil {
+		ctx.Error(err)
 		return
 	}
+	updatedETag(ctx, version)
+	ctx.JSON(http.StatusOK, track)
+}
+
+// PatchTrack 	godoc
+// @Summary      PatchTrack
+// @Description  Change some fields of a track with a JSON merge patch. The patched track must be valid.
+// @Tags         track
+// @Accept       application/merge-patch+json
+// @Produce      json
+// @Param        id  path  string  true  "Update by Track ID"
+// @Param        patch   body     dto.TrackDto  true  "Fields to change"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      200  {object}   models.Track
+// @Param        If-Match  header  string  false  "ETag of the version this change is based on"
+// @Router       /track/update/{id} [patch]
+func (t *TrackController) PatchTrack(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	version, ok := ifMatch(ctx)
+	if !ok {
+		return
+	}
+	track, err := t.trackService.FindTrack(ctx.Request.Context(), &id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	body := trackToDto(track)
+	fields, ok := mergePatch(ctx, &body)
+	if !ok {
+		return
+	}
+	if len(fields) > 0 {
+		if err := t.trackService.PatchTrack(ctx.Request.Context(), &id, trackFromDto(&body), fields, version); err != nil {
+			ctx.Error(err)
+			return
+		}
+		if track, err = t.trackService.FindTrack(ctx.Request.Context(), &id); err != nil {
+			ctx.Error(err)
+			return
+		}
+	}
+	ctx.Header("ETag", etag(track.Version))
 	ctx.JSON(http.StatusOK, track)
 }
 
 // DeleteTrack 	godoc
 // @Summary      DeleteTrack
-// @Description  delete a track
+// @Description  move a track to the trash
 // @Tags         track
 // @Accept       json
 // @Produce      json
 // @Param        id  path  string  true  "Delete by Track ID"
 // @Security ApiKeyAuth
 // @param Authorization header string true "Authorization"
+// @Param        If-Match  header  string  false  "ETag of the version this change is based on"
 // @Router       /track/delete/{id} [delete]
 func (t *TrackController) DeleteTrack(ctx *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(ctx, err.Error())
+		return
+	}
+	version, ok := ifMatch(ctx)
+	if !ok {
 		return
 	}
-	if err := t.trackService.DeleteTrack(&id); err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	if err := t.trackService.DeleteTrack(ctx.Request.Context(), &id, version); err != nil {
+		ctx.Error(err)
 		return
 	}
 	ctx.JSON(http.StatusOK, gin.H{"message": "track deleted"})
@@ -133,25 +204,118 @@ func (t *TrackController) DeleteTrack(ctx *gin.Context) {
 // @Produce      json
 // @Param        id  path  string  true  "Find by Track ID"
 // @Success      200  {object}   models.Track
+// @Param        If-None-Match  header  string  false  "ETag of a cached copy"
 // @Router       /track/get/{id} [get]
 func (t *TrackController) FindTrack(ctx *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		badRequest(ctx, err.Error())
 		return
 	}
-	track, err := t.trackService.FindTrack(&id)
+	track, err := t.trackService.FindTrack(ctx.Request.Context(), &id)
 	if err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		ctx.Error(err)
+		return
+	}
+	if notModified(ctx, track.Version) {
 		return
 	}
 	ctx.JSON(http.StatusOK, track)
 }
+
+// GetSimilarTracks 	godoc
+// @Summary      GetSimilarTracks
+// @Description  Get tracks similar to a track, with the reasons they were picked
+// @Tags         track
+// @Accept       json
+// @Produce      json
+// @Param        id  path  string  true  "Track ID"
+// @Param        limit  query  int  false  "Maximum number of tracks (default 10, at most 100)"
+// @Success      200  {array}   models.SimilarTrack
+// @Router       /track/{id}/similar [get]
+func (t *TrackController) GetSimilarTracks(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		badRequest(ctx, "limit must be a positive number")
+		return
+	}
+	tracks, err := t.trackService.FindSimilarTracks(ctx.Request.Context(), &id, limit)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, tracks)
+}
+
+// RecordPlay 	godoc
+// @Summary      RecordPlay
+// @Description  Record that the current user played a track
+// @Tags         track
+// @Accept       json
+// @Produce      json
+// @Param        id  path  string  true  "Track ID"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      201  {object}   models.Play
+// @Router       /track/play/{id} [post]
+func (t *TrackController) RecordPlay(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	play := models.Play{TrackId: id.Hex(), UserId: currentUserId(ctx)}
+	if err := t.trackService.RecordPlay(ctx.Request.Context(), &play); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, play)
+}
+
+// StreamTrack 	godoc
+// @Summary      StreamTrack
+// @Description  Stream the audio file of a track, with range support. Only files in the library roots are served
+// @Tags         track
+// @Produce      octet-stream
+// @Param        id  path  string  true  "Track ID"
+// @Router       /track/stream/{id} [get]
+func (t *TrackController) StreamTrack(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	track, err := t.trackService.FindTrack(ctx.Request.Context(), &id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	if track.Unavailable {
+		ctx.Error(services.NotFound("track file is unavailable"))
+		return
+	}
+	// Only files in the library are served, whatever the track says.
+	path, ok := helper.LibraryFile(track.FileName)
+	if !ok {
+		ctx.Error(services.NotFound("track file not found"))
+		return
+	}
+	ctx.File(path)
+}
 func (t *TrackController) RegisterTrackRouter(rt *gin.RouterGroup) {
 	router := rt.Group("/track")
 	router.POST("/create", t.CreateTrack)
 	router.GET("/getAll", t.GetTracks)
 	router.PUT("/update/:id", t.UpdateTrack)
+	router.PATCH("/update/:id", t.PatchTrack)
 	router.DELETE("/delete/:id", t.DeleteTrack)
 	router.GET("/get/:id", t.FindTrack)
+	router.GET("/:id/similar", t.GetSimilarTracks)
+	router.POST("/play/:id", t.RecordPlay)
+	router.GET("/stream/:id", t.StreamTrack)
 }
@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"musiclib/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TrashController struct {
+	trackService services.TrackService
+	albumService services.AlbumService
+	userService  services.UserService
+}
+
+func NewTrashController(trackService services.TrackService, albumService services.AlbumService, userService services.UserService) *TrashController {
+	return &TrashController{
+		trackService: trackService,
+		albumService: albumService,
+		userService:  userService,
+	}
+}
+
+// GetDeletedTracks godoc
+// @Summary      GetDeletedTracks
+// @Description  List the tracks in the trash, most recently deleted first (admin only)
+// @Tags         admin
+// @Produce      json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      200  {array}   models.Track
+// @Router       /admin/track/trash [get]
+func (t *TrashController) GetDeletedTracks(ctx *gin.Context) {
+	tracks, err := t.trackService.GetDeletedTracks(ctx.Request.Context())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, tracks)
+}
+
+// RestoreTrack godoc
+// @Summary      RestoreTrack
+// @Description  Take a track out of the trash (admin only)
+// @Tags         admin
+// @Produce      json
+// @Param        id  path  string  true  "Track ID"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Router       /admin/track/restore/{id} [post]
+func (t *TrashController) RestoreTrack(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	if err := t.trackService.RestoreTrack(ctx.Request.Context(), &id); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "track restored"})
+}
+
+// GetDeletedAlbums godoc
+// @Summary      GetDeletedAlbums
+// @Description  List the albums in the trash, most recently deleted first (admin only)
+// @Tags         admin
+// @Produce      json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      200  {array}   models.Album
+// @Router       /admin/album/trash [get]
+func (t *TrashController) GetDeletedAlbums(ctx *gin.Context) {
+	albums, err := t.albumService.GetDeletedAlbums(ctx.Request.Context())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, albums)
+}
+
+// RestoreAlbum godoc
+// @Summary      RestoreAlbum
+// @Description  Take an album out of the trash (admin only)
+// @Tags         admin
+// @Produce      json
+// @Param        id  path  string  true  "Album ID"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Router       /admin/album/restore/{id} [post]
+func (t *TrashController) RestoreAlbum(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	if err := t.albumService.RestoreAlbum(ctx.Request.Context(), &id); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "album restored"})
+}
+
+// GetDeletedUsers godoc
+// @Summary      GetDeletedUsers
+// @Description  List the users in the trash, most recently deleted first (admin only)
+// @Tags         admin
+// @Produce      json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      200  {array}   models.User
+// @Router       /admin/user/trash [get]
+func (t *TrashController) GetDeletedUsers(ctx *gin.Context) {
+	users, err := t.userService.GetDeletedUsers(ctx.Request.Context())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, users)
+}
+
+// RestoreUser godoc
+// @Summary      RestoreUser
+// @Description  Take a user out of the trash; fails with 409 when the username was taken since (admin only)
+// @Tags         admin
+// @Produce      json
+// @Param        id  path  string  true  "User ID"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Router       /admin/user/restore/{id} [post]
+func (t *TrashController) RestoreUser(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	if err := t.userService.RestoreUser(ctx.Request.Context(), &id); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "user restored"})
+}
+
+func (t *TrashController) RegisterTrashRouter(admin *gin.RouterGroup) {
+	admin.GET("/track/trash", t.GetDeletedTracks)
+	admin.POST("/track/restore/:id", t.RestoreTrack)
+	admin.GET("/album/trash", t.GetDeletedAlbums)
+	admin.POST("/album/restore/:id", t.RestoreAlbum)
+	admin.GET("/user/trash", t.GetDeletedUsers)
+	admin.POST("/user/restore/:id", t.RestoreUser)
+}
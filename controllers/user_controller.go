@@ -1,10 +1,12 @@
 package controllers
 
 import (
+	"musiclib/dto"
 	"musiclib/helper"
 	"musiclib/models"
 	"musiclib/services"
 	"net/http"
+	"slices"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -17,7 +19,7 @@ type UserController struct {
 type ResetPassword struct {
 	Username    string `form:"username" json:"username" binding:"required"`
 	OldPassword string `form:"old_password" json:"old_password" binding:"required"`
-	NewPassword string `form:"new_password" json:"new_password" binding:"required"`
+	NewPassword string `form:"new_password" json:"new_password" binding:"required,min=6,max=72"`
 }
 
 func NewUserController(userService services.UserService) *UserController {
@@ -35,25 +37,22 @@ func NewUserController(userService services.UserService) *UserController {
 // @Param        user   body     dto.UserDto  true  "User data to create"
 // @Router       /user/create [post]
 func (uc *UserController) CreateUser(ctx *gin.Context) {
-	var user models.User
-	if err := ctx.ShouldBindJSON(&user); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
-		return
-	}
-
-	if user.Username == "" || user.Password == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"message": "wrong input structure"})
+	var body dto.UserDto
+	if !bindJSON(ctx, &body) {
 		return
 	}
+	// roles are only granted directly in the database
+	user := models.User{Username: body.Username, Password: body.Password}
 
 	hashPassword, err := helper.HashPassword(user.Password)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"message": "failed to hash the password"})
+		ctx.Error(err)
+		return
 	}
 	user.Password = hashPassword
 
-	if err := uc.UserService.CreateUser(&user); err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"message": err.Error()})
+	if err := uc.UserService.CreateUser(ctx.Request.Context(), &user); err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -70,13 +69,17 @@ func (uc *UserController) CreateUser(ctx *gin.Context) {
 // @Security ApiKeyAuth
 // @param Authorization header string true "Authorization"
 // @Success      200  {object}   models.User
+// @Param        If-None-Match  header  string  false  "ETag of a cached copy"
 // @Router       /user/get/{id} [get]
 func (uc *UserController) GetUser(ctx *gin.Context) {
 	userId := ctx.Param("id")
-	user, err := uc.UserService.GetUser(&userId)
+	user, err := uc.UserService.GetUser(ctx.Request.Context(), &userId)
 
 	if err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"message": err.Error()})
+		ctx.Error(err)
+		return
+	}
+	if notModified(ctx, user.Version) {
 		return
 	}
 	ctx.JSON(http.StatusOK, user)
@@ -88,31 +91,91 @@ func (uc *UserController) GetUser(ctx *gin.Context) {
 // @Tags         user
 // @Accept       json
 // @Produce      json
-// @Param        user   body     models.User  true  "User data to update"
+// @Param        user   body     dto.UpdateUserDto  true  "User data to update"
 // @Security ApiKeyAuth
 // @param Authorization header string true "Authorization"
+// @Param        If-Match  header  string  false  "ETag of the version this change is based on"
 // @Router       /user/update [patch]
 func (uc *UserController) UpdateUser(ctx *gin.Context) {
-	var user models.User
-
-	if err := ctx.ShouldBindJSON(&user); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+	version, ok := ifMatch(ctx)
+	if !ok {
 		return
 	}
-
-	if user.UserId == "" || user.Username == "" || user.Password == "" {
-		ctx.JSON(http.StatusBadRequest, gin.H{"message": "wrong input structure"})
+	var body dto.UpdateUserDto
+	if !bindJSON(ctx, &body) {
 		return
 	}
+	user := models.User{UserId: body.UserId, Username: body.Username, Password: body.Password}
 
-	if err := uc.UserService.UpdateUser(&user); err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"message": err.Error()})
+	if err := uc.UserService.UpdateUser(ctx.Request.Context(), &user, version); err != nil {
+		ctx.Error(err)
 		return
 	}
+	updatedETag(ctx, version)
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "Successful"})
 }
 
+// PatchUser 	godoc
+// @Summary      PatchUser
+// @Description  Change the username or password of your own user, or of any user as an admin, with a JSON merge patch
+// @Tags         user
+// @Accept       application/merge-patch+json
+// @Produce      json
+// @Param        id  path  string  true  "Update by User ID"
+// @Param        patch   body     dto.UserDto  true  "Fields to change"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Param        If-Match  header  string  false  "ETag of the version this change is based on"
+// @Router       /user/update/{id} [patch]
+func (uc *UserController) PatchUser(ctx *gin.Context) {
+	userId := ctx.Param("id")
+	if callerId := currentUserId(ctx); callerId != userId {
+		// The token only names the caller; the role is read from the
+		// database, as for the admin routes.
+		caller, err := uc.UserService.GetUser(ctx.Request.Context(), &callerId)
+		if err != nil || caller.Role != models.RoleAdmin {
+			ctx.Error(services.Forbidden("cannot change another user"))
+			return
+		}
+	}
+	version, ok := ifMatch(ctx)
+	if !ok {
+		return
+	}
+	user, err := uc.UserService.GetUser(ctx.Request.Context(), &userId)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	body := dto.UserDto{Username: user.Username, Password: user.Password}
+	fields, ok := mergePatch(ctx, &body)
+	if !ok {
+		return
+	}
+	if len(fields) == 0 {
+		ctx.JSON(http.StatusOK, gin.H{"message": "Successful"})
+		return
+	}
+	user.Username = body.Username
+	if slices.Contains(fields, "password") {
+		if user.Password, err = helper.HashPassword(body.Password); err != nil {
+			ctx.Error(err)
+			return
+		}
+	}
+	if err := uc.UserService.PatchUser(ctx.Request.Context(), user, fields, version); err != nil {
+		ctx.Error(err)
+		return
+	}
+	if user, err = uc.UserService.GetUser(ctx.Request.Context(), &userId); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.Header("ETag", etag(user.Version))
+	ctx.JSON(http.StatusOK, gin.H{"message": "Successful"})
+}
+
 // ChangePass 	godoc
 // @Summary      ChangePass
 // @Description  change pass a user
@@ -125,14 +188,12 @@ func (uc *UserController) UpdateUser(ctx *gin.Context) {
 // @Router       /user/change_password [patch]
 func (uc *UserController) ChangePassword(ctx *gin.Context) {
 	var resetPassword ResetPassword
-
-	if err := ctx.ShouldBindJSON(&resetPassword); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+	if !bindJSON(ctx, &resetPassword) {
 		return
 	}
 
-	if err := uc.UserService.ChangePassword(&resetPassword.Username, &resetPassword.OldPassword, &resetPassword.NewPassword); err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"message": err.Error()})
+	if err := uc.UserService.ChangePassword(ctx.Request.Context(), &resetPassword.Username, &resetPassword.OldPassword, &resetPassword.NewPassword); err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -142,25 +203,30 @@ func (uc *UserController) ChangePassword(ctx *gin.Context) {
 
 // DeleteUser 	godoc
 // @Summary      DeleteUser
-// @Description  delete a user
+// @Description  move a user to the trash
 // @Tags         user
 // @Accept       json
 // @Produce      json
 // @Param        id  path  string  true  "Delete by User ID"
 // @Security ApiKeyAuth
 // @param Authorization header string true "Authorization"
+// @Param        If-Match  header  string  false  "ETag of the version this change is based on"
 // @Router       /user/delete/{id} [delete]
 func (uc *UserController) DeleteUser(ctx *gin.Context) {
 	userId, err := primitive.ObjectIDFromHex(ctx.Param("id"))
 
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid user id"})
+		badRequest(ctx, "Invalid user id")
+		return
+	}
+	version, ok := ifMatch(ctx)
+	if !ok {
 		return
 	}
-	err = uc.UserService.DeleteUser(&userId)
+	err = uc.UserService.DeleteUser(ctx.Request.Context(), &userId, version)
 
 	if err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"message": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
@@ -176,6 +242,8 @@ func (uc *UserController) RegisterUserRoute(rg *gin.RouterGroup) {
 
 	userRoute.PATCH("/update", uc.UpdateUser)
 
+	userRoute.PATCH("/update/:id", uc.PatchUser)
+
 	userRoute.PATCH("/change_password", uc.ChangePassword)
 
 	userRoute.DELETE("/delete/:id", uc.DeleteUser)
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"musiclib/dto"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError is one invalid field of a request body. Code is the rule the
+// field broke, such as required, max or genre.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// bindJSON binds the request body to obj and runs its binding tags. When the
+// body is invalid it answers a 422 problem listing every invalid field, or
+// 400 if the body is not JSON at all, and returns false. The body is kept
+// so a handler may bind it more than once.
+func bindJSON(ctx *gin.Context, obj interface{}) bool {
+	return checkBody(ctx, ctx.ShouldBindBodyWith(obj, binding.JSON))
+}
+
+// checkBody answers the error of decoding or validating a request body, if
+// any, and reports whether there was none.
+func checkBody(ctx *gin.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &validationErrs):
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, fieldError(fe))
+		}
+		invalidFields(ctx, fields)
+	case errors.As(err, &typeErr):
+		invalidFields(ctx, []FieldError{{Field: typeErr.Field, Code: "type", Message: "must be a " + typeErr.Type.String()}})
+	default:
+		badRequest(ctx, err.Error())
+	}
+	return false
+}
+
+func invalidFields(ctx *gin.Context, fields []FieldError) {
+	writeProblem(ctx, Problem{Status: http.StatusUnprocessableEntity, Detail: "validation failed", Errors: fields})
+}
+
+func fieldError(fe validator.FieldError) FieldError {
+	// The namespace starts with the name of the bound struct.
+	_, field, _ := strings.Cut(fe.Namespace(), ".")
+	return FieldError{Field: field, Code: fe.Tag(), Message: fieldMessage(fe)}
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min", "max":
+		limit := "at least"
+		if fe.Tag() == "max" {
+			limit = "at most"
+		}
+		switch fe.Kind().String() {
+		case "string":
+			return fmt.Sprintf("must be %s %s characters long", limit, fe.Param())
+		case "slice", "array", "map":
+			return fmt.Sprintf("must have %s %s items", limit, fe.Param())
+		}
+		return fmt.Sprintf("must be %s %s", limit, fe.Param())
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "alphanum":
+		return "must contain only letters and digits"
+	case "uri":
+		return "must be a URL or an absolute path"
+	case "http_url":
+		return "must be an http or https URL"
+	case "mongodb":
+		return "must be an id"
+	case "genre":
+		return "is not a supported genre"
+	case "year":
+		return fmt.Sprintf("must be a year from %d to next year", dto.MinReleaseYear)
+	case "duration":
+		return "must be a duration such as 4:35 or 275"
+	case "event":
+		return "is not a known event"
+	}
+	return "is invalid"
+}
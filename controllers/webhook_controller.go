@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"musiclib/dto"
+	"musiclib/models"
+	"musiclib/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WebhookController struct {
+	webhookService services.WebhookService
+}
+
+func NewWebhookController(webhookService services.WebhookService) *WebhookController {
+	return &WebhookController{
+		webhookService: webhookService,
+	}
+}
+
+// CreateWebhook godoc
+// @Summary      CreateWebhook
+// @Description  Register an endpoint for catalog events. The answer holds the secret deliveries are signed with; it is not shown again. (admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        webhook   body     dto.WebhookDto  true  "Endpoint and events"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      201  {object}   models.Webhook
+// @Router       /admin/webhook [post]
+func (w *WebhookController) CreateWebhook(ctx *gin.Context) {
+	var body dto.WebhookDto
+	if !bindJSON(ctx, &body) {
+		return
+	}
+	webhook := models.Webhook{URL: body.URL, Events: body.Events}
+	if err := w.webhookService.CreateWebhook(ctx.Request.Context(), &webhook); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, webhook)
+}
+
+// GetWebhooks godoc
+// @Summary      GetWebhooks
+// @Description  List the registered webhooks (admin only)
+// @Tags         admin
+// @Produce      json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      200  {array}   models.Webhook
+// @Router       /admin/webhook [get]
+func (w *WebhookController) GetWebhooks(ctx *gin.Context) {
+	webhooks, err := w.webhookService.GetWebhooks(ctx.Request.Context())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, webhooks)
+}
+
+// DeleteWebhook godoc
+// @Summary      DeleteWebhook
+// @Description  Remove a webhook (admin only)
+// @Tags         admin
+// @Produce      json
+// @Param        id  path  string  true  "Webhook ID"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Router       /admin/webhook/{id} [delete]
+func (w *WebhookController) DeleteWebhook(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	if err := w.webhookService.DeleteWebhook(ctx.Request.Context(), &id); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "webhook deleted"})
+}
+
+// GetDeliveries godoc
+// @Summary      GetDeliveries
+// @Description  List the latest deliveries of a webhook with their status (admin only)
+// @Tags         admin
+// @Produce      json
+// @Param        id  path  string  true  "Webhook ID"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      200  {array}   models.WebhookDelivery
+// @Router       /admin/webhook/{id}/deliveries [get]
+func (w *WebhookController) GetDeliveries(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	deliveries, err := w.webhookService.GetDeliveries(ctx.Request.Context(), &id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, deliveries)
+}
+
+// ReplayDelivery godoc
+// @Summary      ReplayDelivery
+// @Description  Send the payload of a past delivery again, as a new delivery (admin only)
+// @Tags         admin
+// @Produce      json
+// @Param        id  path  string  true  "Delivery ID"
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Success      202  {object}   models.WebhookDelivery
+// @Router       /admin/webhook/delivery/{id}/replay [post]
+func (w *WebhookController) ReplayDelivery(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		badRequest(ctx, err.Error())
+		return
+	}
+	delivery, err := w.webhookService.ReplayDelivery(ctx.Request.Context(), &id)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusAccepted, delivery)
+}
+
+func (w *WebhookController) RegisterWebhookRouter(admin *gin.RouterGroup) {
+	router := admin.Group("/webhook")
+	router.POST("", w.CreateWebhook)
+	router.GET("", w.GetWebhooks)
+	router.DELETE("/:id", w.DeleteWebhook)
+	router.GET("/:id/deliveries", w.GetDeliveries)
+	router.POST("/delivery/:id/replay", w.ReplayDelivery)
+}
@@ -24,70 +24,55 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/album/add_track/{id}": {
+        "/admin/album/restore/{id}": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Add a Track to Album",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Take an album out of the trash (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "album"
+                    "admin"
                 ],
-                "summary": "AddTrackToAlbum",
+                "summary": "RestoreAlbum",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Authorization",
-                        "name": "Authorization",
-                        "in": "header",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Find by album ID",
+                        "description": "Album ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Track data to create",
-                        "name": "track",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.TrackDto"
-                        }
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
                     }
                 ],
                 "responses": {}
             }
         },
-        "/album/create": {
-            "post": {
+        "/admin/album/trash": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "create a Album",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "List the albums in the trash, most recently deleted first (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "album"
+                    "admin"
                 ],
-                "summary": "CreateAlbum",
+                "summary": "GetDeletedAlbums",
                 "parameters": [
                     {
                         "type": "string",
@@ -95,45 +80,78 @@ const docTemplate = `{
                         "name": "Authorization",
                         "in": "header",
                         "required": true
-                    },
-                    {
-                        "description": "Album data to create",
-                        "name": "album",
-                        "in": "body",
-                        "required": true,
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/dto.AlbumDto"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Album"
+                            }
                         }
                     }
-                ],
-                "responses": {}
+                }
             }
         },
-        "/album/delete/{id}": {
-            "delete": {
+        "/admin/audit": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "delete a album",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Query the audit log of catalog and account changes, newest first (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "album"
+                    "admin"
                 ],
-                "summary": "DeleteAlbum",
+                "summary": "GetAuditEntries",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Delete by Album ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "track, album or user",
+                        "name": "resource",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Resource ID",
+                        "name": "resource_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID of the actor",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "create, update, delete, restore, add-track, remove-track or merge",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time, inclusive",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time, inclusive",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of entries (default 100, at most 1000)",
+                        "name": "limit",
+                        "in": "query"
                     },
                     {
                         "type": "string",
@@ -143,75 +161,68 @@ const docTemplate = `{
                         "required": true
                     }
                 ],
-                "responses": {}
-            }
-        },
-        "/album/get/{id}": {
-            "get": {
-                "description": "Get a album",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "album"
-                ],
-                "summary": "GetAlbum",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Find by album ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Album"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.AuditEntry"
+                            }
                         }
                     }
                 }
             }
         },
-        "/album/getAll": {
+        "/admin/jobs": {
             "get": {
-                "description": "get albums",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
+                "description": "List the latest 100 jobs, newest first. Failed jobs that ran out of attempts are dead. (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "album"
+                    "admin"
+                ],
+                "summary": "GetJobs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "queued, running, succeeded or dead",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
                 ],
-                "summary": "List albums",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Album"
+                                "$ref": "#/definitions/models.Job"
                             }
                         }
                     }
                 }
-            }
-        },
-        "/album/remove_track/{id}/{trackId}": {
-            "put": {
+            },
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Remove a Track from Album",
+                "description": "Queue a background job: scan {\"dir\": \"...\"} imports the audio files below dir, charts recomputes the charts and duplicates finds duplicate tracks. A job with a key is not queued twice: while one with the same key is queued or running, that one is returned. (admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -219,23 +230,18 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "album"
+                    "admin"
                 ],
-                "summary": "RemoveTrackFromAlbum",
+                "summary": "Enqueue",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Find by Album ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Remove by Track ID",
-                        "name": "trackId",
-                        "in": "path",
-                        "required": true
+                        "description": "Type, payload and options",
+                        "name": "job",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.JobDto"
+                        }
                     },
                     {
                         "type": "string",
@@ -245,69 +251,80 @@ const docTemplate = `{
                         "required": true
                     }
                 ],
-                "responses": {}
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.Job"
+                        }
+                    }
+                }
             }
         },
-        "/album/search": {
+        "/admin/jobs/{id}": {
             "get": {
-                "description": "Get list tracks and albums by keyword",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
+                "description": "Get a job with its status, attempts, last error and result (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "album"
+                    "admin"
                 ],
-                "summary": "GetTrackAndAlbum",
+                "summary": "FindJob",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Search by keyword",
-                        "name": "keyword",
-                        "in": "query",
+                        "description": "Job ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
                         "required": true
                     }
                 ],
-                "responses": {}
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Job"
+                        }
+                    }
+                }
             }
         },
-        "/album/update/{id}": {
-            "put": {
+        "/admin/jobs/{id}/retry": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Update a album",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Queue a dead job again with fresh attempts (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "album"
+                    "admin"
                 ],
-                "summary": "UpdateAlbum",
+                "summary": "RetryJob",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Update by Album ID",
+                        "description": "Job ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
-                    {
-                        "description": "Album data to update",
-                        "name": "album",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.AlbumDto"
-                        }
-                    },
                     {
                         "type": "string",
                         "description": "Authorization",
@@ -316,37 +333,32 @@ const docTemplate = `{
                         "required": true
                     }
                 ],
-                "responses": {}
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.Job"
+                        }
+                    }
+                }
             }
         },
-        "/track/create": {
-            "post": {
+        "/admin/track/duplicates": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "create a Track",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "The clusters of likely duplicate tracks found by the last search that succeeded, with the time it finished in Last-Modified; 404 until a search queued with POST has finished (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "track"
+                    "admin"
                 ],
-                "summary": "CreateTrack",
+                "summary": "GetDuplicateTracks",
                 "parameters": [
-                    {
-                        "description": "Track data to create",
-                        "name": "track",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.TrackDto"
-                        }
-                    },
                     {
                         "type": "string",
                         "description": "Authorization",
@@ -355,17 +367,25 @@ const docTemplate = `{
                         "required": true
                     }
                 ],
-                "responses": {}
-            }
-        },
-        "/track/delete/{id}": {
-            "delete": {
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.DuplicateCluster"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "delete a track",
+                "description": "Queue a search for clusters of likely duplicate tracks, which reads the whole catalog, and answer the job; its result, once done, are the clusters. A search already queued or running is answered instead of queuing another (admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -373,16 +393,55 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "track"
+                    "admin"
                 ],
-                "summary": "DeleteTrack",
+                "summary": "FindDuplicateTracks",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Delete by Track ID",
-                        "name": "id",
-                        "in": "path",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
                         "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.Job"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/track/merge": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Merge duplicate tracks into a survivor and move them to the trash, in one transaction, so MongoDB must run as a replica set (admin only)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "MergeTracks",
+                "parameters": [
+                    {
+                        "description": "Survivor and duplicates",
+                        "name": "merge",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.MergeTracksDto"
+                        }
                     },
                     {
                         "type": "string",
@@ -395,51 +454,64 @@ const docTemplate = `{
                 "responses": {}
             }
         },
-        "/track/get/{id}": {
-            "get": {
-                "description": "Get a track",
-                "consumes": [
-                    "application/json"
+        "/admin/track/restore/{id}": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
+                "description": "Take a track out of the trash (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "track"
+                    "admin"
                 ],
-                "summary": "GetTrack",
+                "summary": "RestoreTrack",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Find by Track ID",
+                        "description": "Track ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
                     }
                 ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/models.Track"
-                        }
-                    }
-                }
+                "responses": {}
             }
         },
-        "/track/getAll": {
+        "/admin/track/trash": {
             "get": {
-                "description": "get tracks",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
+                "description": "List the tracks in the trash, most recently deleted first (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "track"
+                    "admin"
+                ],
+                "summary": "GetDeletedTracks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
                 ],
-                "summary": "List tracks",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -453,41 +525,29 @@ const docTemplate = `{
                 }
             }
         },
-        "/track/update/{id}": {
-            "put": {
+        "/admin/user/restore/{id}": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Update a track",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Take a user out of the trash; fails with 409 when the username was taken since (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "track"
+                    "admin"
                 ],
-                "summary": "UpdateTrack",
+                "summary": "RestoreUser",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Update by Track ID",
+                        "description": "User ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
-                    {
-                        "description": "Track data to update",
-                        "name": "track",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.TrackDto"
-                        }
-                    },
                     {
                         "type": "string",
                         "description": "Authorization",
@@ -499,14 +559,86 @@ const docTemplate = `{
                 "responses": {}
             }
         },
-        "/user/change_password": {
-            "patch": {
+        "/admin/user/trash": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "change pass a user",
+                "description": "List the users in the trash, most recently deleted first (admin only)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "GetDeletedUsers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.User"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhook": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List the registered webhooks (admin only)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "GetWebhooks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Webhook"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Register an endpoint for catalog events. The answer holds the secret deliveries are signed with; it is not shown again. (admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -514,17 +646,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "admin"
                 ],
-                "summary": "ChangePass",
+                "summary": "CreateWebhook",
                 "parameters": [
                     {
-                        "description": "User data to change password",
-                        "name": "changePass",
+                        "description": "Endpoint and events",
+                        "name": "webhook",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/controllers.ResetPassword"
+                            "$ref": "#/definitions/dto.WebhookDto"
                         }
                     },
                     {
@@ -535,58 +667,76 @@ const docTemplate = `{
                         "required": true
                     }
                 ],
-                "responses": {}
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    }
+                }
             }
         },
-        "/user/create": {
+        "/admin/webhook/delivery/{id}/replay": {
             "post": {
-                "description": "create a user",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
+                "description": "Send the payload of a past delivery again, as a new delivery (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "admin"
                 ],
-                "summary": "CreateUser",
+                "summary": "ReplayDelivery",
                 "parameters": [
                     {
-                        "description": "User data to create",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
+                        "type": "string",
+                        "description": "Delivery ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "$ref": "#/definitions/dto.UserDto"
+                            "$ref": "#/definitions/models.WebhookDelivery"
                         }
                     }
-                ],
-                "responses": {}
+                }
             }
         },
-        "/user/delete/{id}": {
+        "/admin/webhook/{id}": {
             "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "delete a user",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Remove a webhook (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "admin"
                 ],
-                "summary": "DeleteUser",
+                "summary": "DeleteWebhook",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Delete by User ID",
+                        "description": "Webhook ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -602,28 +752,25 @@ const docTemplate = `{
                 "responses": {}
             }
         },
-        "/user/get/{id}": {
+        "/admin/webhook/{id}/deliveries": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Get a user",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "List the latest deliveries of a webhook with their status (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "admin"
                 ],
-                "summary": "GetUser",
+                "summary": "GetDeliveries",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Find by User ID",
+                        "description": "Webhook ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -640,20 +787,23 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.WebhookDelivery"
+                            }
                         }
                     }
                 }
             }
         },
-        "/user/update": {
-            "patch": {
+        "/album/add_track/{id}": {
+            "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Update a user",
+                "description": "Add a Track to Album",
                 "consumes": [
                     "application/json"
                 ],
@@ -661,113 +811,2495 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "album"
                 ],
-                "summary": "UpdateUser",
+                "summary": "AddTrackToAlbum",
                 "parameters": [
-                    {
-                        "description": "User data to update",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.User"
-                        }
-                    },
                     {
                         "type": "string",
                         "description": "Authorization",
                         "name": "Authorization",
                         "in": "header",
                         "required": true
-                    }
-                ],
-                "responses": {}
-            }
-        }
-    },
+                    },
+                    {
+                        "type": "string",
+                        "description": "Find by album ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Track data to create",
+                        "name": "track",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.TrackDto"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/album/create": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "create a Album",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "album"
+                ],
+                "summary": "CreateAlbum",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Album data to create",
+                        "name": "album",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.AlbumDto"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/album/delete/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "move a album to the trash",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "album"
+                ],
+                "summary": "DeleteAlbum",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Delete by Album ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version this change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/album/get/{id}": {
+            "get": {
+                "description": "Get a album",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "album"
+                ],
+                "summary": "GetAlbum",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Find by album ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a cached copy",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Album"
+                        }
+                    }
+                }
+            }
+        },
+        "/album/getAll": {
+            "get": {
+                "description": "get albums",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "album"
+                ],
+                "summary": "List albums",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sort by title, rating or rating_count",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Album"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/album/remove_track/{id}/{trackId}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Remove a Track from Album",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "album"
+                ],
+                "summary": "RemoveTrackFromAlbum",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Find by Album ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Remove by Track ID",
+                        "name": "trackId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/album/search": {
+            "get": {
+                "description": "Get list tracks and albums by keyword",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "album"
+                ],
+                "summary": "GetTrackAndAlbum",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search by keyword",
+                        "name": "keyword",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/album/update/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Update a album",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "album"
+                ],
+                "summary": "UpdateAlbum",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Update by Album ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Album data to update",
+                        "name": "album",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.AlbumDto"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version this change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {}
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Change the title or cover of an album with a JSON merge patch. Tracks are left alone.",
+                "consumes": [
+                    "application/merge-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "album"
+                ],
+                "summary": "PatchAlbum",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Update by Album ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.AlbumDto"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version this change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Album"
+                        }
+                    }
+                }
+            }
+        },
+        "/chart/{kind}/{window}": {
+            "get": {
+                "description": "Get a precomputed chart with rank movement against the previous period",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "chart"
+                ],
+                "summary": "GetChart",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "tracks, albums or artists",
+                        "name": "kind",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "day, week, month or all-time",
+                        "name": "window",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Chart"
+                        }
+                    }
+                }
+            }
+        },
+        "/export/album/{id}": {
+            "get": {
+                "description": "Download an album as an M3U8, PLS or XSPF playlist of stream URLs",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "playlist file"
+                ],
+                "summary": "ExportAlbum",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Album ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "m3u8 (default), pls or xspf",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/export/playlist/{id}": {
+            "get": {
+                "description": "Download the current tracks of a smart playlist as an M3U8, PLS or XSPF playlist",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "playlist file"
+                ],
+                "summary": "ExportPlaylist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Playlist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "m3u8 (default), pls or xspf",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/feed/events": {
+            "get": {
+                "description": "Server-Sent Events stream of track and album changes. The id of every event is a token; reconnect with it in Last-Event-ID, or in the token query parameter, to resume after that event.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "feed"
+                ],
+                "summary": "Events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Resume after the event with this id",
+                        "name": "token",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Resume after the event with this id",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/feed/ws": {
+            "get": {
+                "description": "WebSocket stream of track and album changes, one JSON message per change. Reconnect with the token of the last message to resume after it.",
+                "tags": [
+                    "feed"
+                ],
+                "summary": "Socket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Resume after the change with this token",
+                        "name": "token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/graphql": {
+            "get": {
+                "description": "Run a GraphQL query over tracks, albums, reviews, the public fields of users and search. Mutations must be POSTed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "graphql"
+                ],
+                "summary": "Query",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "GraphQL query",
+                        "name": "query",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Operation to run when the query holds several",
+                        "name": "operationName",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Variables as a JSON object",
+                        "name": "variables",
+                        "in": "query"
+                    }
+                ],
+                "responses": {}
+            },
+            "post": {
+                "description": "Run a GraphQL query or mutation. Queries are public; mutations (addTrackToAlbum, removeTrackFromAlbum) need a token. Operations nested more than 8 levels deep or too costly are refused with 400.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "graphql"
+                ],
+                "summary": "Execute",
+                "parameters": [
+                    {
+                        "description": "Query, operation name and variables",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.GraphQLDto"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header"
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/import/album": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Create an album from an M3U8, PLS or XSPF file. Entries are matched by path, then by title, artist and duration; unmatched entries are reported.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist file"
+                ],
+                "summary": "ImportAlbum",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "m3u8, pls or xspf",
+                        "name": "format",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Album title, defaults to the title in the file",
+                        "name": "title",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Playlist file",
+                        "name": "file",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/playback": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the play queue and position of the current user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playback"
+                ],
+                "summary": "GetPlaybackState",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PlaybackState"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Save the play queue and position of the current user. The version must be the one last read; on a conflict the current state is returned with 409.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playback"
+                ],
+                "summary": "SavePlaybackState",
+                "parameters": [
+                    {
+                        "description": "Playback state",
+                        "name": "state",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.PlaybackStateDto"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PlaybackState"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.PlaybackState"
+                        }
+                    }
+                }
+            }
+        },
+        "/playlist/create": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Save a smart playlist defined by rules",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "CreatePlaylist",
+                "parameters": [
+                    {
+                        "description": "Playlist name and rules",
+                        "name": "playlist",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.PlaylistDto"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Playlist"
+                        }
+                    }
+                }
+            }
+        },
+        "/playlist/delete/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "delete your own smart playlist",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "DeletePlaylist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Delete by Playlist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/playlist/get/{id}": {
+            "get": {
+                "description": "Get a smart playlist and the tracks its rules currently select",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "FindPlaylist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Find by Playlist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/playlist/preview": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Evaluate smart playlist rules without saving them",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "PreviewPlaylist",
+                "parameters": [
+                    {
+                        "description": "Rules to evaluate",
+                        "name": "rules",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SmartRules"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Track"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/playlist/update/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Update your own smart playlist",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "UpdatePlaylist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Update by Playlist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Playlist name and rules",
+                        "name": "playlist",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.PlaylistDto"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Playlist"
+                        }
+                    }
+                }
+            }
+        },
+        "/playlist/user/{userId}": {
+            "get": {
+                "description": "get the smart playlists of a user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "playlist"
+                ],
+                "summary": "List user playlists",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Playlist"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/radio/album/{id}": {
+            "get": {
+                "description": "Endless MP3 stream of an album, played on a loop like an Icecast station; all listeners hear the same moment. Send Icy-MetaData: 1 to get the title of the track playing every icy-metaint bytes. Only the MP3 tracks are played.",
+                "produces": [
+                    "audio/mpeg"
+                ],
+                "tags": [
+                    "radio"
+                ],
+                "summary": "AlbumStation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Album ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "1 to interleave ICY metadata",
+                        "name": "Icy-MetaData",
+                        "in": "header"
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/radio/playlist/{id}": {
+            "get": {
+                "description": "Endless MP3 stream of the tracks a smart playlist selects, played on a loop like an Icecast station; all listeners hear the same moment. Send Icy-MetaData: 1 to get the title of the track playing every icy-metaint bytes. Only the MP3 tracks are played.",
+                "produces": [
+                    "audio/mpeg"
+                ],
+                "tags": [
+                    "radio"
+                ],
+                "summary": "PlaylistStation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Playlist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "1 to interleave ICY metadata",
+                        "name": "Icy-MetaData",
+                        "in": "header"
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/radio/stations": {
+            "get": {
+                "description": "List the radio stations on air, with their listeners and the track playing",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "radio"
+                ],
+                "summary": "GetStations",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/radio.StationInfo"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/review/album/{albumId}": {
+            "get": {
+                "description": "get the reviews of an album",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "review"
+                ],
+                "summary": "List album reviews",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Album ID",
+                        "name": "albumId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Review"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/review/create/{albumId}": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Rate and review an album, once per user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "review"
+                ],
+                "summary": "CreateReview",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Album ID",
+                        "name": "albumId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Rating (1-5) and optional text",
+                        "name": "review",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ReviewDto"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Review"
+                        }
+                    }
+                }
+            }
+        },
+        "/review/delete/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "delete your own review",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "review"
+                ],
+                "summary": "DeleteReview",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Delete by Review ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/review/update/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Update your own review",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "review"
+                ],
+                "summary": "UpdateReview",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Update by Review ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Rating (1-5) and optional text",
+                        "name": "review",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ReviewDto"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Review"
+                        }
+                    }
+                }
+            }
+        },
+        "/subsonic/password": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Create the app password the current user signs in to the Subsonic API (/rest) with, replacing the previous one. It is shown only once.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subsonic"
+                ],
+                "summary": "CreatePassword",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Revoke the Subsonic app password of the current user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subsonic"
+                ],
+                "summary": "DeletePassword",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/track/create": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "create a Track",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "track"
+                ],
+                "summary": "CreateTrack",
+                "parameters": [
+                    {
+                        "description": "Track data to create",
+                        "name": "track",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.TrackDto"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/track/delete/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "move a track to the trash",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "track"
+                ],
+                "summary": "DeleteTrack",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Delete by Track ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version this change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/track/get/{id}": {
+            "get": {
+                "description": "Get a track",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "track"
+                ],
+                "summary": "GetTrack",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Find by Track ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a cached copy",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Track"
+                        }
+                    }
+                }
+            }
+        },
+        "/track/getAll": {
+            "get": {
+                "description": "get tracks",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "track"
+                ],
+                "summary": "List tracks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Track"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/track/play/{id}": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Record that the current user played a track",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "track"
+                ],
+                "summary": "RecordPlay",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Track ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Play"
+                        }
+                    }
+                }
+            }
+        },
+        "/track/stream/{id}": {
+            "get": {
+                "description": "Stream the audio file of a track, with range support. Only files in the library roots are served",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "track"
+                ],
+                "summary": "StreamTrack",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Track ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/track/update/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Update a track",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "track"
+                ],
+                "summary": "UpdateTrack",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Update by Track ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Track data to update",
+                        "name": "track",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.TrackDto"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version this change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {}
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Change some fields of a track with a JSON merge patch. The patched track must be valid.",
+                "consumes": [
+                    "application/merge-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "track"
+                ],
+                "summary": "PatchTrack",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Update by Track ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.TrackDto"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version this change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Track"
+                        }
+                    }
+                }
+            }
+        },
+        "/track/{id}/similar": {
+            "get": {
+                "description": "Get tracks similar to a track, with the reasons they were picked",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "track"
+                ],
+                "summary": "GetSimilarTracks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Track ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of tracks (default 10, at most 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SimilarTrack"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/change_password": {
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "change pass a user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "ChangePass",
+                "parameters": [
+                    {
+                        "description": "User data to change password",
+                        "name": "changePass",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/controllers.ResetPassword"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/user/create": {
+            "post": {
+                "description": "create a user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "CreateUser",
+                "parameters": [
+                    {
+                        "description": "User data to create",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UserDto"
+                        }
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/user/delete/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "move a user to the trash",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "DeleteUser",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Delete by User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version this change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/user/get/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get a user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "GetUser",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Find by User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a cached copy",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/update": {
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Update a user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "UpdateUser",
+                "parameters": [
+                    {
+                        "description": "User data to update",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdateUserDto"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version this change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {}
+            }
+        },
+        "/user/update/{id}": {
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Change the username or password of your own user, or of any user as an admin, with a JSON merge patch",
+                "consumes": [
+                    "application/merge-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "PatchUser",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Update by User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UserDto"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Authorization",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version this change is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {}
+            }
+        }
+    },
     "definitions": {
         "controllers.ResetPassword": {
             "type": "object",
-            "required": [
-                "new_password",
-                "old_password",
-                "username"
-            ],
+            "required": [
+                "new_password",
+                "old_password",
+                "username"
+            ],
+            "properties": {
+                "new_password": {
+                    "type": "string",
+                    "maxLength": 72,
+                    "minLength": 6
+                },
+                "old_password": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.AlbumDto": {
+            "type": "object",
+            "required": [
+                "album_cover",
+                "album_title"
+            ],
+            "properties": {
+                "album_cover": {
+                    "type": "string",
+                    "maxLength": 1024
+                },
+                "album_title": {
+                    "type": "string",
+                    "maxLength": 200
+                }
+            }
+        },
+        "dto.GraphQLDto": {
+            "type": "object",
+            "required": [
+                "query"
+            ],
+            "properties": {
+                "operationName": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "query": {
+                    "type": "string",
+                    "maxLength": 20000
+                },
+                "variables": {
+                    "type": "object",
+                    "additionalProperties": true
+                }
+            }
+        },
+        "dto.JobDto": {
+            "type": "object",
+            "required": [
+                "type"
+            ],
+            "properties": {
+                "key": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "max_attempts": {
+                    "type": "integer",
+                    "maximum": 20,
+                    "minimum": 1
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "run_at": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.MergeTracksDto": {
+            "type": "object",
+            "required": [
+                "duplicate_ids",
+                "survivor_id"
+            ],
+            "properties": {
+                "duplicate_ids": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "survivor_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.PlaybackStateDto": {
+            "type": "object",
+            "properties": {
+                "current_track_id": {
+                    "type": "string"
+                },
+                "device": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "position_ms": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "queue": {
+                    "type": "array",
+                    "maxItems": 1000,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "repeat": {
+                    "type": "string",
+                    "enum": [
+                        "off",
+                        "one",
+                        "all"
+                    ]
+                },
+                "shuffle": {
+                    "type": "boolean"
+                },
+                "version": {
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "dto.PlaylistDto": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "rules": {
+                    "$ref": "#/definitions/models.SmartRules"
+                }
+            }
+        },
+        "dto.ReviewDto": {
+            "type": "object",
+            "required": [
+                "rating"
+            ],
+            "properties": {
+                "rating": {
+                    "type": "integer",
+                    "maximum": 5,
+                    "minimum": 1
+                },
+                "text": {
+                    "type": "string",
+                    "maxLength": 5000
+                }
+            }
+        },
+        "dto.TrackDto": {
+            "type": "object",
+            "required": [
+                "artist",
+                "duration",
+                "file_name",
+                "genre",
+                "music_title",
+                "release_year"
+            ],
+            "properties": {
+                "artist": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "duration": {
+                    "type": "string"
+                },
+                "file_name": {
+                    "type": "string",
+                    "maxLength": 1024
+                },
+                "genre": {
+                    "type": "string"
+                },
+                "music_title": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "release_year": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.UpdateUserDto": {
+            "type": "object",
+            "required": [
+                "id",
+                "password",
+                "username"
+            ],
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string",
+                    "maxLength": 72,
+                    "minLength": 6
+                },
+                "username": {
+                    "type": "string",
+                    "maxLength": 50,
+                    "minLength": 3
+                }
+            }
+        },
+        "dto.UserDto": {
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "maxLength": 72,
+                    "minLength": 6
+                },
+                "username": {
+                    "type": "string",
+                    "maxLength": 50,
+                    "minLength": 3
+                }
+            }
+        },
+        "dto.WebhookDto": {
+            "type": "object",
+            "required": [
+                "events",
+                "url"
+            ],
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "url": {
+                    "type": "string",
+                    "maxLength": 1024
+                }
+            }
+        },
+        "models.Album": {
+            "type": "object",
+            "properties": {
+                "album_cover": {
+                    "type": "string"
+                },
+                "album_title": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "rating_average": {
+                    "type": "number"
+                },
+                "rating_count": {
+                    "type": "integer"
+                },
+                "tracks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Track"
+                    }
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.AuditEntry": {
+            "type": "object",
             "properties": {
-                "new_password": {
+                "action": {
                     "type": "string"
                 },
-                "old_password": {
+                "actor_id": {
                     "type": "string"
                 },
-                "username": {
+                "after": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "at": {
+                    "type": "string"
+                },
+                "before": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "id": {
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "resource": {
+                    "type": "string"
+                },
+                "resource_id": {
                     "type": "string"
                 }
             }
         },
-        "dto.AlbumDto": {
+        "models.Chart": {
             "type": "object",
             "properties": {
-                "album_cover": {
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChartEntry"
+                    }
+                },
+                "generated_at": {
                     "type": "string"
                 },
-                "album_title": {
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "period_end": {
+                    "type": "string"
+                },
+                "period_start": {
+                    "type": "string"
+                },
+                "window": {
                     "type": "string"
                 }
             }
         },
-        "dto.TrackDto": {
+        "models.ChartEntry": {
             "type": "object",
             "properties": {
                 "artist": {
                     "type": "string"
                 },
-                "duration": {
+                "item_id": {
                     "type": "string"
                 },
-                "file_name": {
+                "movement": {
+                    "type": "integer"
+                },
+                "new": {
+                    "type": "boolean"
+                },
+                "plays": {
+                    "type": "integer"
+                },
+                "previous_rank": {
+                    "type": "integer"
+                },
+                "rank": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.DuplicateCluster": {
+            "type": "object",
+            "properties": {
+                "reason": {
                     "type": "string"
                 },
-                "genre": {
+                "tracks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Track"
+                    }
+                }
+            }
+        },
+        "models.Job": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
                     "type": "string"
                 },
-                "music_title": {
+                "finished_at": {
                     "type": "string"
                 },
-                "release_year": {
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "lease_expires_at": {
+                    "type": "string"
+                },
+                "lease_owner": {
+                    "type": "string"
+                },
+                "max_attempts": {
+                    "type": "integer"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "result": {
+                    "type": "object"
+                },
+                "run_at": {
+                    "type": "string"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "type": {
                     "type": "string"
                 }
             }
         },
-        "dto.UserDto": {
+        "models.Play": {
             "type": "object",
             "properties": {
-                "password": {
+                "id": {
                     "type": "string"
                 },
-                "username": {
+                "played_at": {
+                    "type": "string"
+                },
+                "track_id": {
+                    "type": "string"
+                },
+                "user_id": {
                     "type": "string"
                 }
             }
         },
-        "models.Album": {
+        "models.PlaybackState": {
             "type": "object",
             "properties": {
-                "album_cover": {
+                "current_track_id": {
                     "type": "string"
                 },
-                "album_title": {
+                "device": {
+                    "type": "string"
+                },
+                "position_ms": {
+                    "type": "integer"
+                },
+                "queue": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "repeat": {
+                    "type": "string"
+                },
+                "shuffle": {
+                    "type": "boolean"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Playlist": {
+            "type": "object",
+            "properties": {
+                "created_at": {
                     "type": "string"
                 },
                 "id": {
                     "type": "string"
                 },
-                "tracks": {
+                "name": {
+                    "type": "string"
+                },
+                "rules": {
+                    "$ref": "#/definitions/models.SmartRules"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Review": {
+            "type": "object",
+            "properties": {
+                "album_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "rating": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RuleCondition": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "operator": {
+                    "type": "string"
+                },
+                "value": {}
+            }
+        },
+        "models.SimilarTrack": {
+            "type": "object",
+            "properties": {
+                "reasons": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/models.Track"
+                        "type": "string"
+                    }
+                },
+                "score": {
+                    "type": "number"
+                },
+                "track": {
+                    "$ref": "#/definitions/models.Track"
+                }
+            }
+        },
+        "models.SmartRules": {
+            "type": "object",
+            "properties": {
+                "conditions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.RuleCondition"
                     }
+                },
+                "descending": {
+                    "type": "boolean"
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "match": {
+                    "description": "Match is \"all\" (the default) or \"any\" of the conditions.",
+                    "type": "string"
+                },
+                "sort_by": {
+                    "type": "string"
                 }
             }
         },
@@ -777,9 +3309,18 @@ const docTemplate = `{
                 "artist": {
                     "type": "string"
                 },
+                "deleted_at": {
+                    "type": "string"
+                },
                 "duration": {
                     "type": "string"
                 },
+                "duration_seconds": {
+                    "type": "integer"
+                },
+                "file_hash": {
+                    "type": "string"
+                },
                 "file_name": {
                     "type": "string"
                 },
@@ -794,20 +3335,119 @@ const docTemplate = `{
                 },
                 "release_year": {
                     "type": "string"
+                },
+                "unavailable": {
+                    "type": "boolean"
+                },
+                "version": {
+                    "type": "integer"
                 }
             }
         },
         "models.User": {
             "type": "object",
             "properties": {
+                "deleted_at": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "string"
                 },
                 "password": {
                     "type": "string"
                 },
+                "role": {
+                    "type": "string"
+                },
                 "username": {
                     "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Webhook": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "string"
+                },
+                "replay_of": {
+                    "type": "string"
+                },
+                "response_status": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "webhook_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "radio.StationInfo": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "type": "string"
+                },
+                "listeners": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "now_playing": {
+                    "$ref": "#/definitions/models.Track"
+                },
+                "started_at": {
+                    "type": "string"
                 }
             }
         }
@@ -1,6 +1,6 @@
 package dto
 
 type AlbumDto struct {
-	Title      string `json:"album_title" bson:"album_title"`
-	AlbumCover string `json:"album_cover" bson:"album_cover"`
+	Title      string `json:"album_title" bson:"album_title" binding:"required,max=200"`
+	AlbumCover string `json:"album_cover" bson:"album_cover" binding:"required,max=1024,uri"`
 }
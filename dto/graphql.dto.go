@@ -0,0 +1,7 @@
+package dto
+
+type GraphQLDto struct {
+	Query         string                 `json:"query" binding:"required,max=20000"`
+	OperationName string                 `json:"operationName" binding:"max=200"`
+	Variables     map[string]interface{} `json:"variables"`
+}
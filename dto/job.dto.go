@@ -0,0 +1,14 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type JobDto struct {
+	Type        string          `json:"type" binding:"required"`
+	Payload     json.RawMessage `json:"payload" swaggertype:"object"`
+	Key         string          `json:"key" binding:"max=200"`
+	MaxAttempts int             `json:"max_attempts" binding:"omitempty,min=1,max=20"`
+	RunAt       *time.Time      `json:"run_at"`
+}
@@ -0,0 +1,6 @@
+package dto
+
+type MergeTracksDto struct {
+	SurvivorId   string   `json:"survivor_id" binding:"required,mongodb"`
+	DuplicateIds []string `json:"duplicate_ids" binding:"required,min=1,dive,mongodb"`
+}
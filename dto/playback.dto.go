@@ -0,0 +1,11 @@
+package dto
+
+type PlaybackStateDto struct {
+	Queue          []string `json:"queue" bson:"queue" binding:"max=1000,dive,mongodb"`
+	CurrentTrackId string   `json:"current_track_id" bson:"current_track_id" binding:"omitempty,mongodb"`
+	PositionMs     int64    `json:"position_ms" bson:"position_ms" binding:"min=0"`
+	Shuffle        bool     `json:"shuffle" bson:"shuffle"`
+	Repeat         string   `json:"repeat" bson:"repeat" binding:"omitempty,oneof=off one all"`
+	Device         string   `json:"device" bson:"device" binding:"max=100"`
+	Version        int64    `json:"version" bson:"version" binding:"min=0"`
+}
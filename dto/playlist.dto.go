@@ -0,0 +1,8 @@
+package dto
+
+import "musiclib/models"
+
+type PlaylistDto struct {
+	Name  string            `json:"name" bson:"name" binding:"required,max=100"`
+	Rules models.SmartRules `json:"rules" bson:"rules"`
+}
@@ -0,0 +1,6 @@
+package dto
+
+type ReviewDto struct {
+	Rating int    `json:"rating" bson:"rating" binding:"required,min=1,max=5"`
+	Text   string `json:"text" bson:"text" binding:"max=5000"`
+}
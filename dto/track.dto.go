@@ -1,10 +1,10 @@
 package dto
 
 type TrackDto struct {
-	Title       string `json:"music_title" bson:"music_title"`
-	Artist      string `json:"artist" bson:"artist"`
-	Genre       string `json:"genre" bson:"genre"`
-	ReleaseYear string `json:"release_year" bson:"release_year"`
-	Duration    string `json:"duration" bson:"duration"`
-	FileName    string `json:"file_name" bson:"file_name"`
+	Title       string `json:"music_title" bson:"music_title" binding:"required,max=200"`
+	Artist      string `json:"artist" bson:"artist" binding:"required,max=200"`
+	Genre       string `json:"genre" bson:"genre" binding:"required,genre"`
+	ReleaseYear string `json:"release_year" bson:"release_year" binding:"required,year"`
+	Duration    string `json:"duration" bson:"duration" binding:"required,duration"`
+	FileName    string `json:"file_name" bson:"file_name" binding:"required,max=1024,uri"`
 }
@@ -1,6 +1,12 @@
 package dto
 
 type UserDto struct {
-	Username string `json:"username" bson:"username"`
-	Password string `json:"password" bson:"password"`
+	Username string `json:"username" bson:"username" binding:"required,min=3,max=50,alphanum"`
+	Password string `json:"password" bson:"password" binding:"required,min=6,max=72"`
+}
+
+type UpdateUserDto struct {
+	UserId   string `json:"id" binding:"required,mongodb"`
+	Username string `json:"username" bson:"username" binding:"required,min=3,max=50,alphanum"`
+	Password string `json:"password" bson:"password" binding:"required,min=6,max=72"`
 }
@@ -0,0 +1,85 @@
+package dto
+
+import (
+	"errors"
+	"musiclib/helper"
+	"musiclib/models"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// MinReleaseYear is the earliest release year accepted for a track.
+const MinReleaseYear = 1900
+
+// Genres are the track genres accepted by the genre tag. They are compared
+// case-insensitively.
+var Genres = []string{
+	"Alternative", "Ambient", "Blues", "Classical", "Country", "Dance",
+	"Electronic", "Folk", "Funk", "Hip Hop", "Indie", "Jazz", "Latin",
+	"Metal", "Pop", "Punk", "R&B", "Rap", "Reggae", "Rock", "Soul",
+	"Soundtrack", "World", "Other",
+}
+
+// RegisterValidators adds the custom tags used by the DTOs to gin's
+// validator and makes it report fields by their json name.
+func RegisterValidators() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("unexpected validator engine")
+	}
+	v.RegisterTagNameFunc(jsonName)
+	validations := map[string]validator.Func{
+		"genre":    validGenre,
+		"year":     validYear,
+		"duration": validDuration,
+		"event":    validEvent,
+	}
+	for tag, fn := range validations {
+		if err := v.RegisterValidation(tag, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func validGenre(fl validator.FieldLevel) bool {
+	for _, genre := range Genres {
+		if strings.EqualFold(genre, fl.Field().String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// validYear accepts a year from MinReleaseYear up to next year, for
+// announced releases.
+func validYear(fl validator.FieldLevel) bool {
+	year, err := strconv.Atoi(fl.Field().String())
+	return err == nil && year >= MinReleaseYear && year <= time.Now().Year()+1
+}
+
+func validDuration(fl validator.FieldLevel) bool {
+	seconds, ok := helper.ParseDuration(fl.Field().String())
+	return ok && seconds > 0
+}
+
+func validEvent(fl validator.FieldLevel) bool {
+	return slices.Contains(models.WebhookEvents, fl.Field().String())
+}
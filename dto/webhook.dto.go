@@ -0,0 +1,6 @@
+package dto
+
+type WebhookDto struct {
+	URL    string   `json:"url" binding:"required,max=1024,http_url"`
+	Events []string `json:"events" binding:"required,min=1,dive,event"`
+}
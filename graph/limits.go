@@ -0,0 +1,112 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Limits on the operations the schema runs, checked before any resolver.
+const (
+	// MaxDepth is how deeply fields may be nested.
+	MaxDepth = 8
+	// MaxComplexity bounds the cost of an operation: every field costs 1,
+	// and the fields under a list count listFactor times.
+	MaxComplexity = 20000
+	listFactor    = 10
+)
+
+// measure walks a selection set of parent, with fragments expanded, and
+// returns its depth and cost. Introspection fields are free.
+func measure(schema *graphql.Schema, fragments map[string]*ast.FragmentDefinition, parent graphql.Type, set *ast.SelectionSet, seen map[string]bool) (int, int) {
+	if set == nil {
+		return 0, 0
+	}
+	depth, cost := 0, 0
+	add := func(d, c int) {
+		if d > depth {
+			depth = d
+		}
+		cost += c
+	}
+	for _, selection := range set.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			name := selection.Name.Value
+			object, ok := parent.(*graphql.Object)
+			if !ok || len(name) >= 2 && name[:2] == "__" {
+				continue
+			}
+			def, ok := object.Fields()[name]
+			if !ok {
+				continue
+			}
+			fieldType, list := unwrap(def.Type)
+			d, c := measure(schema, fragments, fieldType, selection.SelectionSet, seen)
+			if list {
+				c *= listFactor
+			}
+			add(d+1, c+1)
+		case *ast.InlineFragment:
+			add(measure(schema, fragments, conditionType(schema, selection.TypeCondition, parent), selection.SelectionSet, seen))
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := fragments[name]
+			if !ok || seen[name] {
+				continue
+			}
+			seen[name] = true
+			add(measure(schema, fragments, conditionType(schema, fragment.TypeCondition, parent), fragment.SelectionSet, seen))
+			delete(seen, name)
+		}
+	}
+	return depth, cost
+}
+
+// unwrap strips the non-null and list wrappers off t and reports whether
+// there was a list.
+func unwrap(t graphql.Type) (graphql.Type, bool) {
+	list := false
+	for {
+		switch wrapper := t.(type) {
+		case *graphql.NonNull:
+			t = wrapper.OfType
+		case *graphql.List:
+			t = wrapper.OfType
+			list = true
+		default:
+			return t, list
+		}
+	}
+}
+
+func conditionType(schema *graphql.Schema, condition *ast.Named, parent graphql.Type) graphql.Type {
+	if condition == nil {
+		return parent
+	}
+	return schema.Type(condition.Name.Value)
+}
+
+// checkLimits rejects an operation nested deeper than MaxDepth or costing
+// more than MaxComplexity.
+func checkLimits(schema *graphql.Schema, doc *ast.Document, operation *ast.OperationDefinition) error {
+	fragments := map[string]*ast.FragmentDefinition{}
+	for _, definition := range doc.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+	root := schema.QueryType()
+	if operation.Operation == ast.OperationTypeMutation {
+		root = schema.MutationType()
+	}
+	depth, cost := measure(schema, fragments, root, operation.SelectionSet, map[string]bool{})
+	if depth > MaxDepth {
+		return fmt.Errorf("query is nested %d levels deep, at most %d are allowed", depth, MaxDepth)
+	}
+	if cost > MaxComplexity {
+		return fmt.Errorf("query has a complexity of %d, at most %d is allowed", cost, MaxComplexity)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package graph
+
+import (
+	"context"
+	"musiclib/models"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// thunk is a value resolved later. The executor runs the resolvers of a
+// whole level of the query before it calls the thunks they returned, so
+// the keys of that level are all queued by the time the first thunk runs.
+type thunk = func() (interface{}, error)
+
+// loader batches the keys asked for while a level of the query resolves
+// and fetches them with a single call when the first of their values is
+// needed. Values are kept for the rest of the request.
+type loader struct {
+	fetch   func(keys []string) (map[string]interface{}, error)
+	mu      sync.Mutex
+	pending []string
+	values  map[string]interface{}
+	errs    map[string]error
+}
+
+func newLoader(fetch func(keys []string) (map[string]interface{}, error)) *loader {
+	return &loader{
+		fetch:  fetch,
+		values: map[string]interface{}{},
+		errs:   map[string]error{},
+	}
+}
+
+// load queues keys and returns a thunk of their values, in order. Keys
+// without a value are left out.
+func (l *loader) load(keys ...string) func() ([]interface{}, error) {
+	l.mu.Lock()
+	for _, key := range keys {
+		if _, ok := l.values[key]; !ok {
+			l.pending = append(l.pending, key)
+		}
+	}
+	l.mu.Unlock()
+	return func() ([]interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.flush()
+		values := make([]interface{}, 0, len(keys))
+		for _, key := range keys {
+			if err := l.errs[key]; err != nil {
+				return nil, err
+			}
+			if value := l.values[key]; value != nil {
+				values = append(values, value)
+			}
+		}
+		return values, nil
+	}
+}
+
+// one is load for a single key; the thunk gives nil if it has no value.
+func (l *loader) one(key string) thunk {
+	values := l.load(key)
+	return func() (interface{}, error) {
+		found, err := values()
+		if err != nil || len(found) == 0 {
+			return nil, err
+		}
+		return found[0], nil
+	}
+}
+
+// flush fetches the pending keys. It is called with mu held.
+func (l *loader) flush() {
+	if len(l.pending) == 0 {
+		return
+	}
+	keys := unique(l.pending)
+	l.pending = nil
+	values, err := l.fetch(keys)
+	for _, key := range keys {
+		if err != nil {
+			l.errs[key] = err
+			continue
+		}
+		if value, ok := values[key]; ok {
+			l.values[key] = value
+		} else {
+			// Remember the miss so the key is not fetched again.
+			l.values[key] = nil
+		}
+	}
+}
+
+func unique(keys []string) []string {
+	seen := make(map[string]bool, len(keys))
+	result := keys[:0:0]
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+// objectIds parses the ids among keys, dropping the others: they cannot
+// name a document.
+func objectIds(keys []string) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(keys))
+	for _, key := range keys {
+		if id, err := primitive.ObjectIDFromHex(key); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// loaders are the loaders of one request.
+type loaders struct {
+	// tracks maps a track id to its *models.Track.
+	tracks *loader
+	// trackAlbums maps a track id to the []*models.Album holding it.
+	trackAlbums *loader
+	// reviews maps an album id to its []*models.Review.
+	reviews *loader
+	// users maps a user id to its *models.User.
+	users *loader
+}
+
+func newLoaders(ctx context.Context, s *Schema) *loaders {
+	return &loaders{
+		tracks: newLoader(func(keys []string) (map[string]interface{}, error) {
+			tracks, err := s.trackService.FindTracks(ctx, objectIds(keys))
+			if err != nil {
+				return nil, err
+			}
+			values := make(map[string]interface{}, len(tracks))
+			for i := range tracks {
+				values[tracks[i].TrackId] = &tracks[i]
+			}
+			return values, nil
+		}),
+		trackAlbums: newLoader(func(keys []string) (map[string]interface{}, error) {
+			albums, err := s.albumService.FindAlbumsByTracks(ctx, keys)
+			if err != nil {
+				return nil, err
+			}
+			byTrack := make(map[string][]*models.Album, len(keys))
+			for i := range albums {
+				for _, track := range albums[i].Tracks {
+					byTrack[track.TrackId] = append(byTrack[track.TrackId], &albums[i])
+				}
+			}
+			values := make(map[string]interface{}, len(keys))
+			for _, key := range keys {
+				values[key] = append([]*models.Album{}, byTrack[key]...)
+			}
+			return values, nil
+		}),
+		reviews: newLoader(func(keys []string) (map[string]interface{}, error) {
+			reviews, err := s.reviewService.GetReviewsByAlbums(ctx, keys)
+			if err != nil {
+				return nil, err
+			}
+			byAlbum := make(map[string][]*models.Review, len(keys))
+			for i := range reviews {
+				byAlbum[reviews[i].AlbumId] = append(byAlbum[reviews[i].AlbumId], &reviews[i])
+			}
+			values := make(map[string]interface{}, len(keys))
+			for _, key := range keys {
+				values[key] = append([]*models.Review{}, byAlbum[key]...)
+			}
+			return values, nil
+		}),
+		users: newLoader(func(keys []string) (map[string]interface{}, error) {
+			users, err := s.userService.FindUsers(ctx, objectIds(keys))
+			if err != nil {
+				return nil, err
+			}
+			values := make(map[string]interface{}, len(users))
+			for i := range users {
+				values[users[i].UserId] = &users[i]
+			}
+			return values, nil
+		}),
+	}
+}
+
+type loadersKey struct{}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
@@ -0,0 +1,417 @@
+// Package graph serves the catalog as GraphQL: tracks, albums with their
+// reviews, the public fields of users, search, and adding and removing the
+// tracks of an album. Everything is resolved through the services, with
+// the lookups of related documents batched per level of the query.
+package graph
+
+import (
+	"context"
+	"errors"
+	"log"
+	"musiclib/models"
+	"musiclib/services"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Schema struct {
+	schema        graphql.Schema
+	trackService  services.TrackService
+	albumService  services.AlbumService
+	reviewService services.ReviewService
+	userService   services.UserService
+}
+
+// Operation is a parsed request that passed validation and the limits.
+type Operation struct {
+	doc      *ast.Document
+	name     string
+	Mutation bool
+}
+
+func NewSchema(trackService services.TrackService, albumService services.AlbumService, reviewService services.ReviewService, userService services.UserService) (*Schema, error) {
+	s := &Schema{
+		trackService:  trackService,
+		albumService:  albumService,
+		reviewService: reviewService,
+		userService:   userService,
+	}
+	schema, err := graphql.NewSchema(s.config())
+	if err != nil {
+		return nil, err
+	}
+	s.schema = schema
+	return s, nil
+}
+
+// Prepare parses query, validates it against the schema and checks it
+// against the limits. operationName picks the operation to run when the
+// query holds more than one.
+func (s *Schema) Prepare(query string, operationName string) (*Operation, []gqlerrors.FormattedError) {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(query), Name: "GraphQL request"})})
+	if err != nil {
+		return nil, gqlerrors.FormatErrors(err)
+	}
+	if result := graphql.ValidateDocument(&s.schema, doc, nil); !result.IsValid {
+		return nil, result.Errors
+	}
+	var operation *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		candidate, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" && operation != nil {
+			return nil, gqlerrors.FormatErrors(errors.New("operationName is required when the query holds several operations"))
+		}
+		if operationName == "" || candidate.Name != nil && candidate.Name.Value == operationName {
+			operation = candidate
+		}
+	}
+	if operation == nil {
+		return nil, gqlerrors.FormatErrors(errors.New("unknown operation " + operationName))
+	}
+	if err := checkLimits(&s.schema, doc, operation); err != nil {
+		return nil, gqlerrors.FormatErrors(err)
+	}
+	return &Operation{
+		doc:      doc,
+		name:     operationName,
+		Mutation: operation.Operation == ast.OperationTypeMutation,
+	}, nil
+}
+
+// Execute runs operation.
+func (s *Schema) Execute(ctx context.Context, operation *Operation, variables map[string]interface{}) *graphql.Result {
+	ctx = context.WithValue(ctx, loadersKey{}, newLoaders(ctx, s))
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           operation.doc,
+		OperationName: operation.name,
+		Args:          variables,
+		Context:       ctx,
+	})
+}
+
+// resolveError passes on the service errors a client can act on and
+// hides the others, which are logged.
+func resolveError(err error) error {
+	var serviceErr *services.Error
+	var validationErr *services.ValidationError
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, services.ErrUnavailable), errors.Is(err, services.ErrTimeout):
+		log.Println("graphql", errors.Unwrap(err))
+		return err
+	case errors.As(err, &serviceErr), errors.As(err, &validationErr):
+		return err
+	}
+	log.Println("graphql", err)
+	return errors.New("internal error")
+}
+
+// optional resolves a single lookup, giving null when nothing was found.
+func optional(value interface{}, err error) (interface{}, error) {
+	if errors.Is(err, services.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, resolveError(err)
+	}
+	return value, nil
+}
+
+// idArg parses the id argument name.
+func idArg(p graphql.ResolveParams, name string) (*primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(p.Args[name].(string))
+	if err != nil {
+		return nil, &services.ValidationError{Field: name, Message: "must be an id"}
+	}
+	return &id, nil
+}
+
+// batched turns the values of a loader into the thunk of a list field.
+func batched(values func() ([]interface{}, error)) thunk {
+	return func() (interface{}, error) {
+		found, err := values()
+		return found, resolveError(err)
+	}
+}
+
+// batchedOne is batched for a field holding a single value.
+func batchedOne(value thunk) thunk {
+	return func() (interface{}, error) {
+		found, err := value()
+		return found, resolveError(err)
+	}
+}
+
+func trackPointers(tracks []models.Track) []*models.Track {
+	result := make([]*models.Track, len(tracks))
+	for i := range tracks {
+		result[i] = &tracks[i]
+	}
+	return result
+}
+
+func albumPointers(albums []models.Album) []*models.Album {
+	result := make([]*models.Album, len(albums))
+	for i := range albums {
+		result[i] = &albums[i]
+	}
+	return result
+}
+
+func (s *Schema) config() graphql.SchemaConfig {
+	nonNull := graphql.NewNonNull
+	listOf := func(t graphql.Type) graphql.Output {
+		return nonNull(graphql.NewList(nonNull(t)))
+	}
+	idArgs := func(names ...string) graphql.FieldConfigArgument {
+		args := graphql.FieldConfigArgument{}
+		for _, name := range names {
+			args[name] = &graphql.ArgumentConfig{Type: nonNull(graphql.ID)}
+		}
+		return args
+	}
+
+	// Fields without a resolver are read from the model field of the
+	// same name.
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "User",
+		Description: "The public profile of a user",
+		Fields: graphql.Fields{
+			"id":       &graphql.Field{Type: nonNull(graphql.ID)},
+			"username": &graphql.Field{Type: nonNull(graphql.String)},
+		},
+	})
+
+	reviewType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Review",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: nonNull(graphql.ID)},
+			"rating":    &graphql.Field{Type: nonNull(graphql.Int)},
+			"text":      &graphql.Field{Type: nonNull(graphql.String)},
+			"createdAt": &graphql.Field{Type: nonNull(graphql.DateTime)},
+			"user": &graphql.Field{
+				Type:        userType,
+				Description: "The author, null once their account is deleted",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					review := p.Source.(*models.Review)
+					return batchedOne(loadersFrom(p.Context).users.one(review.UserId)), nil
+				},
+			},
+		},
+	})
+
+	trackType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Track",
+		Fields: graphql.Fields{
+			"id":              &graphql.Field{Type: nonNull(graphql.ID)},
+			"title":           &graphql.Field{Type: nonNull(graphql.String)},
+			"artist":          &graphql.Field{Type: nonNull(graphql.String)},
+			"genre":           &graphql.Field{Type: nonNull(graphql.String)},
+			"releaseYear":     &graphql.Field{Type: nonNull(graphql.String)},
+			"duration":        &graphql.Field{Type: nonNull(graphql.String)},
+			"durationSeconds": &graphql.Field{Type: graphql.Int},
+			"unavailable": &graphql.Field{
+				Type:        nonNull(graphql.Boolean),
+				Description: "Whether the file of the track is missing from the library",
+			},
+		},
+	})
+
+	albumType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Album",
+		Fields: graphql.Fields{
+			"id":    &graphql.Field{Type: nonNull(graphql.ID)},
+			"title": &graphql.Field{Type: nonNull(graphql.String)},
+			"cover": &graphql.Field{
+				Type: nonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*models.Album).AlbumCover, nil
+				},
+			},
+			"ratingAverage": &graphql.Field{Type: nonNull(graphql.Float)},
+			"ratingCount":   &graphql.Field{Type: nonNull(graphql.Int)},
+			"tracks": &graphql.Field{
+				Type:        listOf(trackType),
+				Description: "The tracks of the album, in order, as they are now",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					album := p.Source.(*models.Album)
+					ids := make([]string, len(album.Tracks))
+					for i, track := range album.Tracks {
+						ids[i] = track.TrackId
+					}
+					return batched(loadersFrom(p.Context).tracks.load(ids...)), nil
+				},
+			},
+			"reviews": &graphql.Field{
+				Type: listOf(reviewType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					album := p.Source.(*models.Album)
+					return batchedOne(loadersFrom(p.Context).reviews.one(album.AlbumId)), nil
+				},
+			},
+		},
+	})
+
+	trackType.AddFieldConfig("albums", &graphql.Field{
+		Type:        listOf(albumType),
+		Description: "The albums holding the track",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			track := p.Source.(*models.Track)
+			return batchedOne(loadersFrom(p.Context).trackAlbums.one(track.TrackId)), nil
+		},
+	})
+
+	searchResultType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "SearchResult",
+		Fields: graphql.Fields{
+			"albums": &graphql.Field{Type: listOf(albumType)},
+			"tracks": &graphql.Field{Type: listOf(trackType)},
+		},
+	})
+
+	albumSortType := graphql.NewEnum(graphql.EnumConfig{
+		Name: "AlbumSort",
+		Values: graphql.EnumValueConfigMap{
+			"TITLE":        &graphql.EnumValueConfig{Value: services.AlbumSortTitle},
+			"RATING":       &graphql.EnumValueConfig{Value: services.AlbumSortRating},
+			"RATING_COUNT": &graphql.EnumValueConfig{Value: services.AlbumSortRatingCount},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"track": &graphql.Field{
+				Type: trackType,
+				Args: idArgs("id"),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := idArg(p, "id")
+					if err != nil {
+						return nil, err
+					}
+					return optional(s.trackService.FindTrack(p.Context, id))
+				},
+			},
+			"tracks": &graphql.Field{
+				Type: listOf(trackType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					tracks, err := s.trackService.GetTracks(p.Context)
+					if err != nil {
+						return nil, resolveError(err)
+					}
+					return trackPointers(tracks), nil
+				},
+			},
+			"album": &graphql.Field{
+				Type: albumType,
+				Args: idArgs("id"),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := idArg(p, "id")
+					if err != nil {
+						return nil, err
+					}
+					return optional(s.albumService.FindAlbum(p.Context, id))
+				},
+			},
+			"albums": &graphql.Field{
+				Type: listOf(albumType),
+				Args: graphql.FieldConfigArgument{
+					"sort": &graphql.ArgumentConfig{Type: albumSortType},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					sort, _ := p.Args["sort"].(string)
+					albums, err := s.albumService.GetAlbums(p.Context, &sort)
+					if err != nil {
+						return nil, resolveError(err)
+					}
+					return albumPointers(albums), nil
+				},
+			},
+			"user": &graphql.Field{
+				Type: userType,
+				Args: idArgs("id"),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := idArg(p, "id")
+					if err != nil {
+						return nil, err
+					}
+					hex := id.Hex()
+					return optional(s.userService.GetUser(p.Context, &hex))
+				},
+			},
+			"search": &graphql.Field{
+				Type:        nonNull(searchResultType),
+				Description: "Albums and tracks matching keyword",
+				Args: graphql.FieldConfigArgument{
+					"keyword": &graphql.ArgumentConfig{Type: nonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					keyword := p.Args["keyword"].(string)
+					albums, tracks, err := s.albumService.FindTracksAndAlbums(p.Context, &keyword)
+					if err != nil {
+						return nil, resolveError(err)
+					}
+					return map[string]interface{}{
+						"albums": albumPointers(albums),
+						"tracks": trackPointers(tracks),
+					}, nil
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"addTrackToAlbum": &graphql.Field{
+				Type:        nonNull(albumType),
+				Description: "Append an existing track to an album",
+				Args:        idArgs("albumId", "trackId"),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.changeAlbum(p, s.albumService.AddExistedTrackToAlbum)
+				},
+			},
+			"removeTrackFromAlbum": &graphql.Field{
+				Type:        nonNull(albumType),
+				Description: "Take a track off an album",
+				Args:        idArgs("albumId", "trackId"),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return s.changeAlbum(p, s.albumService.RemoveTrackFromAlbum)
+				},
+			},
+		},
+	})
+
+	return graphql.SchemaConfig{Query: query, Mutation: mutation}
+}
+
+// changeAlbum applies change to the album and track of the arguments and
+// resolves to the album after the change.
+func (s *Schema) changeAlbum(p graphql.ResolveParams, change func(context.Context, *primitive.ObjectID, *primitive.ObjectID) error) (interface{}, error) {
+	albumId, err := idArg(p, "albumId")
+	if err != nil {
+		return nil, err
+	}
+	trackId, err := idArg(p, "trackId")
+	if err != nil {
+		return nil, err
+	}
+	if err := change(p.Context, albumId, trackId); err != nil {
+		return nil, resolveError(err)
+	}
+	after, err := s.albumService.FindAlbum(p.Context, albumId)
+	if err != nil {
+		return nil, resolveError(err)
+	}
+	return after, nil
+}
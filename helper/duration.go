@@ -0,0 +1,52 @@
+package helper
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var durationPart = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*([a-z]+)`)
+
+var durationUnits = map[string]float64{
+	"h": 3600, "hr": 3600, "hrs": 3600, "hour": 3600, "hours": 3600,
+	"m": 60, "min": 60, "mins": 60, "minute": 60, "minutes": 60,
+	"s": 1, "sec": 1, "secs": 1, "second": 1, "seconds": 1,
+}
+
+// ParseDuration converts the free-form durations stored on tracks
+// ("4:35", "275", "4 minutes", "3m20s") into seconds.
+func ParseDuration(duration string) (int, bool) {
+	duration = strings.ToLower(strings.TrimSpace(duration))
+	if duration == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(duration); err == nil {
+		return seconds, seconds >= 0
+	}
+	if strings.Contains(duration, ":") {
+		seconds := 0
+		for _, part := range strings.Split(duration, ":") {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || n < 0 {
+				return 0, false
+			}
+			seconds = seconds*60 + n
+		}
+		return seconds, true
+	}
+	matches := durationPart.FindAllStringSubmatch(duration, -1)
+	if len(matches) == 0 {
+		return 0, false
+	}
+	total := 0.0
+	for _, m := range matches {
+		unit, ok := durationUnits[m[2]]
+		if !ok {
+			return 0, false
+		}
+		n, _ := strconv.ParseFloat(m[1], 64)
+		total += n * unit
+	}
+	return int(total), true
+}
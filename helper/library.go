@@ -0,0 +1,109 @@
+package helper
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// libraryRoots are the directories holding the media files the server
+// serves, with their symlinks resolved.
+var libraryRoots []string
+
+// SetLibraryRoots sets the directories holding the media files. Roots that
+// do not exist are left out.
+func SetLibraryRoots(roots []string) {
+	libraryRoots = nil
+	for _, root := range roots {
+		if root == "" {
+			continue
+		}
+		resolved, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			continue
+		}
+		if resolved, err = filepath.Abs(resolved); err == nil {
+			libraryRoots = append(libraryRoots, resolved)
+		}
+	}
+}
+
+// LibraryRoots lists the directories holding the media files.
+func LibraryRoots() []string {
+	return libraryRoots
+}
+
+// LibraryFile resolves the existing file path, symlinks included, and
+// reports whether it lies in a library root. Only files it accepts may be
+// served.
+func LibraryFile(path string) (string, bool) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", false
+	}
+	if resolved, err = filepath.Abs(resolved); err != nil {
+		return "", false
+	}
+	return resolved, inLibrary(resolved)
+}
+
+// InLibrary reports whether path would lie in a library root. It need not
+// exist yet; the symlinks of the part that does are resolved.
+func InLibrary(path string) bool {
+	resolved, ok := resolve(path)
+	return ok && inLibrary(resolved)
+}
+
+// InDir reports whether path would lie in dir, with the symlinks of both
+// resolved as far as they exist.
+func InDir(path string, dir string) bool {
+	resolved, ok := resolve(path)
+	if !ok {
+		return false
+	}
+	root, ok := resolve(dir)
+	return ok && within(root, resolved)
+}
+
+// resolve makes path absolute and resolves the symlinks of its deepest
+// existing directory, keeping the rest as is.
+func resolve(path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	existing, rest := path, ""
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return "", false
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", false
+	}
+	return filepath.Join(resolved, rest), true
+}
+
+func inLibrary(path string) bool {
+	for _, root := range libraryRoots {
+		if within(root, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func within(root string, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
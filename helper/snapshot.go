@@ -0,0 +1,18 @@
+package helper
+
+import "encoding/json"
+
+// Snapshot is the JSON form of a resource as stored in the audit log, with
+// password hashes left out. It is nil for a nil resource.
+func Snapshot(resource interface{}) map[string]interface{} {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil
+	}
+	delete(fields, "password")
+	return fields
+}
@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"musiclib/helper"
+	"musiclib/scanner"
+	"musiclib/services"
+	"os"
+)
+
+// Job types.
+const (
+	TypeScan       = "scan"
+	TypeCharts     = "charts"
+	TypeDuplicates = "duplicates"
+)
+
+// ScanPayload is the input of a scan job.
+type ScanPayload struct {
+	Dir string `json:"dir"`
+}
+
+// ScanResult is what a scan job did.
+type ScanResult struct {
+	Added    int      `json:"added"`
+	Updated  int      `json:"updated"`
+	Skipped  int      `json:"skipped"`
+	Failed   int      `json:"failed"`
+	Failures []string `json:"failures,omitempty"`
+}
+
+// Register adds the handlers of the job types to r.
+func Register(r *Runner, trackService services.TrackService, albumService services.AlbumService, chartService services.ChartService, duplicateService services.DuplicateService) {
+	Handle(r, TypeScan, func(ctx context.Context, payload ScanPayload) (interface{}, error) {
+		if payload.Dir == "" {
+			return nil, Permanent(errors.New("dir is required"))
+		}
+		if _, err := os.Stat(payload.Dir); err != nil {
+			return nil, Permanent(err)
+		}
+		if !helper.InLibrary(payload.Dir) {
+			return nil, Permanent(errors.New("dir is outside the library roots"))
+		}
+		summary, err := scanner.NewScanner(trackService, albumService).Scan(ctx, payload.Dir)
+		if err != nil {
+			return nil, err
+		}
+		return ScanResult{
+			Added:    summary.Added,
+			Updated:  summary.Updated,
+			Skipped:  summary.Skipped,
+			Failed:   summary.Failed,
+			Failures: summary.Failures,
+		}, nil
+	})
+	Handle(r, TypeCharts, func(ctx context.Context, _ struct{}) (interface{}, error) {
+		return nil, chartService.ComputeCharts(ctx)
+	})
+	Handle(r, TypeDuplicates, func(ctx context.Context, _ struct{}) (interface{}, error) {
+		clusters, err := duplicateService.FindDuplicateTracks(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return clusters, nil
+	})
+}
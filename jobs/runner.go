@@ -0,0 +1,263 @@
+// Package jobs runs the background jobs queued in the job service on a pool
+// of workers. Jobs are leased rather than locked, so several servers can
+// share the queue, and a job whose worker dies is picked up again once its
+// lease expires.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"musiclib/models"
+	"musiclib/services"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// firstRetryDelay is the wait after the first failed attempt of a job;
+	// it doubles with every further attempt, up to maxRetryDelay.
+	firstRetryDelay = 10 * time.Second
+	maxRetryDelay   = time.Hour
+)
+
+// Handler runs a job and returns its result, which is saved as JSON.
+type Handler func(ctx context.Context, job *models.Job) (interface{}, error)
+
+// permanentError is a failure that retrying cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (p *permanentError) Error() string {
+	return p.err.Error()
+}
+
+func (p *permanentError) Unwrap() error {
+	return p.err
+}
+
+// Permanent marks err so the job fails for good instead of being retried.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Handle registers fn for the jobs of jobType, with their payload decoded
+// into a T. A payload that does not decode fails the job for good.
+func Handle[T any](r *Runner, jobType string, fn func(context.Context, T) (interface{}, error)) {
+	r.handlers[jobType] = func(ctx context.Context, job *models.Job) (interface{}, error) {
+		var payload T
+		if len(job.Payload) > 0 {
+			if err := json.Unmarshal(job.Payload, &payload); err != nil {
+				return nil, Permanent(fmt.Errorf("invalid payload: %w", err))
+			}
+		}
+		return fn(ctx, payload)
+	}
+}
+
+type Runner struct {
+	jobService services.JobService
+	handlers   map[string]Handler
+	workers    int
+	lease      time.Duration
+	poll       time.Duration
+	owner      string
+	mu         sync.Mutex
+	// wake is closed, and replaced, when the service signals new jobs, so
+	// that every idle worker wakes up.
+	wake chan struct{}
+}
+
+// NewRunner creates a runner of workers workers. A job is leased for lease
+// at a time, and the queue is checked at least every poll.
+func NewRunner(jobService services.JobService, workers int, lease time.Duration, poll time.Duration) *Runner {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return &Runner{
+		jobService: jobService,
+		handlers:   map[string]Handler{},
+		workers:    workers,
+		lease:      lease,
+		poll:       poll,
+		owner:      fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(suffix)),
+		wake:       make(chan struct{}),
+	}
+}
+
+// Types lists the job types with a handler.
+func (r *Runner) Types() []string {
+	types := make([]string, 0, len(r.handlers))
+	for jobType := range r.handlers {
+		types = append(types, jobType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Handles reports whether jobType has a handler.
+func (r *Runner) Handles(jobType string) bool {
+	_, ok := r.handlers[jobType]
+	return ok
+}
+
+// Run works through the queue until ctx is done, and then waits for the
+// jobs in progress, which see ctx cancelled.
+func (r *Runner) Run(ctx context.Context) {
+	types := r.Types()
+	go func() {
+		for {
+			select {
+			case <-r.jobService.Wake():
+				r.mu.Lock()
+				close(r.wake)
+				r.wake = make(chan struct{})
+				r.mu.Unlock()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < r.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.work(ctx, types)
+		}()
+	}
+	wg.Wait()
+}
+
+// work claims and runs jobs until ctx is done.
+func (r *Runner) work(ctx context.Context, types []string) {
+	for ctx.Err() == nil {
+		r.mu.Lock()
+		wake := r.wake
+		r.mu.Unlock()
+		job, err := r.jobService.Claim(ctx, r.owner, types, r.lease)
+		if err != nil && ctx.Err() == nil {
+			log.Println("err claim job", err)
+		}
+		if job != nil {
+			r.run(ctx, job)
+			continue
+		}
+		select {
+		case <-wake:
+		case <-time.After(r.poll):
+		case <-ctx.Done():
+		}
+	}
+}
+
+// run runs job while renewing its lease, and records the outcome. The
+// outcome is recorded even when ctx is done.
+func (r *Runner) run(ctx context.Context, job *models.Job) {
+	record := context.WithoutCancel(ctx)
+	id, err := primitive.ObjectIDFromHex(job.JobId)
+	if err != nil {
+		log.Println("err job id", job.JobId, err)
+		return
+	}
+	// A job claimed again after its lease expired may have used up its
+	// attempts without recording a failure.
+	if job.Attempts > job.MaxAttempts {
+		r.fail(record, &id, job, errors.New("lease expired too many times"), false)
+		return
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		ticker := time.NewTicker(r.lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := r.jobService.ExtendLease(jobCtx, &id, r.owner, r.lease); err != nil {
+					log.Println("err extend job lease", job.JobId, err)
+					if errors.Is(err, services.ErrNotFound) {
+						cancel()
+						return
+					}
+				}
+			case <-jobCtx.Done():
+				return
+			}
+		}
+	}()
+
+	result, err := r.call(jobCtx, job)
+	switch {
+	case ctx.Err() != nil:
+		// Stopping: hand the job back to be run again straight away.
+		now := time.Now()
+		if err := r.jobService.Fail(record, &id, r.owner, "interrupted", &now); err != nil {
+			log.Println("err release job", job.JobId, err)
+		}
+		return
+	case jobCtx.Err() != nil:
+		// The lease was lost; the job belongs to someone else now.
+		return
+	}
+	if err != nil {
+		r.fail(record, &id, job, err, true)
+		return
+	}
+	var data []byte
+	if result != nil {
+		if data, err = json.Marshal(result); err != nil {
+			r.fail(record, &id, job, fmt.Errorf("encode result: %w", err), false)
+			return
+		}
+	}
+	if err := r.jobService.Complete(record, &id, r.owner, data); err != nil {
+		log.Println("err complete job", job.JobId, err)
+	}
+}
+
+// call runs the handler of job, turning a panic into an error.
+func (r *Runner) call(ctx context.Context, job *models.Job) (result interface{}, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	handler, ok := r.handlers[job.Type]
+	if !ok {
+		return nil, Permanent(fmt.Errorf("no handler for job type %q", job.Type))
+	}
+	return handler(ctx, job)
+}
+
+// fail records a failed attempt of job. It is retried with exponential
+// backoff if retry allows it and attempts are left.
+func (r *Runner) fail(ctx context.Context, id *primitive.ObjectID, job *models.Job, err error, retry bool) {
+	var permanent *permanentError
+	var retryAt *time.Time
+	if retry && !errors.As(err, &permanent) && job.Attempts < job.MaxAttempts {
+		delay := maxRetryDelay
+		if job.Attempts < 20 {
+			delay = min(firstRetryDelay<<(job.Attempts-1), maxRetryDelay)
+		}
+		at := time.Now().Add(delay)
+		retryAt = &at
+	}
+	if retryAt == nil {
+		log.Println("job dead", job.Type, job.JobId, err)
+	}
+	if err := r.jobService.Fail(ctx, id, r.owner, err.Error(), retryAt); err != nil {
+		log.Println("err fail job", job.JobId, err)
+	}
+}
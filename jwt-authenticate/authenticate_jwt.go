@@ -5,6 +5,7 @@ import (
 	"musiclib/controllers"
 	"musiclib/helper"
 	"musiclib/models"
+	"net/http"
 	"os"
 	"time"
 
@@ -55,7 +56,7 @@ func NewJWTAuthMiddleware(userController *controllers.UserController) *jwt.GinJW
 			username := loginVals.Username
 			password := loginVals.Password
 
-			user, err := userController.UserService.GetUserFromUsername(&username)
+			user, err := userController.UserService.GetUserFromUsername(c.Request.Context(), &username)
 
 			if err != nil {
 				return nil, jwt.ErrFailedAuthentication
@@ -103,3 +104,41 @@ func NewJWTAuthMiddleware(userController *controllers.UserController) *jwt.GinJW
 	}
 	return authMiddleware
 }
+
+// RequireAdmin only lets users with the admin role through. It must run
+// after the JWT middleware.
+func RequireAdmin(userController *controllers.UserController) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, ok := c.Get(identityKey)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"code": http.StatusUnauthorized, "message": "missing identity"})
+			return
+		}
+		userId := identity.(*models.User).UserId
+		user, err := userController.UserService.GetUser(c.Request.Context(), &userId)
+		if err != nil || user.Role != models.RoleAdmin {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"code": http.StatusForbidden, "message": "admin role required"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// VerifyToken checks a token issued by the login route, as the middleware
+// does for a request, and returns the user it identifies. It serves the
+// clients that do not come through gin.
+func VerifyToken(authMiddleware *jwt.GinJWTMiddleware, token string) (*models.User, error) {
+	parsed, err := authMiddleware.ParseTokenString(token)
+	if err != nil {
+		return nil, err
+	}
+	claims := jwt.ExtractClaimsFromToken(parsed)
+	if _, ok := claims["exp"].(float64); !ok {
+		return nil, jwt.ErrMissingExpField
+	}
+	userId, ok := claims[identityKey].(string)
+	if !ok {
+		return nil, jwt.ErrInvalidAuthHeader
+	}
+	return &models.User{UserId: userId}, nil
+}
@@ -6,11 +6,27 @@ import (
 	"musiclib/connect"
 	"musiclib/controllers"
 	docs "musiclib/docs"
+	"musiclib/dto"
+	"musiclib/graph"
+	"musiclib/helper"
+	"musiclib/jobs"
 	auth "musiclib/jwt-authenticate"
+	"musiclib/migrations"
 	"musiclib/models"
+	"musiclib/radio"
+	"musiclib/rpc"
+	"musiclib/scanner"
+	"musiclib/services"
 	implements "musiclib/services/implement"
+	"musiclib/subsonic"
+	"net"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
+	jwt "github.com/appleboy/gin-jwt/v2"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	swaggerfiles "github.com/swaggo/files"
@@ -19,11 +35,33 @@ import (
 )
 
 var (
-	trackController *controllers.TrackController
-	albumController *controllers.AlbumController
-	userController  *controllers.UserController
-	ctx             context.Context
-	mongoClient     *mongo.Client
+	trackController        *controllers.TrackController
+	albumController        *controllers.AlbumController
+	userController         *controllers.UserController
+	reviewController       *controllers.ReviewController
+	chartController        *controllers.ChartController
+	playlistController     *controllers.PlaylistController
+	playbackController     *controllers.PlaybackController
+	duplicateController    *controllers.DuplicateController
+	playlistFileController *controllers.PlaylistFileController
+	trashController        *controllers.TrashController
+	auditController        *controllers.AuditController
+	webhookController      *controllers.WebhookController
+	feedController         *controllers.FeedController
+	graphqlController      *controllers.GraphQLController
+	radioController        *controllers.RadioController
+	jobController          *controllers.JobController
+	subsonicController     *subsonic.Controller
+	trackService           services.TrackService
+	albumService           services.AlbumService
+	userService            services.UserService
+	auditService           services.AuditService
+	chartService           services.ChartService
+	webhookService         services.WebhookService
+	jobService             services.JobService
+	jobRunner              *jobs.Runner
+	ctx                    context.Context
+	mongoClient            *mongo.Client
 )
 
 func Init() {
@@ -34,18 +72,262 @@ func Init() {
 	if err := connect.Connect(); err != nil {
 		log.Fatal("err connect db", err)
 	}
+	implements.SetTimeouts(serviceTimeouts())
+	setLibraryRoots()
 	ctx := context.TODO()
+	if err := migrations.CheckVersion(ctx, connect.Ng.Database); err != nil {
+		log.Fatal("err schema version: ", err)
+	}
+	if os.Getenv("MIGRATE_ON_START") == "true" {
+		done, err := migrations.Up(ctx, connect.Ng.Database)
+		for _, migration := range done {
+			log.Printf("applied migration %d %s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal("err migrate: ", err)
+		}
+	}
 	trackCollection := connect.Ng.Database.Collection("tracks")
-	trackService := implements.NewTrackService(trackCollection, ctx)
+	albumCollection := connect.Ng.Database.Collection("albums")
+	playCollection := connect.Ng.Database.Collection("plays")
+	auditCollection := connect.Ng.Database.Collection("audit_log")
+	auditService = implements.NewAuditService(auditCollection)
+	auditController = controllers.NewAuditController(auditService)
+
+	webhookCollection := connect.Ng.Database.Collection("webhooks")
+	deliveryCollection := connect.Ng.Database.Collection("webhook_deliveries")
+	webhookService = implements.NewWebhookService(webhookCollection, deliveryCollection)
+	webhookController = controllers.NewWebhookController(webhookService)
+
+	trackService = implements.NewTrackService(trackCollection, albumCollection, playCollection, webhookService, auditService)
 	trackController = controllers.NewTrackController(trackService)
 
 	userCollection := connect.Ng.Database.Collection("users")
-	userService := implements.NewUserService(userCollection, ctx)
+	userService = implements.NewUserService(userCollection, auditService)
 	userController = controllers.NewUserController(userService)
 
-	albumCollection := connect.Ng.Database.Collection("albums")
-	albumService := implements.NewAlbumService(albumCollection, trackCollection, ctx)
+	albumService = implements.NewAlbumService(albumCollection, trackCollection, webhookService, auditService)
 	albumController = controllers.NewAlbumController(albumService)
+
+	feedService := implements.NewFeedService(trackCollection, albumCollection)
+	feedController = controllers.NewFeedController(feedService)
+
+	reviewCollection := connect.Ng.Database.Collection("reviews")
+	reviewService := implements.NewReviewService(reviewCollection, albumCollection)
+	reviewController = controllers.NewReviewController(reviewService)
+
+	schema, err := graph.NewSchema(trackService, albumService, reviewService, userService)
+	if err != nil {
+		log.Fatal("err graphql schema", err)
+	}
+	graphqlController = controllers.NewGraphQLController(schema)
+
+	chartCollection := connect.Ng.Database.Collection("charts")
+	chartService = implements.NewChartService(chartCollection, playCollection)
+	chartController = controllers.NewChartController(chartService)
+
+	playlistCollection := connect.Ng.Database.Collection("playlists")
+	playlistService := implements.NewPlaylistService(playlistCollection, trackCollection)
+	playlistController = controllers.NewPlaylistController(playlistService)
+
+	playbackCollection := connect.Ng.Database.Collection("playback_states")
+	playbackService := implements.NewPlaybackService(playbackCollection)
+	playbackController = controllers.NewPlaybackController(playbackService)
+
+	jobCollection := connect.Ng.Database.Collection("jobs")
+	jobService = implements.NewJobService(jobCollection)
+
+	duplicateService := implements.NewDuplicateService(trackCollection, albumCollection, playCollection, playbackCollection, albumService, webhookService, auditService)
+	duplicateController = controllers.NewDuplicateController(duplicateService, jobService)
+
+	jobRunner = newJobRunner()
+	jobs.Register(jobRunner, trackService, albumService, chartService, duplicateService)
+	jobController = controllers.NewJobController(jobService, jobRunner)
+
+	streamPath := os.Getenv("SERVER_GROUP") + "/track/stream/"
+	playlistFileController = controllers.NewPlaylistFileController(trackService, albumService, playlistService, streamPath)
+	radioController = controllers.NewRadioController(albumService, playlistService, radio.NewHub())
+	trashController = controllers.NewTrashController(trackService, albumService, userService)
+
+	// The Subsonic app passwords are sealed with SUBSONIC_SECRET_KEY, or the
+	// JWT secret when it is not set.
+	subsonicSecret := os.Getenv("SUBSONIC_SECRET_KEY")
+	if subsonicSecret == "" {
+		subsonicSecret = os.Getenv("JWT_SECRET_KEY")
+	}
+	subsonicController = subsonic.NewController(trackService, albumService, userService, playlistService, []byte(subsonicSecret))
+}
+
+// setLibraryRoots sets the directories the media files are served from:
+// those listed in LIBRARY_ROOTS and the watched LIBRARY_WATCH ones.
+func setLibraryRoots() {
+	roots := filepath.SplitList(os.Getenv("LIBRARY_ROOTS"))
+	roots = append(roots, filepath.SplitList(os.Getenv("LIBRARY_WATCH"))...)
+	helper.SetLibraryRoots(roots)
+	if len(helper.LibraryRoots()) == 0 {
+		log.Println("no library roots: set LIBRARY_ROOTS to serve and import media files")
+	}
+}
+
+// serviceTimeouts reads the timeouts of the service operations:
+// DB_READ_TIMEOUT (default 5s), DB_WRITE_TIMEOUT (default 10s) and
+// DB_BULK_TIMEOUT (default 5m) by kind, and DB_OPERATION_TIMEOUTS for
+// single operations, as in "album.FindTracksAndAlbums=2s,chart.ComputeCharts=15m".
+// A timeout of 0 means none.
+func serviceTimeouts() implements.Timeouts {
+	timeouts := implements.DefaultTimeouts
+	durations := map[string]*time.Duration{
+		"DB_READ_TIMEOUT":  &timeouts.Read,
+		"DB_WRITE_TIMEOUT": &timeouts.Write,
+		"DB_BULK_TIMEOUT":  &timeouts.Bulk,
+	}
+	for name, timeout := range durations {
+		if d, err := time.ParseDuration(os.Getenv(name)); err == nil && d >= 0 {
+			*timeout = d
+		}
+	}
+	timeouts.Operations = map[string]time.Duration{}
+	for _, entry := range strings.Split(os.Getenv("DB_OPERATION_TIMEOUTS"), ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || d < 0 {
+			log.Println("err operation timeout", entry)
+			continue
+		}
+		timeouts.Operations[strings.TrimSpace(name)] = d
+	}
+	return timeouts
+}
+
+// scheduleCharts queues a recomputation of the charts every CHART_INTERVAL
+// (default 1h). The job is keyed, so servers sharing the queue do not
+// compute them twice.
+func scheduleCharts() {
+	interval, err := time.ParseDuration(os.Getenv("CHART_INTERVAL"))
+	if err != nil || interval <= 0 {
+		interval = time.Hour
+	}
+	for {
+		job := models.Job{Type: jobs.TypeCharts, Key: jobs.TypeCharts}
+		if err := jobService.Enqueue(context.Background(), &job); err != nil {
+			log.Println("err queue charts", err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// newJobRunner creates the job runner with JOB_WORKERS workers (default
+// 4), leasing jobs for JOB_LEASE (default 1m) and checking the queue at
+// least every JOB_POLL_INTERVAL (default 5s).
+func newJobRunner() *jobs.Runner {
+	workers, err := strconv.Atoi(os.Getenv("JOB_WORKERS"))
+	if err != nil || workers <= 0 {
+		workers = 4
+	}
+	lease, err := time.ParseDuration(os.Getenv("JOB_LEASE"))
+	if err != nil || lease < 3*time.Second {
+		lease = time.Minute
+	}
+	poll, err := time.ParseDuration(os.Getenv("JOB_POLL_INTERVAL"))
+	if err != nil || poll <= 0 {
+		poll = 5 * time.Second
+	}
+	return jobs.NewRunner(jobService, workers, lease, poll)
+}
+
+// schedulePurge permanently removes the tracks, albums and users that have
+// been in the trash for longer than TRASH_RETENTION (default 30 days),
+// checking every TRASH_PURGE_INTERVAL (default 24h).
+func schedulePurge() {
+	retention, err := time.ParseDuration(os.Getenv("TRASH_RETENTION"))
+	if err != nil || retention <= 0 {
+		retention = 30 * 24 * time.Hour
+	}
+	interval, err := time.ParseDuration(os.Getenv("TRASH_PURGE_INTERVAL"))
+	if err != nil || interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	for {
+		before := time.Now().Add(-retention)
+		purges := map[string]func(context.Context, time.Time) (int64, error){
+			"tracks": trackService.PurgeTracks,
+			"albums": albumService.PurgeAlbums,
+			"users":  userService.PurgeUsers,
+		}
+		for name, purge := range purges {
+			count, err := purge(context.Background(), before)
+			if err != nil {
+				log.Println("err purge", name, err)
+			} else if count > 0 {
+				log.Printf("purged %d %s from the trash", count, name)
+			}
+		}
+		time.Sleep(interval)
+	}
+}
+
+// deliverWebhooks sends the queued webhook deliveries as soon as they are
+// published, and retries the failed ones, checking at least every
+// WEBHOOK_POLL_INTERVAL (default 10s).
+func deliverWebhooks() {
+	interval, err := time.ParseDuration(os.Getenv("WEBHOOK_POLL_INTERVAL"))
+	if err != nil || interval <= 0 {
+		interval = 10 * time.Second
+	}
+	for {
+		if err := webhookService.DeliverPending(context.Background()); err != nil {
+			log.Println("err deliver webhooks", err)
+		}
+		select {
+		case <-webhookService.Wake():
+		case <-time.After(interval):
+		}
+	}
+}
+
+// watchLibrary keeps the tracks in sync with the directories listed in
+// LIBRARY_WATCH, if any.
+func watchLibrary() {
+	roots := filepath.SplitList(os.Getenv("LIBRARY_WATCH"))
+	if len(roots) == 0 {
+		return
+	}
+	debounce, err := time.ParseDuration(os.Getenv("LIBRARY_WATCH_DEBOUNCE"))
+	if err != nil || debounce <= 0 {
+		debounce = 2 * time.Second
+	}
+	watcher, err := scanner.NewWatcher(scanner.NewScanner(trackService, albumService), debounce)
+	if err != nil {
+		log.Println("err start library watcher", err)
+		return
+	}
+	defer watcher.Close()
+	if err := watcher.Watch(roots); err != nil {
+		log.Println("err library watcher", err)
+	}
+}
+
+// serveGRPC serves the catalog over gRPC on GRPC_ADDR (default :9090),
+// with the tokens of authMiddleware.
+func serveGRPC(authMiddleware *jwt.GinJWTMiddleware) {
+	addr := os.Getenv("GRPC_ADDR")
+	if addr == "" {
+		addr = ":9090"
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Println("err listen grpc", err)
+		return
+	}
+	server := rpc.NewServer(trackService, albumService, func(token string) (*models.User, error) {
+		return auth.VerifyToken(authMiddleware, token)
+	})
+	if err := server.Serve(listener); err != nil {
+		log.Println("err serve grpc", err)
+	}
 }
 
 func returnUser(c *gin.Context) {
@@ -73,10 +355,26 @@ func returnUser(c *gin.Context) {
 // @name                       Authorization
 func main() {
 	Init()
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+	if err := migrations.CheckApplied(context.TODO(), connect.Ng.Database); err != nil {
+		log.Fatal("err schema version: ", err)
+	}
+	if err := dto.RegisterValidators(); err != nil {
+		log.Fatal("err register validators", err)
+	}
 	authMiddleware := auth.NewJWTAuthMiddleware(userController)
 	defer mongoClient.Disconnect(ctx)
+	go jobRunner.Run(context.Background())
+	go scheduleCharts()
+	go schedulePurge()
+	go deliverWebhooks()
+	go watchLibrary()
+	go serveGRPC(authMiddleware)
 	docs.SwaggerInfo.BasePath = "/v1"
 	r := gin.Default()
+	r.Use(controllers.ErrorHandler())
 	group := os.Getenv("SERVER_GROUP")
 	basepath := r.Group(group)
 	// basepath.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
@@ -88,15 +386,36 @@ func main() {
 	// Apply middleware only to the /currentUser route
 	basepath.GET("/currentUser", authMiddleware.MiddlewareFunc(), returnUser)
 
-	// Apply middleware to all routes under basePath, except for GET requests, and /v1/user/create
+	// Apply middleware to all routes under basePath, except for GET requests, /v1/user/create
+	// and /v1/graphql, which checks the token of mutations itself
 	basepath.Use(func(c *gin.Context) {
-		if c.Request.Method != "GET" && c.FullPath() != "/v1/user/create" {
+		if c.Request.Method != "GET" && c.FullPath() != "/v1/user/create" && c.FullPath() != "/v1/graphql" {
 			authMiddleware.MiddlewareFunc()(c)
 		}
 	})
+	basepath.Use(controllers.Actor())
 	userController.RegisterUserRoute(basepath)
 	trackController.RegisterTrackRouter(basepath)
 	albumController.RegisterAlbumRouter(basepath)
+	reviewController.RegisterReviewRouter(basepath)
+	chartController.RegisterChartRouter(basepath)
+	playlistController.RegisterPlaylistRouter(basepath)
+	playbackController.RegisterPlaybackRouter(basepath, authMiddleware.MiddlewareFunc())
+	playlistFileController.RegisterPlaylistFileRouter(basepath)
+	feedController.RegisterFeedRouter(basepath)
+	radioController.RegisterRadioRouter(basepath)
+	graphqlController.RegisterGraphQLRouter(basepath, authMiddleware.MiddlewareFunc())
+	subsonicController.RegisterPasswordRouter(basepath)
+
+	// Admin routes require a token for every method and the admin role
+	admin := basepath.Group("/admin", authMiddleware.MiddlewareFunc(), auth.RequireAdmin(userController), controllers.Actor())
+	duplicateController.RegisterDuplicateRouter(admin)
+	trashController.RegisterTrashRouter(admin)
+	auditController.RegisterAuditRouter(admin)
+	webhookController.RegisterWebhookRouter(admin)
+	jobController.RegisterJobRouter(admin)
+	// Subsonic clients expect the API at /rest and sign every request in
+	subsonicController.RegisterSubsonicRouter(r)
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 	r.Run(":8080")
 }
@@ -0,0 +1,196 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"musiclib/helper"
+	"musiclib/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// all lists the migrations in version order. Never reorder or renumber an
+// existing entry; append new ones at the end.
+var all = []Migration{
+	{Version: 1, Name: "backfill track duration_seconds", Up: backfillDurationSeconds},
+	{Version: 2, Name: "create indexes", Up: createIndexes},
+	{Version: 3, Name: "index deleted_at", Up: indexDeletedAt},
+	{Version: 4, Name: "index audit log", Up: indexAuditLog},
+	{Version: 5, Name: "index webhook deliveries", Up: indexWebhookDeliveries},
+	{Version: 6, Name: "index jobs", Up: indexJobs},
+	{Version: 7, Name: "unique pending job keys", Up: uniquePendingJobKeys},
+	{Version: 8, Name: "index album tracks", Up: indexAlbumTracks},
+}
+
+// backfillDurationSeconds parses the free-form duration of tracks written
+// before duration_seconds existed.
+func backfillDurationSeconds(ctx context.Context, db *mongo.Database) error {
+	tracks := db.Collection("tracks")
+	filter := bson.M{"duration_seconds": bson.M{"$exists": false}, "duration": bson.M{"$type": "string"}}
+	cursor, err := tracks.Find(ctx, filter, options.Find().SetProjection(bson.M{"duration": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var track struct {
+			Id       interface{} `bson:"_id"`
+			Duration string      `bson:"duration"`
+		}
+		if err := cursor.Decode(&track); err != nil {
+			return err
+		}
+		seconds, ok := helper.ParseDuration(track.Duration)
+		if !ok {
+			continue
+		}
+		update := bson.M{"$set": bson.M{"duration_seconds": seconds}}
+		if _, err := tracks.UpdateOne(ctx, bson.M{"_id": track.Id}, update); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// createIndexes creates the indexes of the lookups, and the unique index
+// that allows a single review of an album per user.
+func createIndexes(ctx context.Context, db *mongo.Database) error {
+	indexes := map[string][]mongo.IndexModel{
+		"tracks": {
+			{Keys: bson.D{{Key: "file_name", Value: 1}}},
+			{Keys: bson.D{{Key: "file_hash", Value: 1}}},
+		},
+		"reviews": {
+			{Keys: bson.D{{Key: "album_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		},
+		"plays": {
+			{Keys: bson.D{{Key: "played_at", Value: 1}}},
+			{Keys: bson.D{{Key: "track_id", Value: 1}, {Key: "user_id", Value: 1}}},
+		},
+		"playlists": {
+			{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		},
+		"users": {
+			{Keys: bson.D{{Key: "username", Value: 1}}},
+		},
+	}
+	for collection, models := range indexes {
+		if _, err := db.Collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexDeletedAt indexes the deletion time of the collections with a trash,
+// used to list and purge it.
+func indexDeletedAt(ctx context.Context, db *mongo.Database) error {
+	for _, collection := range []string{"tracks", "albums", "users"} {
+		index := mongo.IndexModel{
+			Keys:    bson.D{{Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		}
+		if _, err := db.Collection(collection).Indexes().CreateOne(ctx, index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexAuditLog indexes the audit log for its queries by resource and by
+// actor, newest first.
+func indexAuditLog(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("audit_log").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "resource", Value: 1}, {Key: "resource_id", Value: 1}, {Key: "at", Value: -1}}},
+		{Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "at", Value: -1}}},
+		{Keys: bson.D{{Key: "at", Value: -1}}},
+	})
+	return err
+}
+
+// indexWebhookDeliveries indexes the due deliveries, the delivery history of
+// a webhook and the subscriptions to an event.
+func indexWebhookDeliveries(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("webhook_deliveries").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		{Keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = db.Collection("webhooks").Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "events", Value: 1}}})
+	return err
+}
+
+// indexJobs indexes the jobs by what workers claim them on, their key and
+// their age.
+func indexJobs(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("jobs").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "type", Value: 1}, {Key: "run_at", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "lease_expires_at", Value: 1}}},
+		{Keys: bson.D{{Key: "key", Value: 1}, {Key: "status", Value: 1}}},
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+	})
+	return err
+}
+
+// codeIndexNotFound is the server error of dropping an index that does not
+// exist.
+const codeIndexNotFound = 27
+
+// uniquePendingJobKeys lets only one queued or running job hold a key, in
+// place of the plain index on key and status.
+func uniquePendingJobKeys(ctx context.Context, db *mongo.Database) error {
+	major, err := serverMajorVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+	pending := bson.M{"$in": bson.A{models.JobQueued, models.JobRunning}}
+	if major < 6 {
+		// Partial filters take $in from MongoDB 6.0 on. Queued and running
+		// are the only statuses sorting between them.
+		pending = bson.M{"$gte": models.JobQueued, "$lte": models.JobRunning}
+	}
+	jobs := db.Collection("jobs")
+	index := mongo.IndexModel{
+		Keys: bson.D{{Key: "key", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+			"key":    bson.M{"$exists": true},
+			"status": pending,
+		}),
+	}
+	if _, err := jobs.Indexes().CreateOne(ctx, index); err != nil {
+		return err
+	}
+	// The plain index is gone already if this runs again.
+	_, err = jobs.Indexes().DropOne(ctx, "key_1_status_1")
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) && serverErr.HasErrorCode(codeIndexNotFound) {
+		return nil
+	}
+	return err
+}
+
+// serverMajorVersion is the major version of the MongoDB server.
+func serverMajorVersion(ctx context.Context, db *mongo.Database) (int, error) {
+	var info struct {
+		VersionArray []int `bson:"versionArray"`
+	}
+	if err := db.RunCommand(ctx, bson.D{{Key: "buildInfo", Value: 1}}).Decode(&info); err != nil {
+		return 0, err
+	}
+	if len(info.VersionArray) == 0 {
+		return 0, errors.New("server did not report its version")
+	}
+	return info.VersionArray[0], nil
+}
+
+// indexAlbumTracks indexes the ids of the tracks embedded in albums, which
+// the album charts join the plays on, and the lookups of the albums of a
+// track go by.
+func indexAlbumTracks(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("albums").Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "tracks._id", Value: 1}}})
+	return err
+}
@@ -0,0 +1,148 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CollectionName is where applied migrations are recorded.
+const CollectionName = "schema_migrations"
+
+// Migration upgrades existing documents. Up must be idempotent: it may run
+// again if the process stops before the migration is recorded.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+}
+
+// Record is the schema_migrations document of an applied migration.
+type Record struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+// Status is the state of one known migration.
+type Status struct {
+	Migration *Migration
+	Applied   *Record
+}
+
+// Latest is the highest schema version this build knows about.
+func Latest() int {
+	return all[len(all)-1].Version
+}
+
+func applied(ctx context.Context, db *mongo.Database) (map[int]*Record, error) {
+	cursor, err := db.Collection(CollectionName).Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var records []Record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*Record, len(records))
+	for i := range records {
+		byVersion[records[i].Version] = &records[i]
+	}
+	return byVersion, nil
+}
+
+// CurrentVersion is the highest version recorded in the database, 0 for a
+// fresh database.
+func CurrentVersion(ctx context.Context, db *mongo.Database) (int, error) {
+	records, err := applied(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	current := 0
+	for version := range records {
+		current = max(current, version)
+	}
+	return current, nil
+}
+
+// CheckVersion fails when the database was migrated by a newer build.
+func CheckVersion(ctx context.Context, db *mongo.Database) error {
+	current, err := CurrentVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+	if current > Latest() {
+		return fmt.Errorf("database schema version %d is newer than the latest known version %d, upgrade musiclib", current, Latest())
+	}
+	return nil
+}
+
+// List returns every known migration with its applied record, if any.
+func List(ctx context.Context, db *mongo.Database) ([]Status, error) {
+	records, err := applied(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, 0, len(all))
+	for i := range all {
+		statuses = append(statuses, Status{Migration: &all[i], Applied: records[all[i].Version]})
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations that have not been applied, in order.
+func Pending(ctx context.Context, db *mongo.Database) ([]*Migration, error) {
+	statuses, err := List(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	var pending []*Migration
+	for _, status := range statuses {
+		if status.Applied == nil {
+			pending = append(pending, status.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// CheckApplied fails while migrations are pending. The services rely on
+// the indexes they create, such as the unique keys of reviews and of
+// pending jobs, so the server must not run without them.
+func CheckApplied(ctx context.Context, db *mongo.Database) error {
+	pending, err := Pending(ctx, db)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%d migrations pending, from %d %s: run musiclib migrate or set MIGRATE_ON_START=true", len(pending), pending[0].Version, pending[0].Name)
+	}
+	return nil
+}
+
+// Up applies the pending migrations in order and returns them. It stops at
+// the first failure.
+func Up(ctx context.Context, db *mongo.Database) ([]*Migration, error) {
+	if err := CheckVersion(ctx, db); err != nil {
+		return nil, err
+	}
+	pending, err := Pending(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	done := make([]*Migration, 0, len(pending))
+	for _, migration := range pending {
+		if err := migration.Up(ctx, db); err != nil {
+			return done, fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+		record := Record{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}
+		if _, err := db.Collection(CollectionName).InsertOne(ctx, record); err != nil && !mongo.IsDuplicateKeyError(err) {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
@@ -1,8 +1,14 @@
 package models
 
+import "time"
+
 type Album struct {
-	AlbumId    string  `json:"id,omitempty" bson:"_id,omitempty"`
-	Title      string  `json:"album_title" bson:"album_title"`
-	AlbumCover string  `json:"album_cover" bson:"album_cover"`
-	Tracks     []Track `json:"tracks" bson:"tracks"`
+	AlbumId       string     `json:"id,omitempty" bson:"_id,omitempty"`
+	Title         string     `json:"album_title" bson:"album_title"`
+	AlbumCover    string     `json:"album_cover" bson:"album_cover"`
+	Tracks        []Track    `json:"tracks" bson:"tracks"`
+	RatingAverage float64    `json:"rating_average" bson:"rating_average,omitempty"`
+	RatingCount   int        `json:"rating_count" bson:"rating_count,omitempty"`
+	Version       int64      `json:"version,omitempty" bson:"version,omitempty"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
 }
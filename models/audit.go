@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Audited actions.
+const (
+	AuditCreate      = "create"
+	AuditUpdate      = "update"
+	AuditDelete      = "delete"
+	AuditRestore     = "restore"
+	AuditAddTrack    = "add-track"
+	AuditRemoveTrack = "remove-track"
+	AuditMerge       = "merge"
+)
+
+// AuditEntry records one mutation of a resource. Before and After are
+// snapshots of the resource, absent when it did not exist.
+type AuditEntry struct {
+	AuditId    string                 `json:"id,omitempty" bson:"_id,omitempty"`
+	Action     string                 `json:"action" bson:"action"`
+	Resource   string                 `json:"resource" bson:"resource"`
+	ResourceId string                 `json:"resource_id" bson:"resource_id"`
+	ActorId    string                 `json:"actor_id,omitempty" bson:"actor_id,omitempty"`
+	IP         string                 `json:"ip" bson:"ip"`
+	At         time.Time              `json:"at" bson:"at"`
+	Before     map[string]interface{} `json:"before,omitempty" bson:"before,omitempty"`
+	After      map[string]interface{} `json:"after,omitempty" bson:"after,omitempty"`
+}
+
+// AuditFilter selects audit entries. Empty fields match everything.
+type AuditFilter struct {
+	Resource   string
+	ResourceId string
+	ActorId    string
+	Action     string
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ChangeAlbumTracks is the change feed type of a change to the tracks of an
+// album. The other types are the track and album webhook events.
+const ChangeAlbumTracks = "album.tracks_changed"
+
+// Change is one library change pushed to feed clients. Token resumes the
+// feed right after this change. Data is the document after the change; it
+// is left out for deletions.
+type Change struct {
+	Token      string      `json:"token"`
+	Type       string      `json:"type"`
+	ResourceId string      `json:"id"`
+	At         time.Time   `json:"at"`
+	Data       interface{} `json:"data,omitempty"`
+}
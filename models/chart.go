@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+type Chart struct {
+	ChartId     string       `json:"id" bson:"_id"`
+	Kind        string       `json:"kind" bson:"kind"`
+	Window      string       `json:"window" bson:"window"`
+	PeriodStart time.Time    `json:"period_start" bson:"period_start"`
+	PeriodEnd   time.Time    `json:"period_end" bson:"period_end"`
+	GeneratedAt time.Time    `json:"generated_at" bson:"generated_at"`
+	Entries     []ChartEntry `json:"entries" bson:"entries"`
+}
+
+type ChartEntry struct {
+	Rank         int    `json:"rank" bson:"rank"`
+	PreviousRank int    `json:"previous_rank,omitempty" bson:"previous_rank,omitempty"`
+	Movement     int    `json:"movement" bson:"movement"`
+	New          bool   `json:"new" bson:"new"`
+	ItemId       string `json:"item_id" bson:"item_id"`
+	Title        string `json:"title" bson:"title"`
+	Artist       string `json:"artist,omitempty" bson:"artist,omitempty"`
+	Plays        int    `json:"plays" bson:"plays"`
+}
@@ -0,0 +1,7 @@
+package models
+
+// DuplicateCluster is a group of tracks that are likely the same recording.
+type DuplicateCluster struct {
+	Reason string  `json:"reason"`
+	Tracks []Track `json:"tracks"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Job states. A job is queued until a worker leases it, and goes back to
+// queued after a failed attempt until it runs out of attempts and is dead.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobDead      = "dead"
+)
+
+var JobStatuses = []string{JobQueued, JobRunning, JobSucceeded, JobDead}
+
+// Job is a piece of background work. Payload is the JSON input of the
+// handler of Type and Result its JSON output. A running job belongs to
+// LeaseOwner until LeaseExpiresAt; a worker that stops renewing its lease
+// loses the job to another one.
+type Job struct {
+	JobId          string          `json:"id,omitempty" bson:"_id,omitempty"`
+	Type           string          `json:"type" bson:"type"`
+	Key            string          `json:"key,omitempty" bson:"key,omitempty"`
+	Payload        json.RawMessage `json:"payload,omitempty" bson:"payload,omitempty" swaggertype:"object"`
+	Status         string          `json:"status" bson:"status"`
+	Attempts       int             `json:"attempts" bson:"attempts"`
+	MaxAttempts    int             `json:"max_attempts" bson:"max_attempts"`
+	RunAt          time.Time       `json:"run_at" bson:"run_at"`
+	LeaseOwner     string          `json:"lease_owner,omitempty" bson:"lease_owner,omitempty"`
+	LeaseExpiresAt *time.Time      `json:"lease_expires_at,omitempty" bson:"lease_expires_at,omitempty"`
+	LastError      string          `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	Result         json.RawMessage `json:"result,omitempty" bson:"result,omitempty" swaggertype:"object"`
+	CreatedAt      time.Time       `json:"created_at" bson:"created_at"`
+	StartedAt      *time.Time      `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	FinishedAt     *time.Time      `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+}
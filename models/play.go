@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+type Play struct {
+	PlayId   string    `json:"id,omitempty" bson:"_id,omitempty"`
+	TrackId  string    `json:"track_id" bson:"track_id"`
+	UserId   string    `json:"user_id" bson:"user_id"`
+	PlayedAt time.Time `json:"played_at" bson:"played_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// PlaybackState is the play queue a user resumes from on any device.
+type PlaybackState struct {
+	UserId         string    `json:"user_id" bson:"_id"`
+	Queue          []string  `json:"queue" bson:"queue"`
+	CurrentTrackId string    `json:"current_track_id" bson:"current_track_id"`
+	PositionMs     int64     `json:"position_ms" bson:"position_ms"`
+	Shuffle        bool      `json:"shuffle" bson:"shuffle"`
+	Repeat         string    `json:"repeat" bson:"repeat"`
+	Device         string    `json:"device" bson:"device"`
+	Version        int64     `json:"version" bson:"version"`
+	UpdatedAt      time.Time `json:"updated_at" bson:"updated_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+type Playlist struct {
+	PlaylistId string     `json:"id,omitempty" bson:"_id,omitempty"`
+	UserId     string     `json:"user_id" bson:"user_id"`
+	Name       string     `json:"name" bson:"name"`
+	Rules      SmartRules `json:"rules" bson:"rules"`
+	CreatedAt  time.Time  `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" bson:"updated_at"`
+}
+
+// SmartRules selects the tracks of a playlist when it is read.
+type SmartRules struct {
+	// Match is "all" (the default) or "any" of the conditions.
+	Match      string          `json:"match" bson:"match"`
+	Conditions []RuleCondition `json:"conditions" bson:"conditions"`
+	SortBy     string          `json:"sort_by" bson:"sort_by"`
+	Descending bool            `json:"descending" bson:"descending"`
+	Limit      int             `json:"limit" bson:"limit"`
+}
+
+type RuleCondition struct {
+	Field    string      `json:"field" bson:"field"`
+	Operator string      `json:"operator" bson:"operator"`
+	Value    interface{} `json:"value" bson:"value"`
+}
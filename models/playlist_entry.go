@@ -0,0 +1,11 @@
+package models
+
+// PlaylistEntry is one track of an M3U8, PLS or XSPF playlist file.
+type PlaylistEntry struct {
+	Location        string `json:"location"`
+	Title           string `json:"title"`
+	Artist          string `json:"artist"`
+	DurationSeconds int    `json:"duration_seconds"`
+	// Line is where the entry starts in the imported file.
+	Line int `json:"line"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type Review struct {
+	ReviewId  string    `json:"id,omitempty" bson:"_id,omitempty"`
+	AlbumId   string    `json:"album_id" bson:"album_id"`
+	UserId    string    `json:"user_id" bson:"user_id"`
+	Rating    int       `json:"rating" bson:"rating"`
+	Text      string    `json:"text" bson:"text"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
@@ -0,0 +1,7 @@
+package models
+
+type SimilarTrack struct {
+	Track   Track    `json:"track"`
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+}
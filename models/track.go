@@ -1,11 +1,18 @@
 package models
 
+import "time"
+
 type Track struct {
-	TrackId     string `json:"id,omitempty" bson:"_id,omitempty"`
-	Title       string `json:"music_title" bson:"music_title"`
-	Artist      string `json:"artist" bson:"artist"`
-	Genre       string `json:"genre" bson:"genre"`
-	ReleaseYear string `json:"release_year" bson:"release_year"`
-	Duration    string `json:"duration" bson:"duration"`
-	FileName    string `json:"file_name" bson:"file_name"`
+	TrackId         string     `json:"id,omitempty" bson:"_id,omitempty"`
+	Title           string     `json:"music_title" bson:"music_title"`
+	Artist          string     `json:"artist" bson:"artist"`
+	Genre           string     `json:"genre" bson:"genre"`
+	ReleaseYear     string     `json:"release_year" bson:"release_year"`
+	Duration        string     `json:"duration" bson:"duration"`
+	DurationSeconds int        `json:"duration_seconds,omitempty" bson:"duration_seconds,omitempty"`
+	FileName        string     `json:"file_name" bson:"file_name"`
+	FileHash        string     `json:"file_hash,omitempty" bson:"file_hash,omitempty"`
+	Unavailable     bool       `json:"unavailable,omitempty" bson:"unavailable,omitempty"`
+	Version         int64      `json:"version,omitempty" bson:"version,omitempty"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
 }
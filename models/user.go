@@ -1,7 +1,16 @@
 package models
 
+import "time"
+
+// RoleAdmin is the role of users allowed to use the /admin routes.
+const RoleAdmin = "admin"
+
 type User struct {
-	UserId   string `json:"id,omitempty" bson:"_id,omitempty"`
-	Username string `json:"username" bson:"username"`
-	Password string `json:"password" bson:"password"`
+	UserId           string     `json:"id,omitempty" bson:"_id,omitempty"`
+	Username         string     `json:"username" bson:"username"`
+	Password         string     `json:"password" bson:"password"`
+	Role             string     `json:"role,omitempty" bson:"role,omitempty"`
+	SubsonicPassword string     `json:"-" bson:"subsonic_password,omitempty"`
+	Version          int64      `json:"version,omitempty" bson:"version,omitempty"`
+	DeletedAt        *time.Time `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
 }
@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// Catalog events webhooks can subscribe to.
+const (
+	EventTrackCreated      = "track.created"
+	EventTrackUpdated      = "track.updated"
+	EventTrackDeleted      = "track.deleted"
+	EventTrackRestored     = "track.restored"
+	EventAlbumCreated      = "album.created"
+	EventAlbumUpdated      = "album.updated"
+	EventAlbumDeleted      = "album.deleted"
+	EventAlbumRestored     = "album.restored"
+	EventAlbumTrackAdded   = "album.track_added"
+	EventAlbumTrackRemoved = "album.track_removed"
+)
+
+var WebhookEvents = []string{
+	EventTrackCreated, EventTrackUpdated, EventTrackDeleted, EventTrackRestored,
+	EventAlbumCreated, EventAlbumUpdated, EventAlbumDeleted, EventAlbumRestored,
+	EventAlbumTrackAdded, EventAlbumTrackRemoved,
+}
+
+// Webhook is an endpoint notified of the events it subscribed to. Secret
+// signs the deliveries; it is only shown when the webhook is created.
+type Webhook struct {
+	WebhookId string    `json:"id,omitempty" bson:"_id,omitempty"`
+	URL       string    `json:"url" bson:"url"`
+	Events    []string  `json:"events" bson:"events"`
+	Secret    string    `json:"secret,omitempty" bson:"secret"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// Delivery states.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event sent, or to be sent, to a webhook. Payload
+// is the exact body that is signed and posted.
+type WebhookDelivery struct {
+	DeliveryId     string     `json:"id,omitempty" bson:"_id,omitempty"`
+	WebhookId      string     `json:"webhook_id" bson:"webhook_id"`
+	Event          string     `json:"event" bson:"event"`
+	Payload        string     `json:"payload" bson:"payload"`
+	Status         string     `json:"status" bson:"status"`
+	Attempts       int        `json:"attempts" bson:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" bson:"next_attempt_at"`
+	LastError      string     `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	ResponseStatus int        `json:"response_status,omitempty" bson:"response_status,omitempty"`
+	ReplayOf       string     `json:"replay_of,omitempty" bson:"replay_of,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" bson:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty" bson:"delivered_at,omitempty"`
+}
@@ -0,0 +1,259 @@
+package playlistfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"musiclib/models"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Supported playlist file formats.
+const (
+	M3U8 = "m3u8"
+	PLS  = "pls"
+	XSPF = "xspf"
+)
+
+var ErrUnknownFormat = errors.New("unknown playlist format, use m3u8, pls or xspf")
+
+var contentTypes = map[string]string{
+	M3U8: "audio/x-mpegurl; charset=utf-8",
+	PLS:  "audio/x-scpls; charset=utf-8",
+	XSPF: "application/xspf+xml; charset=utf-8",
+}
+
+// ContentType returns the MIME type of format.
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// Encode writes entries as a playlist file named title.
+func Encode(w io.Writer, format string, title string, entries []models.PlaylistEntry) error {
+	switch format {
+	case M3U8:
+		return encodeM3U8(w, title, entries)
+	case PLS:
+		return encodePLS(w, entries)
+	case XSPF:
+		return encodeXSPF(w, title, entries)
+	}
+	return ErrUnknownFormat
+}
+
+// Decode reads the entries and the title, if the file has one, of a
+// playlist file.
+func Decode(r io.Reader, format string) (string, []models.PlaylistEntry, error) {
+	switch format {
+	case M3U8:
+		return decodeM3U8(r)
+	case PLS:
+		entries, err := decodePLS(r)
+		return "", entries, err
+	case XSPF:
+		return decodeXSPF(r)
+	}
+	return "", nil, ErrUnknownFormat
+}
+
+func displayName(entry *models.PlaylistEntry) string {
+	if entry.Artist == "" {
+		return entry.Title
+	}
+	return entry.Artist + " - " + entry.Title
+}
+
+// splitDisplayName undoes displayName.
+func splitDisplayName(name string, entry *models.PlaylistEntry) {
+	if artist, title, ok := strings.Cut(name, " - "); ok {
+		entry.Artist = strings.TrimSpace(artist)
+		entry.Title = strings.TrimSpace(title)
+		return
+	}
+	entry.Title = strings.TrimSpace(name)
+}
+
+func encodeM3U8(w io.Writer, title string, entries []models.PlaylistEntry) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, "#EXTM3U")
+	if title != "" {
+		fmt.Fprintf(b, "#PLAYLIST:%s\n", title)
+	}
+	for i := range entries {
+		duration := entries[i].DurationSeconds
+		if duration == 0 {
+			duration = -1
+		}
+		fmt.Fprintf(b, "#EXTINF:%d,%s\n%s\n", duration, displayName(&entries[i]), entries[i].Location)
+	}
+	return b.Flush()
+}
+
+func decodeM3U8(r io.Reader) (string, []models.PlaylistEntry, error) {
+	var title string
+	var entries []models.PlaylistEntry
+	var info *models.PlaylistEntry
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(strings.TrimPrefix(s.Text(), "\ufeff"))
+		switch {
+		case line == "" || line == "#EXTM3U":
+		case strings.HasPrefix(line, "#PLAYLIST:"):
+			title = strings.TrimSpace(strings.TrimPrefix(line, "#PLAYLIST:"))
+		case strings.HasPrefix(line, "#EXTINF:"):
+			info = &models.PlaylistEntry{Line: n}
+			length, name, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			if seconds, err := strconv.Atoi(strings.TrimSpace(length)); err == nil && seconds > 0 {
+				info.DurationSeconds = seconds
+			}
+			splitDisplayName(name, info)
+		case strings.HasPrefix(line, "#"):
+		default:
+			if info == nil {
+				info = &models.PlaylistEntry{Line: n}
+			}
+			info.Location = line
+			entries = append(entries, *info)
+			info = nil
+		}
+	}
+	return title, entries, s.Err()
+}
+
+func encodePLS(w io.Writer, entries []models.PlaylistEntry) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, "[playlist]")
+	for i := range entries {
+		n := i + 1
+		duration := entries[i].DurationSeconds
+		if duration == 0 {
+			duration = -1
+		}
+		fmt.Fprintf(b, "File%d=%s\nTitle%d=%s\nLength%d=%d\n", n, entries[i].Location, n, displayName(&entries[i]), n, duration)
+	}
+	fmt.Fprintf(b, "NumberOfEntries=%d\nVersion=2\n", len(entries))
+	return b.Flush()
+}
+
+func decodePLS(r io.Reader) ([]models.PlaylistEntry, error) {
+	byNumber := map[int]*models.PlaylistEntry{}
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		key, value, ok := strings.Cut(strings.TrimSpace(s.Text()), "=")
+		if !ok {
+			continue
+		}
+		var field string
+		for _, prefix := range []string{"File", "Title", "Length"} {
+			if strings.HasPrefix(key, prefix) {
+				field = prefix
+			}
+		}
+		number, err := strconv.Atoi(strings.TrimPrefix(key, field))
+		if field == "" || err != nil {
+			continue
+		}
+		entry, ok := byNumber[number]
+		if !ok {
+			entry = &models.PlaylistEntry{Line: n}
+			byNumber[number] = entry
+		}
+		switch field {
+		case "File":
+			entry.Location = value
+		case "Title":
+			splitDisplayName(value, entry)
+		case "Length":
+			if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+				entry.DurationSeconds = seconds
+			}
+		}
+	}
+	numbers := make([]int, 0, len(byNumber))
+	for number := range byNumber {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	entries := make([]models.PlaylistEntry, 0, len(numbers))
+	for _, number := range numbers {
+		entries = append(entries, *byNumber[number])
+	}
+	return entries, s.Err()
+}
+
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"http://xspf.org/ns/0/ playlist"`
+	Version string      `xml:"version,attr"`
+	Title   string      `xml:"title,omitempty"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location string `xml:"location"`
+	Title    string `xml:"title,omitempty"`
+	Creator  string `xml:"creator,omitempty"`
+	Duration int    `xml:"duration,omitempty"`
+}
+
+func encodeXSPF(w io.Writer, title string, entries []models.PlaylistEntry) error {
+	playlist := xspfPlaylist{Version: "1", Title: title}
+	for _, entry := range entries {
+		playlist.Tracks = append(playlist.Tracks, xspfTrack{
+			Location: entry.Location,
+			Title:    entry.Title,
+			Creator:  entry.Artist,
+			Duration: entry.DurationSeconds * 1000,
+		})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	return e.Encode(playlist)
+}
+
+func decodeXSPF(r io.Reader) (string, []models.PlaylistEntry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", nil, err
+	}
+	var playlist xspfPlaylist
+	if err := xml.Unmarshal(data, &playlist); err != nil {
+		return "", nil, err
+	}
+	// XSPF is not line based; Line points at the opening <track> tag.
+	lines := trackLines(data)
+	entries := make([]models.PlaylistEntry, 0, len(playlist.Tracks))
+	for i, track := range playlist.Tracks {
+		entry := models.PlaylistEntry{
+			Location:        strings.TrimSpace(track.Location),
+			Title:           strings.TrimSpace(track.Title),
+			Artist:          strings.TrimSpace(track.Creator),
+			DurationSeconds: track.Duration / 1000,
+		}
+		if i < len(lines) {
+			entry.Line = lines[i]
+		}
+		entries = append(entries, entry)
+	}
+	return playlist.Title, entries, nil
+}
+
+func trackLines(data []byte) []int {
+	var lines []int
+	line := 1
+	for i := 0; i < len(data); i++ {
+		if data[i] == '\n' {
+			line++
+		} else if bytes.HasPrefix(data[i:], []byte("<track>")) || bytes.HasPrefix(data[i:], []byte("<track ")) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
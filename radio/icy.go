@@ -0,0 +1,77 @@
+package radio
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// MetaInt is how many bytes of audio are sent between two ICY metadata
+// blocks, announced to the listener in the icy-metaint header.
+const MetaInt = 16000
+
+// maxTitle keeps a metadata block within the 255*16 bytes its length byte
+// can describe.
+const maxTitle = 4000
+
+// IcyWriter interleaves ICY metadata into the audio written to it: after
+// every MetaInt bytes comes a block with the title of the track playing,
+// or an empty block when it has not changed since the last one.
+type IcyWriter struct {
+	w         io.Writer
+	remaining int
+	title     string
+	sent      string
+	first     bool
+}
+
+func NewIcyWriter(w io.Writer) *IcyWriter {
+	return &IcyWriter{w: w, remaining: MetaInt, first: true}
+}
+
+// Write writes the audio of chunk, with the title of its track in the
+// metadata blocks that fall into it.
+func (i *IcyWriter) Write(chunk Chunk) error {
+	i.title = chunk.Title
+	data := chunk.Data
+	for len(data) > 0 {
+		n := len(data)
+		if n > i.remaining {
+			n = i.remaining
+		}
+		if _, err := i.w.Write(data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+		i.remaining -= n
+		if i.remaining == 0 {
+			if _, err := i.w.Write(i.metadata()); err != nil {
+				return err
+			}
+			i.remaining = MetaInt
+		}
+	}
+	return nil
+}
+
+// metadata is the next metadata block: a length byte, in units of 16
+// bytes, then StreamTitle padded with zeros.
+func (i *IcyWriter) metadata() []byte {
+	if !i.first && i.title == i.sent {
+		return []byte{0}
+	}
+	i.first = false
+	i.sent = i.title
+	title := i.title
+	if len(title) > maxTitle {
+		title = title[:maxTitle]
+	}
+	// A quote followed by a semicolon would end the title early.
+	text := "StreamTitle='" + strings.ReplaceAll(title, "';", "' ;") + "';"
+	blocks := (len(text) + 15) / 16
+	block := bytes.NewBuffer(make([]byte, 0, 1+blocks*16))
+	block.WriteByte(byte(blocks))
+	block.WriteString(text)
+	block.Write(make([]byte, blocks*16-len(text)))
+	return block.Bytes()
+}
@@ -0,0 +1,100 @@
+package radio
+
+import (
+	"bufio"
+	"io"
+	"time"
+)
+
+// Bitrates of MPEG Layer III in kbit/s, by bitrate index, for MPEG 1 and
+// for MPEG 2 and 2.5.
+var (
+	bitratesV1 = [15]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320}
+	bitratesV2 = [15]int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160}
+)
+
+// Sample rates in Hz by version bits (2.5, reserved, 2, 1) and index.
+var sampleRates = [4][3]int{
+	{11025, 12000, 8000},
+	{},
+	{22050, 24000, 16000},
+	{44100, 48000, 32000},
+}
+
+// frameReader reads the MPEG Layer III frames of an MP3 file. ID3 tags and
+// anything else that is not a frame are skipped, so tracks can be played
+// one after the other as a single stream.
+type frameReader struct {
+	r *bufio.Reader
+}
+
+func newFrameReader(r io.Reader) *frameReader {
+	return &frameReader{r: bufio.NewReaderSize(r, 8<<10)}
+}
+
+// parseHeader returns the length and the duration of the frame that
+// header starts, or 0 if it does not start one.
+func parseHeader(header []byte) (int, time.Duration) {
+	if header[0] != 0xFF || header[1]&0xE0 != 0xE0 {
+		return 0, 0
+	}
+	version := header[1] >> 3 & 3
+	layer := header[1] >> 1 & 3
+	bitrateIndex := header[2] >> 4
+	rateIndex := header[2] >> 2 & 3
+	padding := int(header[2] >> 1 & 1)
+	if version == 1 || layer != 1 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return 0, 0
+	}
+	sampleRate := sampleRates[version][rateIndex]
+	bitrate, samples := bitratesV1[bitrateIndex], 1152
+	if version != 3 {
+		bitrate, samples = bitratesV2[bitrateIndex], 576
+	}
+	length := samples/8*bitrate*1000/sampleRate + padding
+	return length, time.Duration(samples) * time.Second / time.Duration(sampleRate)
+}
+
+// next returns the next frame and how long it plays, or io.EOF at the end
+// of the file.
+func (f *frameReader) next() ([]byte, time.Duration, error) {
+	for {
+		header, err := f.r.Peek(10)
+		if len(header) < 4 {
+			if err == nil || err == io.ErrUnexpectedEOF {
+				err = io.EOF
+			}
+			return nil, 0, err
+		}
+		switch {
+		case len(header) == 10 && string(header[:3]) == "ID3":
+			// ID3v2: a 10-byte header, the tag, of a syncsafe size, and
+			// a 10-byte footer if flagged.
+			size := int(header[6])<<21 | int(header[7])<<14 | int(header[8])<<7 | int(header[9])
+			size += 10
+			if header[5]&0x10 != 0 {
+				size += 10
+			}
+			if _, err := f.r.Discard(size); err != nil {
+				return nil, 0, io.EOF
+			}
+			continue
+		case string(header[:3]) == "TAG":
+			// ID3v1, 128 bytes at the end of the file.
+			if _, err := f.r.Discard(128); err != nil {
+				return nil, 0, io.EOF
+			}
+			continue
+		}
+		length, duration := parseHeader(header)
+		if length == 0 {
+			f.r.Discard(1)
+			continue
+		}
+		frame := make([]byte, length)
+		if _, err := io.ReadFull(f.r, frame); err != nil {
+			return nil, 0, io.EOF
+		}
+		return frame, duration, nil
+	}
+}
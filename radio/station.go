@@ -0,0 +1,292 @@
+// Package radio plays albums and playlists as endless MP3 streams, the way
+// an Icecast or SHOUTcast station does. Each station has a single playhead:
+// its tracks are read once, at the pace they play, and every chunk is sent
+// to all of its listeners.
+package radio
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"musiclib/helper"
+	"musiclib/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// chunkDuration is how much audio is sent at once.
+	chunkDuration = 250 * time.Millisecond
+	// burstChunks are the chunks a new listener gets straight away, so its
+	// player can fill its buffer.
+	burstChunks = 8
+	// listenerBuffer is how many chunks a listener may fall behind before
+	// it is dropped.
+	listenerBuffer = 40
+	// maxLag is how far the playhead may fall behind the clock, after a
+	// slow disk for instance, before it skips ahead instead of catching up.
+	maxLag = time.Second
+)
+
+var ErrNoTracks = errors.New("station has no playable MP3 tracks")
+
+// Chunk is a piece of the stream, with the title of the track it is from.
+type Chunk struct {
+	Data  []byte
+	Title string
+}
+
+// Source returns the name of a station and the tracks it plays, in order.
+// It is called again every time the station has played them all, so edits
+// to the album or playlist are picked up.
+type Source func(ctx context.Context) (string, []models.Track, error)
+
+type StationInfo struct {
+	Key        string        `json:"key"`
+	Name       string        `json:"name"`
+	Listeners  int           `json:"listeners"`
+	NowPlaying *models.Track `json:"now_playing,omitempty"`
+	StartedAt  time.Time     `json:"started_at"`
+}
+
+type station struct {
+	key       string
+	name      string
+	source    Source
+	listeners map[*Listener]bool
+	recent    []Chunk
+	track     *models.Track
+	started   time.Time
+}
+
+// Listener receives the stream of a station on C, which is closed when the
+// station stops or the listener falls too far behind.
+type Listener struct {
+	C       <-chan Chunk
+	Name    string
+	c       chan Chunk
+	hub     *Hub
+	station *station
+}
+
+// Hub runs the stations that have listeners. A station starts with its
+// first listener and stops after its last one leaves.
+type Hub struct {
+	mu       sync.Mutex
+	stations map[string]*station
+}
+
+func NewHub() *Hub {
+	return &Hub{stations: map[string]*station{}}
+}
+
+// playable reports whether track can go on air.
+func playable(track *models.Track) bool {
+	return !track.Unavailable && strings.EqualFold(filepath.Ext(track.FileName), ".mp3")
+}
+
+func hasPlayable(tracks []models.Track) bool {
+	for i := range tracks {
+		if playable(&tracks[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Listen tunes in to the station key, starting it with the tracks of
+// source if it is not on air. ctx is only used to load those tracks; the
+// station outlives the listener that started it.
+func (h *Hub) Listen(ctx context.Context, key string, source Source) (*Listener, error) {
+	h.mu.Lock()
+	s, ok := h.stations[key]
+	if !ok {
+		h.mu.Unlock()
+		name, tracks, err := source(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if !hasPlayable(tracks) {
+			return nil, ErrNoTracks
+		}
+		h.mu.Lock()
+		if s, ok = h.stations[key]; !ok {
+			s = &station{
+				key:       key,
+				name:      name,
+				source:    source,
+				listeners: map[*Listener]bool{},
+				started:   time.Now(),
+			}
+			h.stations[key] = s
+			go h.run(s, tracks)
+		}
+	}
+	defer h.mu.Unlock()
+	c := make(chan Chunk, listenerBuffer)
+	for _, chunk := range s.recent {
+		c <- chunk
+	}
+	listener := &Listener{C: c, Name: s.name, c: c, hub: h, station: s}
+	s.listeners[listener] = true
+	return listener, nil
+}
+
+// Close tunes out.
+func (l *Listener) Close() {
+	l.hub.mu.Lock()
+	defer l.hub.mu.Unlock()
+	if l.station.listeners[l] {
+		delete(l.station.listeners, l)
+		close(l.c)
+	}
+}
+
+// Stations lists the stations on air.
+func (h *Hub) Stations() []StationInfo {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	stations := make([]StationInfo, 0, len(h.stations))
+	for _, s := range h.stations {
+		stations = append(stations, StationInfo{
+			Key:        s.key,
+			Name:       s.name,
+			Listeners:  len(s.listeners),
+			NowPlaying: s.track,
+			StartedAt:  s.started,
+		})
+	}
+	sort.Slice(stations, func(i, j int) bool { return stations[i].Key < stations[j].Key })
+	return stations
+}
+
+// broadcast sends chunk to the listeners of s and drops those too far
+// behind. It returns false, taking s off air, once s has no listeners.
+func (h *Hub) broadcast(s *station, chunk Chunk) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s.recent = append(s.recent, chunk)
+	if len(s.recent) > burstChunks {
+		s.recent = s.recent[len(s.recent)-burstChunks:]
+	}
+	for listener := range s.listeners {
+		select {
+		case listener.c <- chunk:
+		default:
+			delete(s.listeners, listener)
+			close(listener.c)
+		}
+	}
+	if len(s.listeners) == 0 {
+		delete(h.stations, s.key)
+		return false
+	}
+	return true
+}
+
+// stop takes s off air and disconnects its listeners.
+func (h *Hub) stop(s *station) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.stations[s.key] == s {
+		delete(h.stations, s.key)
+	}
+	for listener := range s.listeners {
+		delete(s.listeners, listener)
+		close(listener.c)
+	}
+}
+
+func (h *Hub) nowPlaying(s *station, track *models.Track) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s.track = track
+}
+
+// run plays tracks over and over, reloading them from the source after
+// every pass, until s has no listeners or nothing left to play.
+func (h *Hub) run(s *station, tracks []models.Track) {
+	clock := time.Now()
+	for {
+		played := false
+		for i := range tracks {
+			if !playable(&tracks[i]) {
+				continue
+			}
+			ok, onAir := h.play(s, &tracks[i], &clock)
+			if !onAir {
+				return
+			}
+			played = played || ok
+		}
+		if !played {
+			log.Println("err radio", s.key, ErrNoTracks)
+			h.stop(s)
+			return
+		}
+		var err error
+		if _, tracks, err = s.source(context.Background()); err != nil {
+			log.Println("err radio", s.key, err)
+			h.stop(s)
+			return
+		}
+	}
+}
+
+// play sends track to the listeners of s in chunks, each when clock says
+// it starts playing. It reports whether any of track was played and
+// whether s is still on air.
+func (h *Hub) play(s *station, track *models.Track, clock *time.Time) (bool, bool) {
+	// Only files in the library go on air, whatever the track says.
+	path, ok := helper.LibraryFile(track.FileName)
+	if !ok {
+		log.Println("err radio open", s.key, track.TrackId, "file is not in the library")
+		return false, true
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		log.Println("err radio open", s.key, track.TrackId, err)
+		return false, true
+	}
+	defer file.Close()
+	h.nowPlaying(s, track)
+	title := track.Title
+	if track.Artist != "" {
+		title = track.Artist + " - " + track.Title
+	}
+
+	frames := newFrameReader(file)
+	played := false
+	var data []byte
+	var duration time.Duration
+	for {
+		frame, frameDuration, err := frames.next()
+		if err == nil {
+			data = append(data, frame...)
+			duration += frameDuration
+		}
+		if duration >= chunkDuration || err != nil && len(data) > 0 {
+			if lag := time.Since(*clock); lag > maxLag {
+				*clock = time.Now()
+			}
+			time.Sleep(time.Until(*clock))
+			if !h.broadcast(s, Chunk{Data: data, Title: title}) {
+				return played, false
+			}
+			*clock = clock.Add(duration)
+			played = true
+			data, duration = nil, 0
+		}
+		if err != nil {
+			if err != io.EOF {
+				log.Println("err radio read", s.key, track.TrackId, err)
+			}
+			return played, true
+		}
+	}
+}
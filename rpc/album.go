@@ -0,0 +1,111 @@
+package rpc
+
+import (
+	"context"
+	"musiclib/catalogpb"
+	"musiclib/models"
+	"musiclib/services"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+)
+
+type albumServer struct {
+	catalogpb.UnimplementedAlbumServiceServer
+	albumService services.AlbumService
+}
+
+func (a *albumServer) GetAlbum(ctx context.Context, req *catalogpb.GetAlbumRequest) (*catalogpb.Album, error) {
+	id, err := parseId("id", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	album, err := a.albumService.FindAlbum(ctx, id)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toAlbum(album), nil
+}
+
+func (a *albumServer) ListAlbums(req *catalogpb.ListAlbumsRequest, stream grpc.ServerStreamingServer[catalogpb.Album]) error {
+	sort, ok := albumSorts[req.GetSort()]
+	if !ok {
+		sort = req.GetSort().String()
+	}
+	err := a.albumService.EachAlbum(stream.Context(), &sort, func(album *models.Album) error {
+		return stream.Send(toAlbum(album))
+	})
+	return statusError(err)
+}
+
+func (a *albumServer) CreateAlbum(ctx context.Context, req *catalogpb.CreateAlbumRequest) (*catalogpb.Album, error) {
+	body := albumDto(req.GetAlbum())
+	if err := validate("album", body); err != nil {
+		return nil, err
+	}
+	album := models.Album{Title: body.Title, AlbumCover: body.AlbumCover, Tracks: []models.Track{}}
+	if err := a.albumService.CreateAlbum(ctx, &album); err != nil {
+		return nil, statusError(err)
+	}
+	return toAlbum(&album), nil
+}
+
+func (a *albumServer) UpdateAlbum(ctx context.Context, req *catalogpb.UpdateAlbumRequest) (*catalogpb.Album, error) {
+	id, err := parseId("id", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	body := albumDto(req.GetAlbum())
+	if err := validate("album", body); err != nil {
+		return nil, err
+	}
+	album := models.Album{Title: body.Title, AlbumCover: body.AlbumCover}
+	if err := a.albumService.UpdateAlbum(ctx, id, &album, req.Version); err != nil {
+		return nil, statusError(err)
+	}
+	return a.changed(ctx, id)
+}
+
+func (a *albumServer) DeleteAlbum(ctx context.Context, req *catalogpb.DeleteAlbumRequest) (*catalogpb.DeleteAlbumResponse, error) {
+	id, err := parseId("id", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if err := a.albumService.DeleteAlbum(ctx, id, req.Version); err != nil {
+		return nil, statusError(err)
+	}
+	return &catalogpb.DeleteAlbumResponse{}, nil
+}
+
+func (a *albumServer) AddTrackToAlbum(ctx context.Context, req *catalogpb.AlbumTrackRequest) (*catalogpb.Album, error) {
+	return a.changeTracks(ctx, req, a.albumService.AddExistedTrackToAlbum)
+}
+
+func (a *albumServer) RemoveTrackFromAlbum(ctx context.Context, req *catalogpb.AlbumTrackRequest) (*catalogpb.Album, error) {
+	return a.changeTracks(ctx, req, a.albumService.RemoveTrackFromAlbum)
+}
+
+// changeTracks applies change to the album and track of req.
+func (a *albumServer) changeTracks(ctx context.Context, req *catalogpb.AlbumTrackRequest, change func(context.Context, *primitive.ObjectID, *primitive.ObjectID) error) (*catalogpb.Album, error) {
+	albumId, err := parseId("album_id", req.GetAlbumId())
+	if err != nil {
+		return nil, err
+	}
+	trackId, err := parseId("track_id", req.GetTrackId())
+	if err != nil {
+		return nil, err
+	}
+	if err := change(ctx, albumId, trackId); err != nil {
+		return nil, statusError(err)
+	}
+	return a.changed(ctx, albumId)
+}
+
+// changed returns the album id as it is after a change.
+func (a *albumServer) changed(ctx context.Context, id *primitive.ObjectID) (*catalogpb.Album, error) {
+	after, err := a.albumService.FindAlbum(ctx, id)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toAlbum(after), nil
+}
@@ -0,0 +1,78 @@
+package rpc
+
+import (
+	"musiclib/catalogpb"
+	"musiclib/dto"
+	"musiclib/models"
+	"musiclib/services"
+)
+
+var albumSorts = map[catalogpb.AlbumSort]string{
+	catalogpb.AlbumSort_ALBUM_SORT_UNSPECIFIED:  "",
+	catalogpb.AlbumSort_ALBUM_SORT_TITLE:        services.AlbumSortTitle,
+	catalogpb.AlbumSort_ALBUM_SORT_RATING:       services.AlbumSortRating,
+	catalogpb.AlbumSort_ALBUM_SORT_RATING_COUNT: services.AlbumSortRatingCount,
+}
+
+func toTrack(track *models.Track) *catalogpb.Track {
+	return &catalogpb.Track{
+		Id:              track.TrackId,
+		Title:           track.Title,
+		Artist:          track.Artist,
+		Genre:           track.Genre,
+		ReleaseYear:     track.ReleaseYear,
+		Duration:        track.Duration,
+		DurationSeconds: int32(track.DurationSeconds),
+		FileName:        track.FileName,
+		Unavailable:     track.Unavailable,
+		Version:         track.Version,
+	}
+}
+
+func toTracks(tracks []models.Track) []*catalogpb.Track {
+	result := make([]*catalogpb.Track, len(tracks))
+	for i := range tracks {
+		result[i] = toTrack(&tracks[i])
+	}
+	return result
+}
+
+func toAlbum(album *models.Album) *catalogpb.Album {
+	return &catalogpb.Album{
+		Id:            album.AlbumId,
+		Title:         album.Title,
+		Cover:         album.AlbumCover,
+		Tracks:        toTracks(album.Tracks),
+		RatingAverage: album.RatingAverage,
+		RatingCount:   int32(album.RatingCount),
+		Version:       album.Version,
+	}
+}
+
+func toAlbums(albums []models.Album) []*catalogpb.Album {
+	result := make([]*catalogpb.Album, len(albums))
+	for i := range albums {
+		result[i] = toAlbum(&albums[i])
+	}
+	return result
+}
+
+// trackDto is the REST body of input, so it is validated by the same
+// rules.
+func trackDto(input *catalogpb.TrackInput) *dto.TrackDto {
+	return &dto.TrackDto{
+		Title:       input.GetTitle(),
+		Artist:      input.GetArtist(),
+		Genre:       input.GetGenre(),
+		ReleaseYear: input.GetReleaseYear(),
+		Duration:    input.GetDuration(),
+		FileName:    input.GetFileName(),
+	}
+}
+
+func albumDto(input *catalogpb.AlbumInput) *dto.AlbumDto {
+	return &dto.AlbumDto{
+		Title:      input.GetTitle(),
+		AlbumCover: input.GetCover(),
+	}
+}
@@ -0,0 +1,21 @@
+package rpc
+
+import (
+	"context"
+	"musiclib/catalogpb"
+	"musiclib/services"
+)
+
+type searchServer struct {
+	catalogpb.UnimplementedSearchServiceServer
+	albumService services.AlbumService
+}
+
+func (s *searchServer) Search(ctx context.Context, req *catalogpb.SearchRequest) (*catalogpb.SearchResponse, error) {
+	keyword := req.GetKeyword()
+	albums, tracks, err := s.albumService.FindTracksAndAlbums(ctx, &keyword)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &catalogpb.SearchResponse{Albums: toAlbums(albums), Tracks: toTracks(tracks)}, nil
+}
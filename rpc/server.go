@@ -0,0 +1,201 @@
+// Package rpc serves the catalog over gRPC, with the services defined in
+// proto/catalog.proto. A few single-document reads are public; every other
+// call needs a token, sent in the authorization metadata as
+// "Bearer <token>".
+package rpc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"musiclib/catalogpb"
+	"musiclib/models"
+	"musiclib/services"
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Verifier checks a token and returns the user it identifies.
+type Verifier func(token string) (*models.User, error)
+
+// public are the methods that need no token. Anything else, including a
+// method added later, does.
+var public = map[string]bool{
+	catalogpb.TrackService_GetTrack_FullMethodName: true,
+	catalogpb.AlbumService_GetAlbum_FullMethodName: true,
+	catalogpb.SearchService_Search_FullMethodName:  true,
+}
+
+func NewServer(trackService services.TrackService, albumService services.AlbumService, verify Verifier) *grpc.Server {
+	server := grpc.NewServer(grpc.UnaryInterceptor(authenticate(verify)), grpc.StreamInterceptor(authenticateStream(verify)))
+	catalogpb.RegisterTrackServiceServer(server, &trackServer{
+		trackService: trackService,
+	})
+	catalogpb.RegisterAlbumServiceServer(server, &albumServer{
+		albumService: albumService,
+	})
+	catalogpb.RegisterSearchServiceServer(server, &searchServer{
+		albumService: albumService,
+	})
+	return server
+}
+
+// authenticate lets the calls to methods that are not public through only
+// with a valid token, and passes its user on to the services as the actor.
+func authenticate(verify Verifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if public[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		user, err := caller(ctx, verify)
+		if err != nil {
+			return nil, err
+		}
+		return handler(withActor(ctx, user), req)
+	}
+}
+
+// authenticateStream is authenticate for the streaming calls.
+func authenticateStream(verify Verifier) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if public[info.FullMethod] {
+			return handler(srv, stream)
+		}
+		user, err := caller(stream.Context(), verify)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &userStream{ServerStream: stream, ctx: withActor(stream.Context(), user)})
+	}
+}
+
+// caller is the user of the token in the authorization metadata.
+func caller(ctx context.Context, verify Verifier) (*models.User, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "authorization metadata is required")
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "authorization must be Bearer <token>")
+	}
+	user, err := verify(token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return user, nil
+}
+
+// withActor has the changes made in ctx recorded in the audit log as made
+// by user, from the address of the peer.
+func withActor(ctx context.Context, user *models.User) context.Context {
+	actor := services.Actor{UserId: user.UserId}
+	if p, ok := peer.FromContext(ctx); ok {
+		actor.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(actor.IP); err == nil {
+			actor.IP = host
+		}
+	}
+	return services.WithActor(ctx, actor)
+}
+
+// userStream is a server stream whose context carries the caller.
+type userStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *userStream) Context() context.Context {
+	return s.ctx
+}
+
+// statusError is the gRPC status of a service error, as ErrorHandler
+// picks the HTTP status of one.
+func statusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	var validationErr *services.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return invalidArgument(&errdetails.BadRequest_FieldViolation{Field: validationErr.Field, Description: validationErr.Message})
+	case errors.Is(err, services.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, services.ErrConflict):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, services.ErrPreconditionFailed):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, services.ErrForbidden):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, services.ErrUnavailable):
+		log.Println("grpc", errors.Unwrap(err))
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, services.ErrTimeout):
+		log.Println("grpc", err)
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, services.ErrCanceled):
+		return status.Error(codes.Canceled, err.Error())
+	}
+	log.Println("grpc", err)
+	return status.Error(codes.Internal, "internal error")
+}
+
+// invalidArgument is an InvalidArgument status listing the invalid
+// fields.
+func invalidArgument(violations ...*errdetails.BadRequest_FieldViolation) error {
+	st, err := status.New(codes.InvalidArgument, "validation failed").WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "validation failed")
+	}
+	return st.Err()
+}
+
+// protoFields are the proto names of the DTO fields named differently.
+var protoFields = map[string]string{
+	"music_title": "title",
+	"album_title": "title",
+	"album_cover": "cover",
+}
+
+// validate runs the binding tags of the DTO body, as the REST routes do
+// for a JSON body. The invalid fields are reported under the request
+// field message.
+func validate(message string, body interface{}) error {
+	err := binding.Validator.ValidateStruct(body)
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return statusError(err)
+	}
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		_, field, _ := strings.Cut(fe.Namespace(), ".")
+		if name, ok := protoFields[field]; ok {
+			field = name
+		}
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: message + "." + field, Description: "breaks the " + fe.Tag() + " rule"})
+	}
+	return invalidArgument(violations...)
+}
+
+// parseId parses the id in the request field name.
+func parseId(name string, value string) (*primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(value)
+	if err != nil {
+		return nil, invalidArgument(&errdetails.BadRequest_FieldViolation{Field: name, Description: "must be an id"})
+	}
+	return &id, nil
+}
@@ -0,0 +1,91 @@
+package rpc
+
+import (
+	"context"
+	"musiclib/catalogpb"
+	"musiclib/models"
+	"musiclib/services"
+
+	"google.golang.org/grpc"
+)
+
+type trackServer struct {
+	catalogpb.UnimplementedTrackServiceServer
+	trackService services.TrackService
+}
+
+func (t *trackServer) GetTrack(ctx context.Context, req *catalogpb.GetTrackRequest) (*catalogpb.Track, error) {
+	id, err := parseId("id", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	track, err := t.trackService.FindTrack(ctx, id)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toTrack(track), nil
+}
+
+func (t *trackServer) ListTracks(req *catalogpb.ListTracksRequest, stream grpc.ServerStreamingServer[catalogpb.Track]) error {
+	err := t.trackService.EachTrack(stream.Context(), func(track *models.Track) error {
+		return stream.Send(toTrack(track))
+	})
+	return statusError(err)
+}
+
+func (t *trackServer) CreateTrack(ctx context.Context, req *catalogpb.CreateTrackRequest) (*catalogpb.Track, error) {
+	body := trackDto(req.GetTrack())
+	if err := validate("track", body); err != nil {
+		return nil, err
+	}
+	track := models.Track{
+		Title:       body.Title,
+		Artist:      body.Artist,
+		Genre:       body.Genre,
+		ReleaseYear: body.ReleaseYear,
+		Duration:    body.Duration,
+		FileName:    body.FileName,
+	}
+	if err := t.trackService.CreateTrack(ctx, &track); err != nil {
+		return nil, statusError(err)
+	}
+	return toTrack(&track), nil
+}
+
+func (t *trackServer) UpdateTrack(ctx context.Context, req *catalogpb.UpdateTrackRequest) (*catalogpb.Track, error) {
+	id, err := parseId("id", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	body := trackDto(req.GetTrack())
+	if err := validate("track", body); err != nil {
+		return nil, err
+	}
+	track := models.Track{
+		Title:       body.Title,
+		Artist:      body.Artist,
+		Genre:       body.Genre,
+		ReleaseYear: body.ReleaseYear,
+		Duration:    body.Duration,
+		FileName:    body.FileName,
+	}
+	if err := t.trackService.UpdateTrack(ctx, id, &track, req.Version); err != nil {
+		return nil, statusError(err)
+	}
+	after, err := t.trackService.FindTrack(ctx, id)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return toTrack(after), nil
+}
+
+func (t *trackServer) DeleteTrack(ctx context.Context, req *catalogpb.DeleteTrackRequest) (*catalogpb.DeleteTrackResponse, error) {
+	id, err := parseId("id", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if err := t.trackService.DeleteTrack(ctx, id, req.Version); err != nil {
+		return nil, statusError(err)
+	}
+	return &catalogpb.DeleteTrackResponse{}, nil
+}
@@ -0,0 +1,232 @@
+package scanner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"musiclib/models"
+	"musiclib/services"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dhowden/tag"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AudioExtensions are the file types the scanner imports.
+var AudioExtensions = map[string]bool{
+	".mp3":  true,
+	".flac": true,
+	".m4a":  true,
+	".ogg":  true,
+}
+
+// Result is what happened to a single file.
+type Result int
+
+const (
+	Added Result = iota
+	Updated
+	Skipped
+	Failed
+)
+
+type Summary struct {
+	Added    int
+	Updated  int
+	Skipped  int
+	Failed   int
+	Failures []string
+}
+
+func (s *Summary) record(path string, result Result, err error) {
+	switch result {
+	case Added:
+		s.Added++
+	case Updated:
+		s.Updated++
+	case Skipped:
+		s.Skipped++
+	case Failed:
+		s.Failed++
+		s.Failures = append(s.Failures, fmt.Sprintf("%s: %v", path, err))
+	}
+}
+
+func (s *Summary) String() string {
+	return fmt.Sprintf("added %d, updated %d, skipped %d, failed %d", s.Added, s.Updated, s.Skipped, s.Failed)
+}
+
+// Scanner imports audio files into the library. A file is identified by its
+// path and content hash, so scanning the same tree again changes nothing.
+type Scanner struct {
+	trackService services.TrackService
+	albumService services.AlbumService
+}
+
+func NewScanner(trackService services.TrackService, albumService services.AlbumService) *Scanner {
+	return &Scanner{
+		trackService: trackService,
+		albumService: albumService,
+	}
+}
+
+// Scan walks dir and imports every audio file below it. It stops early,
+// with the error of ctx, once ctx is done.
+func (s *Scanner) Scan(ctx context.Context, dir string) (*Summary, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	summary := &Summary{}
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			summary.record(path, Failed, err)
+			return nil
+		}
+		if d.IsDir() || !AudioExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		result, err := s.ImportFile(ctx, path)
+		summary.record(path, result, err)
+		return nil
+	})
+	return summary, err
+}
+
+// ImportFile creates or updates the track stored at path and files it
+// under the album named in its tags.
+func (s *Scanner) ImportFile(ctx context.Context, path string) (Result, error) {
+	hash, err := hashFile(path)
+	if err != nil {
+		return Failed, err
+	}
+	track, album, err := readTags(path)
+	if err != nil {
+		return Failed, err
+	}
+	track.FileName = path
+	track.FileHash = hash
+
+	existing, err := s.trackService.FindTrackByFile(ctx, &path, &hash)
+	if err != nil && !errors.Is(err, services.ErrNotFound) {
+		return Failed, err
+	}
+
+	result := Added
+	switch {
+	case existing == nil:
+		if err := s.trackService.CreateTrack(ctx, track); err != nil {
+			return Failed, err
+		}
+	case existing.FileName == path && existing.FileHash == hash:
+		track = existing
+		result = Skipped
+	default:
+		id, err := primitive.ObjectIDFromHex(existing.TrackId)
+		if err != nil {
+			return Failed, err
+		}
+		if err := s.trackService.UpdateTrack(ctx, &id, track, nil); err != nil {
+			return Failed, err
+		}
+		track.TrackId = existing.TrackId
+		result = Updated
+	}
+
+	if existing != nil && existing.Unavailable {
+		if err := s.trackService.MarkFileAvailable(ctx, &path); err != nil {
+			return Failed, err
+		}
+		if result == Skipped {
+			result = Updated
+		}
+	}
+	if album != "" {
+		if err := s.fileUnderAlbum(ctx, track, album); err != nil {
+			return Failed, err
+		}
+	}
+	return result, nil
+}
+
+// fileUnderAlbum adds track to the album titled title, creating the album
+// when needed.
+func (s *Scanner) fileUnderAlbum(ctx context.Context, track *models.Track, title string) error {
+	album, err := s.albumService.FindAlbumByTitle(ctx, &title)
+	if errors.Is(err, services.ErrNotFound) {
+		album = &models.Album{Title: title, Tracks: []models.Track{}}
+		err = s.albumService.CreateAlbum(ctx, album)
+	}
+	if err != nil {
+		return err
+	}
+	for _, t := range album.Tracks {
+		if t.TrackId == track.TrackId {
+			return nil
+		}
+	}
+	albumId, err := primitive.ObjectIDFromHex(album.AlbumId)
+	if err != nil {
+		return err
+	}
+	trackId, err := primitive.ObjectIDFromHex(track.TrackId)
+	if err != nil {
+		return err
+	}
+	return s.albumService.AddExistedTrackToAlbum(ctx, &albumId, &trackId)
+}
+
+// readTags builds a track from the tags of path and returns the album it
+// belongs to. Files without tags are named after the file.
+func readTags(path string) (*models.Track, string, error) {
+	track := &models.Track{
+		Title: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	metadata, err := tag.ReadFrom(f)
+	if errors.Is(err, tag.ErrNoTagsFound) {
+		return track, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if metadata.Title() != "" {
+		track.Title = metadata.Title()
+	}
+	track.Artist = metadata.Artist()
+	if track.Artist == "" {
+		track.Artist = metadata.AlbumArtist()
+	}
+	track.Genre = metadata.Genre()
+	if metadata.Year() > 0 {
+		track.ReleaseYear = strconv.Itoa(metadata.Year())
+	}
+	return track, metadata.Album(), nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
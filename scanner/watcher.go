@@ -0,0 +1,143 @@
+package scanner
+
+import (
+	"context"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher keeps the library in sync with directories on disk. Events are
+// debounced per path; when a path settles its current state on disk is
+// imported, or its tracks are flagged unavailable if it is gone.
+type Watcher struct {
+	scanner  *Scanner
+	debounce time.Duration
+	fsw      *fsnotify.Watcher
+	mu       sync.Mutex
+	pending  map[string]*time.Timer
+}
+
+func NewWatcher(scanner *Scanner, debounce time.Duration) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return &Watcher{
+		scanner:  scanner,
+		debounce: debounce,
+		fsw:      fsw,
+		pending:  map[string]*time.Timer{},
+	}, nil
+}
+
+// Watch watches roots and their subdirectories until the watcher is closed.
+func (w *Watcher) Watch(roots []string) error {
+	for _, root := range roots {
+		root, err := filepath.Abs(root)
+		if err != nil {
+			return err
+		}
+		if err := w.addTree(root); err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+				continue
+			}
+			w.schedule(event.Name)
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return nil
+			}
+			log.Println("library watcher:", err)
+		}
+	}
+}
+
+func (w *Watcher) Close() error {
+	return w.fsw.Close()
+}
+
+// schedule (re)starts the debounce timer of path.
+func (w *Watcher) schedule(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if timer, ok := w.pending[path]; ok {
+		timer.Reset(w.debounce)
+		return
+	}
+	w.pending[path] = time.AfterFunc(w.debounce, func() {
+		w.mu.Lock()
+		delete(w.pending, path)
+		w.mu.Unlock()
+		w.apply(path)
+	})
+}
+
+// apply brings the library in line with what is at path now.
+func (w *Watcher) apply(path string) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		n, err := w.scanner.trackService.MarkFilesUnavailable(context.Background(), &path)
+		if err != nil {
+			log.Println("library watcher:", path, err)
+		} else if n > 0 {
+			log.Printf("library watcher: %s removed, %d tracks unavailable", path, n)
+		}
+		return
+	}
+	if err != nil {
+		log.Println("library watcher:", path, err)
+		return
+	}
+	if info.IsDir() {
+		if err := w.addTree(path); err != nil {
+			log.Println("library watcher:", path, err)
+		}
+		summary, err := w.scanner.Scan(context.Background(), path)
+		if err != nil {
+			log.Println("library watcher:", path, err)
+			return
+		}
+		log.Printf("library watcher: %s scanned, %s", path, summary)
+		return
+	}
+	if !AudioExtensions[strings.ToLower(filepath.Ext(path))] {
+		return
+	}
+	result, err := w.scanner.ImportFile(context.Background(), path)
+	switch result {
+	case Added:
+		log.Println("library watcher: added", path)
+	case Updated:
+		log.Println("library watcher: updated", path)
+	case Failed:
+		log.Println("library watcher:", path, err)
+	}
+}
+
+// addTree watches dir and every directory below it.
+func (w *Watcher) addTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return w.fsw.Add(path)
+		}
+		return nil
+	})
+}
@@ -1,20 +1,36 @@
 package services
 
 import (
+	"context"
 	"musiclib/models"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Sort keys accepted by AlbumService.GetAlbums.
+const (
+	AlbumSortTitle       = "title"
+	AlbumSortRating      = "rating"
+	AlbumSortRatingCount = "rating_count"
+)
+
 //go:generate go-mockgen-tool --type AlbumService
 type AlbumService interface {
-	CreateAlbum(*models.Album) error
-	GetAlbums() ([]models.Album, error)
-	FindAlbum(*primitive.ObjectID) (*models.Album, error)
-	UpdateAlbum(*primitive.ObjectID, *models.Album) error
-	DeleteAlbum(*primitive.ObjectID) error
-	FindTracksAndAlbums(*string) ([]models.Album, []models.Track, error)
-	AddTrackToAlbum(*primitive.ObjectID, *models.Track) error
-	AddExistedTrackToAlbum(*primitive.ObjectID, *primitive.ObjectID) error
-	RemoveTrackFromAlbum(*primitive.ObjectID, *primitive.ObjectID) error
+	CreateAlbum(context.Context, *models.Album) error
+	GetAlbums(context.Context, *string) ([]models.Album, error)
+	EachAlbum(context.Context, *string, func(*models.Album) error) error
+	FindAlbum(context.Context, *primitive.ObjectID) (*models.Album, error)
+	FindAlbumByTitle(context.Context, *string) (*models.Album, error)
+	FindAlbumsByTracks(context.Context, []string) ([]models.Album, error)
+	UpdateAlbum(context.Context, *primitive.ObjectID, *models.Album, *int64) error
+	PatchAlbum(context.Context, *primitive.ObjectID, *models.Album, []string, *int64) error
+	DeleteAlbum(context.Context, *primitive.ObjectID, *int64) error
+	GetDeletedAlbums(context.Context) ([]models.Album, error)
+	RestoreAlbum(context.Context, *primitive.ObjectID) error
+	PurgeAlbums(context.Context, time.Time) (int64, error)
+	FindTracksAndAlbums(context.Context, *string) ([]models.Album, []models.Track, error)
+	AddTrackToAlbum(context.Context, *primitive.ObjectID, *models.Track) error
+	AddExistedTrackToAlbum(context.Context, *primitive.ObjectID, *primitive.ObjectID) error
+	RemoveTrackFromAlbum(context.Context, *primitive.ObjectID, *primitive.ObjectID) error
 }
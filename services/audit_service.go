@@ -0,0 +1,33 @@
+package services
+
+import (
+	"context"
+	"musiclib/models"
+)
+
+// AuditService keeps the append-only audit log. Entries are never changed
+// or removed.
+type AuditService interface {
+	Record(context.Context, *models.AuditEntry) error
+	FindEntries(context.Context, *models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+// Actor is who a change is made for, as recorded in the audit log. It is
+// empty for the changes the server makes on its own, such as a scan.
+type Actor struct {
+	UserId string
+	IP     string
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx whose changes are made by actor.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorOf returns the actor set on ctx by WithActor, if any.
+func ActorOf(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	return actor
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"context"
+	"musiclib/models"
+)
+
+// Chart kinds and windows accepted by ChartService.GetChart.
+const (
+	ChartTracks  = "tracks"
+	ChartAlbums  = "albums"
+	ChartArtists = "artists"
+
+	ChartDay     = "day"
+	ChartWeek    = "week"
+	ChartMonth   = "month"
+	ChartAllTime = "all-time"
+)
+
+var (
+	ChartKinds   = []string{ChartTracks, ChartAlbums, ChartArtists}
+	ChartWindows = []string{ChartDay, ChartWeek, ChartMonth, ChartAllTime}
+)
+
+type ChartService interface {
+	ComputeCharts(context.Context) error
+	GetChart(context.Context, *string, *string) (*models.Chart, error)
+}
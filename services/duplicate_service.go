@@ -0,0 +1,13 @@
+package services
+
+import (
+	"context"
+	"musiclib/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DuplicateService interface {
+	FindDuplicateTracks(context.Context) ([]models.DuplicateCluster, error)
+	MergeTracks(context.Context, *primitive.ObjectID, []primitive.ObjectID) error
+}
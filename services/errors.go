@@ -0,0 +1,69 @@
+package services
+
+import "errors"
+
+// Kinds of service failures. Check them with errors.Is; the controllers
+// turn them into HTTP statuses.
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrConflict    = errors.New("conflict")
+	ErrForbidden   = errors.New("forbidden")
+	ErrUnavailable = errors.New("unavailable")
+	// ErrTimeout is returned when an operation took longer than its
+	// timeout, and ErrCanceled when its caller gave up on it.
+	ErrTimeout  = errors.New("timeout")
+	ErrCanceled = errors.New("canceled")
+	// ErrPreconditionFailed is returned when a write was conditional on a
+	// version of the document that is no longer current.
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// Error is a service failure of a known Kind. Err is the underlying cause,
+// if any, so errors.Is still matches driver errors such as
+// mongo.ErrNoDocuments.
+type Error struct {
+	Kind    error
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func NotFound(message string) error {
+	return &Error{Kind: ErrNotFound, Message: message}
+}
+
+func Conflict(message string) error {
+	return &Error{Kind: ErrConflict, Message: message}
+}
+
+func Forbidden(message string) error {
+	return &Error{Kind: ErrForbidden, Message: message}
+}
+
+// ValidationError reports input that a service refused to act on.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// ErrVersionConflict is returned when a document was changed since the
+// version the caller based its update on. It is an ErrConflict.
+var ErrVersionConflict error = &Error{Kind: ErrConflict, Message: "version conflict"}
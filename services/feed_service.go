@@ -0,0 +1,21 @@
+package services
+
+import (
+	"context"
+	"musiclib/models"
+)
+
+// FeedService streams the changes to tracks and albums made by any server
+// instance.
+type FeedService interface {
+	// Watch opens a stream of the changes made from now on, or right after
+	// the change of token when token is not empty. It fails with
+	// ErrUnavailable when too many streams are resuming.
+	Watch(ctx context.Context, token string) (ChangeStream, error)
+}
+
+type ChangeStream interface {
+	// Next blocks until the next change. It fails when ctx is done.
+	Next(ctx context.Context) (*models.Change, error)
+	Close(ctx context.Context) error
+}
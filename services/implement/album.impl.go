@@ -2,174 +2,487 @@ package implements
 
 import (
 	"context"
+	"log"
+	"musiclib/helper"
 	"musiclib/models"
 	"musiclib/services"
+	"slices"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type AlbumImpl struct {
 	albumCollection *mongo.Collection
 	trackCollection *mongo.Collection
-	ctx             context.Context
+	events          services.EventPublisher
+	audit           services.AuditService
 }
 
-func NewAlbumService(albumCollection *mongo.Collection, trackCollection *mongo.Collection, ctx context.Context) services.AlbumService {
+func NewAlbumService(albumCollection *mongo.Collection, trackCollection *mongo.Collection, events services.EventPublisher, audit services.AuditService) services.AlbumService {
 	return &AlbumImpl{
 		albumCollection: albumCollection,
 		trackCollection: trackCollection,
-		ctx:             ctx,
+		events:          events,
+		audit:           audit,
 	}
 }
 
-func (a *AlbumImpl) CreateAlbum(album *models.Album) error {
-	_, err := a.albumCollection.InsertOne(a.ctx, album)
-	return err
+// publishAlbum announces event with the current state of the album, and
+// records action on it in the audit log, from before. The change is
+// already made, so it is announced even if ctx is canceled.
+func (a *AlbumImpl) publishAlbum(ctx context.Context, event string, action string, albumId *primitive.ObjectID, before *models.Album) {
+	album, err := a.FindAlbum(context.WithoutCancel(ctx), albumId)
+	if err != nil {
+		log.Println("err publish", event, err)
+		return
+	}
+	a.events.Publish(event, album)
+	a.recordAlbum(ctx, action, albumId, before, album)
+}
+
+// auditAlbum records action on the album in the audit log, from before to
+// its current state.
+func (a *AlbumImpl) auditAlbum(ctx context.Context, action string, albumId *primitive.ObjectID, before *models.Album) {
+	album, err := a.FindAlbum(context.WithoutCancel(ctx), albumId)
+	if err != nil {
+		log.Println("err record audit entry", action, "album", albumId.Hex(), err)
+		return
+	}
+	a.recordAlbum(ctx, action, albumId, before, album)
+}
+
+// recordAlbum records action on the album in the audit log. The tracks in
+// the trash are left out of before, as they are out of every album read.
+func (a *AlbumImpl) recordAlbum(ctx context.Context, action string, albumId *primitive.ObjectID, before *models.Album, after *models.Album) {
+	if before != nil {
+		if err := a.hideDeletedTracks(context.WithoutCancel(ctx), before); err != nil {
+			log.Println("err record audit entry", action, "album", albumId.Hex(), err)
+			return
+		}
+	}
+	recordAudit(ctx, a.audit, action, "album", albumId.Hex(), before, after)
+}
+
+// hideDeletedTracks leaves out of the albums the tracks that are in the
+// trash or purged, looking them all up in one query. The albums keep their
+// copy, so a restored track is back in its albums.
+func (a *AlbumImpl) hideDeletedTracks(ctx context.Context, albums ...*models.Album) error {
+	ids := []primitive.ObjectID{}
+	for _, album := range albums {
+		for _, track := range album.Tracks {
+			if id, err := primitive.ObjectIDFromHex(track.TrackId); err == nil {
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	cursor, err := a.trackCollection.Find(ctx, live(bson.M{"_id": bson.M{"$in": ids}}), opts)
+	if err != nil {
+		return storeError(err, "track")
+	}
+	var found []struct {
+		Id primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &found); err != nil {
+		return storeError(err, "track")
+	}
+	alive := make(map[string]bool, len(found))
+	for _, track := range found {
+		alive[track.Id.Hex()] = true
+	}
+	for _, album := range albums {
+		album.Tracks = slices.DeleteFunc(album.Tracks, func(track models.Track) bool {
+			return !alive[track.TrackId]
+		})
+	}
+	return nil
+}
+
+// hideDeletedTracksOf is hideDeletedTracks for a slice of albums.
+func (a *AlbumImpl) hideDeletedTracksOf(ctx context.Context, albums []models.Album) error {
+	pointers := make([]*models.Album, len(albums))
+	for i := range albums {
+		pointers[i] = &albums[i]
+	}
+	return a.hideDeletedTracks(ctx, pointers...)
+}
+
+// publishTrackAdded announces that track was added to the album.
+func (a *AlbumImpl) publishTrackAdded(albumId *primitive.ObjectID, track *models.Track) {
+	a.events.Publish(models.EventAlbumTrackAdded, map[string]interface{}{"album_id": albumId.Hex(), "track": track})
+}
+
+func (a *AlbumImpl) CreateAlbum(ctx context.Context, album *models.Album) error {
+	ctx, cancel := operation(ctx, "album.CreateAlbum", write)
+	defer cancel()
+	// rating aggregates are maintained by the review service only
+	album.RatingAverage, album.RatingCount = 0, 0
+	result, err := a.albumCollection.InsertOne(ctx, album)
+	if err != nil {
+		return storeError(err, "album")
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		album.AlbumId = id.Hex()
+	}
+	a.events.Publish(models.EventAlbumCreated, album)
+	recordAudit(ctx, a.audit, models.AuditCreate, "album", album.AlbumId, nil, album)
+	return nil
+}
+
+// findAlbums finds the live albums in the order of the sort key.
+func (a *AlbumImpl) findAlbums(ctx context.Context, sort *string) (*mongo.Cursor, error) {
+	opts := options.Find()
+	switch *sort {
+	case "":
+	case services.AlbumSortTitle:
+		opts.SetSort(bson.D{{Key: "album_title", Value: 1}})
+	case services.AlbumSortRating:
+		opts.SetSort(bson.D{{Key: "rating_average", Value: -1}, {Key: "rating_count", Value: -1}})
+	case services.AlbumSortRatingCount:
+		opts.SetSort(bson.D{{Key: "rating_count", Value: -1}, {Key: "rating_average", Value: -1}})
+	default:
+		return nil, &services.ValidationError{Field: "sort", Message: "unsupported sort key"}
+	}
+	cursor, err := a.albumCollection.Find(ctx, live(bson.M{}), opts)
+	return cursor, storeError(err, "album")
 }
-func (a *AlbumImpl) GetAlbums() ([]models.Album, error) {
+
+func (a *AlbumImpl) GetAlbums(ctx context.Context, sort *string) ([]models.Album, error) {
+	ctx, cancel := operation(ctx, "album.GetAlbums", read)
+	defer cancel()
 	var albums []models.Album
-	cursor, err := a.albumCollection.Find(a.ctx, bson.M{})
+	cursor, err := a.findAlbums(ctx, sort)
 	if err != nil {
 		return nil, err
 	}
-	if err = cursor.All(a.ctx, &albums); err != nil {
+	if err = cursor.All(ctx, &albums); err != nil {
+		return nil, storeError(err, "album")
+	}
+	if err := a.hideDeletedTracksOf(ctx, albums); err != nil {
 		return nil, err
 	}
 	return albums, nil
 }
-func (a *AlbumImpl) UpdateAlbum(albumId *primitive.ObjectID, album *models.Album) error {
-	filter := bson.M{"_id": albumId}
-	update := bson.M{"$set": album}
-	_, err := a.albumCollection.UpdateOne(a.ctx, filter, update)
-	return err
+
+// EachAlbum calls fn with every album in the order of the sort key,
+// reading them one at a time, until fn fails.
+func (a *AlbumImpl) EachAlbum(ctx context.Context, sort *string, fn func(*models.Album) error) error {
+	ctx, cancel := operation(ctx, "album.EachAlbum", bulk)
+	defer cancel()
+	cursor, err := a.findAlbums(ctx, sort)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var album models.Album
+		if err := cursor.Decode(&album); err != nil {
+			return storeError(err, "album")
+		}
+		if err := a.hideDeletedTracks(ctx, &album); err != nil {
+			return err
+		}
+		if err := fn(&album); err != nil {
+			return err
+		}
+	}
+	return storeError(cursor.Err(), "album")
 }
-func (a *AlbumImpl) DeleteAlbum(albumId *primitive.ObjectID) error {
-	_, err := a.albumCollection.DeleteOne(a.ctx, albumId)
-	return err
+
+// UpdateAlbum changes the title and cover of an album. Tracks and ratings
+// are kept. When version is set the album must still be at that version.
+func (a *AlbumImpl) UpdateAlbum(ctx context.Context, albumId *primitive.ObjectID, album *models.Album, version *int64) error {
+	ctx, cancel := operation(ctx, "album.UpdateAlbum", write)
+	defer cancel()
+	filter := withVersion(live(bson.M{"_id": albumId}), version)
+	update := bson.M{
+		"$set":         bson.M{"album_title": album.Title, "album_cover": album.AlbumCover},
+		"$inc":         bumpVersion,
+		"$currentDate": touched,
+	}
+	var before models.Album
+	if err := updateVersioned(ctx, a.albumCollection, filter, update, albumId, "album", &before); err != nil {
+		return err
+	}
+	a.publishAlbum(ctx, models.EventAlbumUpdated, models.AuditUpdate, albumId, &before)
+	return nil
 }
-func (a *AlbumImpl) FindAlbum(albumId *primitive.ObjectID) (*models.Album, error) {
+
+// PatchAlbum changes only the given fields of an album, named by their bson
+// keys, to their value in album. When version is set the album must still
+// be at that version.
+func (a *AlbumImpl) PatchAlbum(ctx context.Context, albumId *primitive.ObjectID, album *models.Album, fields []string, version *int64) error {
+	ctx, cancel := operation(ctx, "album.PatchAlbum", write)
+	defer cancel()
+	set, err := patchFields(album, fields)
+	if err != nil {
+		return err
+	}
+	filter := withVersion(live(bson.M{"_id": albumId}), version)
+	var before models.Album
+	update := bson.M{"$set": set, "$inc": bumpVersion, "$currentDate": touched}
+	if err := updateVersioned(ctx, a.albumCollection, filter, update, albumId, "album", &before); err != nil {
+		return err
+	}
+	a.publishAlbum(ctx, models.EventAlbumUpdated, models.AuditUpdate, albumId, &before)
+	return nil
+}
+
+// DeleteAlbum moves an album to the trash. When version is set the album
+// must still be at that version.
+func (a *AlbumImpl) DeleteAlbum(ctx context.Context, albumId *primitive.ObjectID, version *int64) error {
+	ctx, cancel := operation(ctx, "album.DeleteAlbum", write)
+	defer cancel()
+	filter := withVersion(live(bson.M{"_id": albumId}), version)
+	var before models.Album
+	if err := updateVersioned(ctx, a.albumCollection, filter, moveToTrash(), albumId, "album", &before); err != nil {
+		return err
+	}
+	a.events.Publish(models.EventAlbumDeleted, map[string]string{"id": albumId.Hex()})
+	a.recordAlbum(ctx, models.AuditDelete, albumId, &before, nil)
+	return nil
+}
+
+// GetDeletedAlbums lists the albums in the trash, most recently deleted
+// first.
+func (a *AlbumImpl) GetDeletedAlbums(ctx context.Context) ([]models.Album, error) {
+	ctx, cancel := operation(ctx, "album.GetDeletedAlbums", read)
+	defer cancel()
+	albums := []models.Album{}
+	if err := findTrash(ctx, a.albumCollection, &albums); err != nil {
+		return nil, storeError(err, "album")
+	}
+	return albums, nil
+}
+
+// RestoreAlbum takes an album out of the trash.
+func (a *AlbumImpl) RestoreAlbum(ctx context.Context, albumId *primitive.ObjectID) error {
+	ctx, cancel := operation(ctx, "album.RestoreAlbum", write)
+	defer cancel()
+	result, err := a.albumCollection.UpdateOne(ctx, trashed(bson.M{"_id": albumId}), restoreFromTrash)
+	if err != nil {
+		return storeError(err, "album")
+	}
+	if result.MatchedCount == 0 {
+		return services.NotFound("album not in trash")
+	}
+	a.publishAlbum(ctx, models.EventAlbumRestored, models.AuditRestore, albumId, nil)
+	return nil
+}
+
+// PurgeAlbums permanently removes the albums moved to the trash before
+// before.
+func (a *AlbumImpl) PurgeAlbums(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := operation(ctx, "album.PurgeAlbums", bulk)
+	defer cancel()
+	count, err := purgeTrash(ctx, a.albumCollection, before)
+	return count, storeError(err, "album")
+}
+
+func (a *AlbumImpl) FindAlbum(ctx context.Context, albumId *primitive.ObjectID) (*models.Album, error) {
+	ctx, cancel := operation(ctx, "album.FindAlbum", read)
+	defer cancel()
 	var album *models.Album
-	filter := bson.M{"_id": albumId}
-	err := a.albumCollection.FindOne(a.ctx, filter).Decode(&album)
-	return album, err
+	filter := live(bson.M{"_id": albumId})
+	if err := a.albumCollection.FindOne(ctx, filter).Decode(&album); err != nil {
+		return nil, storeError(err, "album")
+	}
+	return album, a.hideDeletedTracks(ctx, album)
+}
+func (a *AlbumImpl) FindAlbumByTitle(ctx context.Context, title *string) (*models.Album, error) {
+	ctx, cancel := operation(ctx, "album.FindAlbumByTitle", read)
+	defer cancel()
+	var album *models.Album
+	filter := live(bson.M{"album_title": title})
+	if err := a.albumCollection.FindOne(ctx, filter).Decode(&album); err != nil {
+		return nil, storeError(err, "album")
+	}
+	return album, a.hideDeletedTracks(ctx, album)
 }
-func (a *AlbumImpl) AddTrackToAlbum(albumId *primitive.ObjectID, track *models.Track) error {
+
+// FindAlbumsByTracks lists the albums holding any of the given tracks, in
+// one query.
+func (a *AlbumImpl) FindAlbumsByTracks(ctx context.Context, trackIds []string) ([]models.Album, error) {
+	ctx, cancel := operation(ctx, "album.FindAlbumsByTracks", read)
+	defer cancel()
+	albums := []models.Album{}
+	cursor, err := a.albumCollection.Find(ctx, live(bson.M{"tracks._id": bson.M{"$in": trackIds}}))
+	if err != nil {
+		return nil, storeError(err, "album")
+	}
+	if err = cursor.All(ctx, &albums); err != nil {
+		return nil, storeError(err, "album")
+	}
+	if err := a.hideDeletedTracksOf(ctx, albums); err != nil {
+		return nil, err
+	}
+	return albums, nil
+}
+func (a *AlbumImpl) AddTrackToAlbum(ctx context.Context, albumId *primitive.ObjectID, track *models.Track) error {
+	ctx, cancel := operation(ctx, "album.AddTrackToAlbum", write)
+	defer cancel()
 	var album models.Album
-	err := a.albumCollection.FindOne(a.ctx, bson.M{"_id": albumId}).Decode(&album)
-	if err != nil && err != mongo.ErrNoDocuments {
-		return err
+	err := a.albumCollection.FindOne(ctx, live(bson.M{"_id": albumId})).Decode(&album)
+	if err != nil {
+		return storeError(err, "album")
 	}
 
 	if track.TrackId == "" {
-		result, err := a.trackCollection.InsertOne(a.ctx, track)
+		track.DurationSeconds, _ = helper.ParseDuration(track.Duration)
+		result, err := a.trackCollection.InsertOne(ctx, track)
 		if err != nil {
-			return err
+			return storeError(err, "track")
 		}
 		track.TrackId = result.InsertedID.(primitive.ObjectID).Hex()
+		a.events.Publish(models.EventTrackCreated, track)
+		recordAudit(ctx, a.audit, models.AuditCreate, "track", track.TrackId, nil, track)
 	}
 
 	filter := bson.M{"_id": albumId}
 
-	if err == mongo.ErrNoDocuments || len(album.Tracks) == 0 {
+	if len(album.Tracks) == 0 {
 		update := bson.M{
 			"$set": bson.M{
 				"tracks": []models.Track{*track},
 			},
+			"$inc":         bumpVersion,
+			"$currentDate": touched,
 		}
-		_, err := a.albumCollection.UpdateOne(a.ctx, filter, update)
-		return err
+		if _, err := a.albumCollection.UpdateOne(ctx, filter, update); err != nil {
+			return storeError(err, "album")
+		}
+		a.publishTrackAdded(albumId, track)
+		a.auditAlbum(ctx, models.AuditAddTrack, albumId, &album)
+		return nil
 	}
 
 	update := bson.M{
 		"$push": bson.M{
 			"tracks": track,
 		},
+		"$inc":         bumpVersion,
+		"$currentDate": touched,
+	}
+	if _, err := a.albumCollection.UpdateOne(ctx, filter, update); err != nil {
+		return storeError(err, "album")
 	}
-	_, err = a.albumCollection.UpdateOne(a.ctx, filter, update)
-	return err
+	a.publishTrackAdded(albumId, track)
+	a.auditAlbum(ctx, models.AuditAddTrack, albumId, &album)
+	return nil
 }
 
-func (a *AlbumImpl) AddExistedTrackToAlbum(albumId *primitive.ObjectID, trackId *primitive.ObjectID) error {
+func (a *AlbumImpl) AddExistedTrackToAlbum(ctx context.Context, albumId *primitive.ObjectID, trackId *primitive.ObjectID) error {
+	ctx, cancel := operation(ctx, "album.AddExistedTrackToAlbum", write)
+	defer cancel()
 	var track models.Track
-	err := a.trackCollection.FindOne(a.ctx, bson.M{"_id": trackId}).Decode(&track)
+	err := a.trackCollection.FindOne(ctx, live(bson.M{"_id": trackId})).Decode(&track)
 	if err != nil {
-		return err
+		return storeError(err, "track")
 	}
 	var album models.Album
-	err = a.albumCollection.FindOne(a.ctx, bson.M{"_id": albumId}).Decode(&album)
+	err = a.albumCollection.FindOne(ctx, live(bson.M{"_id": albumId})).Decode(&album)
 	if err != nil {
-		return err
+		return storeError(err, "album")
 	}
 	filter := bson.M{"_id": albumId}
-	if err == mongo.ErrNoDocuments || len(album.Tracks) == 0 {
+	if len(album.Tracks) == 0 {
 		update := bson.M{
 			"$set": bson.M{
 				"tracks": []models.Track{track},
 			},
+			"$inc":         bumpVersion,
+			"$currentDate": touched,
 		}
-		_, err := a.albumCollection.UpdateOne(a.ctx, filter, update)
-		return err
+		if _, err := a.albumCollection.UpdateOne(ctx, filter, update); err != nil {
+			return storeError(err, "album")
+		}
+		a.publishTrackAdded(albumId, &track)
+		a.auditAlbum(ctx, models.AuditAddTrack, albumId, &album)
+		return nil
 	}
 
 	update := bson.M{
 		"$push": bson.M{
 			"tracks": track,
 		},
+		"$inc":         bumpVersion,
+		"$currentDate": touched,
 	}
-	_, err = a.albumCollection.UpdateOne(a.ctx, filter, update)
-	return err
+	if _, err := a.albumCollection.UpdateOne(ctx, filter, update); err != nil {
+		return storeError(err, "album")
+	}
+	a.publishTrackAdded(albumId, &track)
+	a.auditAlbum(ctx, models.AuditAddTrack, albumId, &album)
+	return nil
 }
 
-func (a *AlbumImpl) RemoveTrackFromAlbum(albumId *primitive.ObjectID, trackId *primitive.ObjectID) error {
-	filter := bson.M{"_id": albumId}
-	update := bson.M{"$pull": bson.M{"tracks": bson.M{"_id": trackId.Hex()}}}
-	_, err := a.albumCollection.UpdateOne(a.ctx, filter, update)
-	return err
+func (a *AlbumImpl) RemoveTrackFromAlbum(ctx context.Context, albumId *primitive.ObjectID, trackId *primitive.ObjectID) error {
+	ctx, cancel := operation(ctx, "album.RemoveTrackFromAlbum", write)
+	defer cancel()
+	filter := live(bson.M{"_id": albumId})
+	update := bson.M{"$pull": bson.M{"tracks": bson.M{"_id": trackId.Hex()}}, "$inc": bumpVersion, "$currentDate": touched}
+	var before models.Album
+	if err := a.albumCollection.FindOneAndUpdate(ctx, filter, update).Decode(&before); err != nil {
+		return storeError(err, "album")
+	}
+	a.events.Publish(models.EventAlbumTrackRemoved, map[string]string{"album_id": albumId.Hex(), "track_id": trackId.Hex()})
+	a.auditAlbum(ctx, models.AuditRemoveTrack, albumId, &before)
+	return nil
 }
 
-func (a *AlbumImpl) FindTracksAndAlbums(keyword *string) ([]models.Album, []models.Track, error) {
+func (a *AlbumImpl) FindTracksAndAlbums(ctx context.Context, keyword *string) ([]models.Album, []models.Track, error) {
+	ctx, cancel := operation(ctx, "album.FindTracksAndAlbums", read)
+	defer cancel()
 	var albums []models.Album
 	var tracks []models.Track
 
 	// Tạo bộ lọc tìm kiếm gần đúng cho albums
-	albumFilter := bson.M{
-		"title": bson.M{"$regex": primitive.Regex{Pattern: *keyword, Options: "i"}},
-	}
+	albumFilter := live(bson.M{
+		"album_title": bson.M{"$regex": primitive.Regex{Pattern: *keyword, Options: "i"}},
+	})
 
 	// Tạo bộ lọc tìm kiếm gần đúng cho tracks
-	trackFilter := bson.M{
+	trackFilter := live(bson.M{
 		"$or": []bson.M{
-			{"title": bson.M{"$regex": primitive.Regex{Pattern: *keyword, Options: "i"}}},
+			{"music_title": bson.M{"$regex": primitive.Regex{Pattern: *keyword, Options: "i"}}},
 			{"artist": bson.M{"$regex": primitive.Regex{Pattern: *keyword, Options: "i"}}},
 			{"album": bson.M{"$regex": primitive.Regex{Pattern: *keyword, Options: "i"}}},
 			{"genre": bson.M{"$regex": primitive.Regex{Pattern: *keyword, Options: "i"}}},
 		},
-	}
+	})
 
 	// Tìm albums dựa trên bộ lọc
-	cursor, err := a.albumCollection.Find(a.ctx, albumFilter)
+	cursor, err := a.albumCollection.Find(ctx, albumFilter)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, storeError(err, "album")
 	}
-	defer cursor.Close(a.ctx)
+	defer cursor.Close(ctx)
 
 	// Giải nén kết quả tìm kiếm vào slice albums
-	if err := cursor.All(a.ctx, &albums); err != nil {
+	if err := cursor.All(ctx, &albums); err != nil {
+		return nil, nil, storeError(err, "album")
+	}
+	if err := a.hideDeletedTracksOf(ctx, albums); err != nil {
 		return nil, nil, err
 	}
 
 	// Tìm tracks dựa trên bộ lọc
-	cursor, err = a.trackCollection.Find(a.ctx, trackFilter)
+	cursor, err = a.trackCollection.Find(ctx, trackFilter)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, storeError(err, "track")
 	}
-	defer cursor.Close(a.ctx)
+	defer cursor.Close(ctx)
 
 	// Giải nén kết quả tìm kiếm vào slice tracks
-	if err := cursor.All(a.ctx, &tracks); err != nil {
-		return nil, nil, err
+	if err := cursor.All(ctx, &tracks); err != nil {
+		return nil, nil, storeError(err, "track")
 	}
 
 	return albums, tracks, nil
@@ -0,0 +1,111 @@
+package implements
+
+import (
+	"context"
+	"log"
+	"musiclib/helper"
+	"musiclib/models"
+	"musiclib/services"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Limits on the number of audit entries returned by FindEntries.
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+type AuditImpl struct {
+	auditCollection *mongo.Collection
+}
+
+func NewAuditService(auditCollection *mongo.Collection) services.AuditService {
+	return &AuditImpl{
+		auditCollection: auditCollection,
+	}
+}
+
+// recordAudit records that the actor of ctx made action on resource id,
+// changing it from before to after. The change is already made, so a
+// failure to record it is logged rather than returned.
+func recordAudit(ctx context.Context, audit services.AuditService, action string, resource string, id string, before interface{}, after interface{}) {
+	actor := services.ActorOf(ctx)
+	entry := models.AuditEntry{
+		Action:     action,
+		Resource:   resource,
+		ResourceId: id,
+		ActorId:    actor.UserId,
+		IP:         actor.IP,
+		Before:     helper.Snapshot(before),
+		After:      helper.Snapshot(after),
+	}
+	if err := audit.Record(ctx, &entry); err != nil {
+		log.Println("err record audit entry", action, resource, id, err)
+	}
+}
+
+// Record keeps entry even if ctx is canceled, as the change it records is
+// already made.
+func (a *AuditImpl) Record(ctx context.Context, entry *models.AuditEntry) error {
+	ctx, cancel := operation(context.WithoutCancel(ctx), "audit.Record", write)
+	defer cancel()
+	entry.AuditId = ""
+	entry.At = time.Now()
+	result, err := a.auditCollection.InsertOne(ctx, entry)
+	if err != nil {
+		return storeError(err, "audit entry")
+	}
+	entry.AuditId = result.InsertedID.(primitive.ObjectID).Hex()
+	return nil
+}
+
+// FindEntries lists the entries matching filter, newest first.
+func (a *AuditImpl) FindEntries(ctx context.Context, filter *models.AuditFilter) ([]models.AuditEntry, error) {
+	ctx, cancel := operation(ctx, "audit.FindEntries", read)
+	defer cancel()
+	limit := filter.Limit
+	if limit == 0 {
+		limit = defaultAuditLimit
+	}
+	if limit < 0 || limit > maxAuditLimit {
+		return nil, &services.ValidationError{Field: "limit", Message: "must be between 1 and 1000"}
+	}
+	query := bson.M{}
+	if filter.Resource != "" {
+		query["resource"] = filter.Resource
+	}
+	if filter.ResourceId != "" {
+		query["resource_id"] = filter.ResourceId
+	}
+	if filter.ActorId != "" {
+		query["actor_id"] = filter.ActorId
+	}
+	if filter.Action != "" {
+		query["action"] = filter.Action
+	}
+	at := bson.M{}
+	if filter.From != nil {
+		at["$gte"] = *filter.From
+	}
+	if filter.To != nil {
+		at["$lte"] = *filter.To
+	}
+	if len(at) > 0 {
+		query["at"] = at
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "at", Value: -1}, {Key: "_id", Value: -1}}).SetLimit(int64(limit))
+	entries := []models.AuditEntry{}
+	cursor, err := a.auditCollection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, storeError(err, "audit entry")
+	}
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, storeError(err, "audit entry")
+	}
+	return entries, nil
+}
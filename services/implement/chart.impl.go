@@ -0,0 +1,213 @@
+package implements
+
+import (
+	"context"
+	"errors"
+	"musiclib/models"
+	"musiclib/services"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// chartSize is the number of entries kept in every chart.
+const chartSize = 50
+
+// chartWindows maps every window to its length. The all-time chart has no
+// start; its previous period is the all-time chart as it stood a week ago.
+var chartWindows = map[string]time.Duration{
+	services.ChartDay:     24 * time.Hour,
+	services.ChartWeek:    7 * 24 * time.Hour,
+	services.ChartMonth:   30 * 24 * time.Hour,
+	services.ChartAllTime: 7 * 24 * time.Hour,
+}
+
+type ChartImpl struct {
+	chartCollection *mongo.Collection
+	playCollection  *mongo.Collection
+}
+
+func NewChartService(chartCollection *mongo.Collection, playCollection *mongo.Collection) services.ChartService {
+	return &ChartImpl{
+		chartCollection: chartCollection,
+		playCollection:  playCollection,
+	}
+}
+
+func (c *ChartImpl) GetChart(ctx context.Context, kind *string, window *string) (*models.Chart, error) {
+	ctx, cancel := operation(ctx, "chart.GetChart", read)
+	defer cancel()
+	var chart *models.Chart
+	err := c.chartCollection.FindOne(ctx, bson.M{"_id": chartId(*kind, *window)}).Decode(&chart)
+	if err == mongo.ErrNoDocuments {
+		return nil, services.NotFound("chart has not been computed yet")
+	}
+	return chart, storeError(err, "chart")
+}
+
+// ComputeCharts rebuilds every chart from the recorded plays.
+func (c *ChartImpl) ComputeCharts(ctx context.Context) error {
+	ctx, cancel := operation(ctx, "chart.ComputeCharts", bulk)
+	defer cancel()
+	now := time.Now()
+	for _, window := range services.ChartWindows {
+		for _, kind := range services.ChartKinds {
+			chart, err := c.computeChart(ctx, kind, window, now)
+			if err != nil {
+				return err
+			}
+			opts := options.Replace().SetUpsert(true)
+			if _, err := c.chartCollection.ReplaceOne(ctx, bson.M{"_id": chart.ChartId}, chart, opts); err != nil {
+				return storeError(err, "chart")
+			}
+		}
+	}
+	return nil
+}
+
+func (c *ChartImpl) computeChart(ctx context.Context, kind string, window string, now time.Time) (*models.Chart, error) {
+	length, ok := chartWindows[window]
+	if !ok {
+		return nil, errors.New("unknown chart window")
+	}
+	var start, previousStart, previousEnd time.Time
+	if window != services.ChartAllTime {
+		start = now.Add(-length)
+		previousStart = start.Add(-length)
+	}
+	previousEnd = now.Add(-length)
+
+	current, err := c.rank(ctx, kind, start, now)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := c.rank(ctx, kind, previousStart, previousEnd)
+	if err != nil {
+		return nil, err
+	}
+	previousRanks := make(map[string]int, len(previous))
+	for _, entry := range previous {
+		previousRanks[entry.ItemId] = entry.Rank
+	}
+	for i := range current {
+		entry := &current[i]
+		if rank, ok := previousRanks[entry.ItemId]; ok {
+			entry.PreviousRank = rank
+			entry.Movement = rank - entry.Rank
+		} else {
+			entry.New = true
+		}
+	}
+	return &models.Chart{
+		ChartId:     chartId(kind, window),
+		Kind:        kind,
+		Window:      window,
+		PeriodStart: start,
+		PeriodEnd:   now,
+		GeneratedAt: time.Now(),
+		Entries:     current,
+	}, nil
+}
+
+// rank counts the plays in [from, to) grouped by kind. A zero from means
+// no lower bound.
+func (c *ChartImpl) rank(ctx context.Context, kind string, from time.Time, to time.Time) ([]models.ChartEntry, error) {
+	playedAt := bson.M{"$lt": to}
+	if !from.IsZero() {
+		playedAt["$gte"] = from
+	}
+	pipeline := bson.A{bson.M{"$match": bson.M{"played_at": playedAt}}}
+	switch kind {
+	case services.ChartTracks:
+		pipeline = append(pipeline, withTrack()...)
+		pipeline = append(pipeline,
+			bson.M{"$group": bson.M{
+				"_id":    "$track_id",
+				"plays":  bson.M{"$sum": 1},
+				"title":  bson.M{"$first": "$track.music_title"},
+				"artist": bson.M{"$first": "$track.artist"},
+			}},
+		)
+	case services.ChartArtists:
+		pipeline = append(pipeline, withTrack()...)
+		pipeline = append(pipeline,
+			bson.M{"$group": bson.M{
+				"_id":   "$track.artist",
+				"plays": bson.M{"$sum": 1},
+				"title": bson.M{"$first": "$track.artist"},
+			}},
+		)
+	case services.ChartAlbums:
+		pipeline = append(pipeline,
+			bson.M{"$lookup": bson.M{
+				"from":         "albums",
+				"localField":   "track_id",
+				"foreignField": "tracks._id",
+				"as":           "album",
+			}},
+			bson.M{"$unwind": "$album"},
+			bson.M{"$match": bson.M{"album.deleted_at": bson.M{"$exists": false}}},
+			bson.M{"$group": bson.M{
+				"_id":   bson.M{"$toString": "$album._id"},
+				"plays": bson.M{"$sum": 1},
+				"title": bson.M{"$first": "$album.album_title"},
+			}},
+		)
+	default:
+		return nil, errors.New("unknown chart kind")
+	}
+	pipeline = append(pipeline,
+		bson.M{"$sort": bson.D{{Key: "plays", Value: -1}, {Key: "_id", Value: 1}}},
+		bson.M{"$limit": chartSize},
+	)
+
+	cursor, err := c.playCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, storeError(err, "play")
+	}
+	var rows []struct {
+		ItemId string `bson:"_id"`
+		Title  string `bson:"title"`
+		Artist string `bson:"artist"`
+		Plays  int    `bson:"plays"`
+	}
+	if err = cursor.All(ctx, &rows); err != nil {
+		return nil, storeError(err, "play")
+	}
+	entries := make([]models.ChartEntry, 0, len(rows))
+	for i, row := range rows {
+		entries = append(entries, models.ChartEntry{
+			Rank:   i + 1,
+			ItemId: row.ItemId,
+			Title:  row.Title,
+			Artist: row.Artist,
+			Plays:  row.Plays,
+		})
+	}
+	return entries, nil
+}
+
+// withTrack joins every play with its live track document. The track id of
+// a play is a string, so it is converted once to match the _id of the
+// track with its index.
+func withTrack() bson.A {
+	return bson.A{
+		bson.M{"$addFields": bson.M{
+			"track_oid": bson.M{"$convert": bson.M{"input": "$track_id", "to": "objectId", "onError": nil, "onNull": nil}},
+		}},
+		bson.M{"$lookup": bson.M{
+			"from":         "tracks",
+			"localField":   "track_oid",
+			"foreignField": "_id",
+			"as":           "track",
+		}},
+		bson.M{"$unwind": "$track"},
+		bson.M{"$match": bson.M{"track.deleted_at": bson.M{"$exists": false}}},
+	}
+}
+
+func chartId(kind string, window string) string {
+	return kind + ":" + window
+}
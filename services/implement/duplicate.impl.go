@@ -0,0 +1,300 @@
+package implements
+
+import (
+	"context"
+	"log"
+	"musiclib/models"
+	"musiclib/services"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// durationTolerance is how many seconds apart two recordings may be and
+// still count as the same track.
+const durationTolerance = 3
+
+var (
+	bracketed   = regexp.MustCompile(`[(\[][^)\]]*[)\]]`)
+	nonAlnum    = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+	reasonHash  = "identical file hash"
+	reasonTitle = "same title, artist and duration"
+)
+
+type DuplicateImpl struct {
+	trackCollection    *mongo.Collection
+	albumCollection    *mongo.Collection
+	playCollection     *mongo.Collection
+	playbackCollection *mongo.Collection
+	albumService       services.AlbumService
+	events             services.EventPublisher
+	audit              services.AuditService
+}
+
+func NewDuplicateService(trackCollection *mongo.Collection, albumCollection *mongo.Collection, playCollection *mongo.Collection, playbackCollection *mongo.Collection, albumService services.AlbumService, events services.EventPublisher, audit services.AuditService) services.DuplicateService {
+	return &DuplicateImpl{
+		trackCollection:    trackCollection,
+		albumCollection:    albumCollection,
+		playCollection:     playCollection,
+		playbackCollection: playbackCollection,
+		albumService:       albumService,
+		events:             events,
+		audit:              audit,
+	}
+}
+
+// merged is what a merge changed: the survivor, the duplicates moved to
+// the trash and the albums that held them.
+type merged struct {
+	survivor   models.Track
+	duplicates []models.Track
+	albumIds   []primitive.ObjectID
+}
+
+// FindDuplicateTracks clusters tracks sharing a file hash, or sharing a
+// normalized title and artist with durations within durationTolerance.
+func (d *DuplicateImpl) FindDuplicateTracks(ctx context.Context) ([]models.DuplicateCluster, error) {
+	ctx, cancel := operation(ctx, "duplicate.FindDuplicateTracks", bulk)
+	defer cancel()
+	var tracks []models.Track
+	cursor, err := d.trackCollection.Find(ctx, live(bson.M{}))
+	if err != nil {
+		return nil, storeError(err, "track")
+	}
+	if err = cursor.All(ctx, &tracks); err != nil {
+		return nil, storeError(err, "track")
+	}
+
+	clusters := []models.DuplicateCluster{}
+	clustered := map[string]bool{}
+
+	byHash := map[string][]models.Track{}
+	for _, track := range tracks {
+		if track.FileHash != "" {
+			byHash[track.FileHash] = append(byHash[track.FileHash], track)
+		}
+	}
+	for _, group := range byHash {
+		if len(group) < 2 {
+			continue
+		}
+		for _, track := range group {
+			clustered[track.TrackId] = true
+		}
+		clusters = append(clusters, models.DuplicateCluster{Reason: reasonHash, Tracks: group})
+	}
+
+	byTitle := map[string][]models.Track{}
+	for _, track := range tracks {
+		title := normalizeTitle(track.Title)
+		if clustered[track.TrackId] || title == "" {
+			continue
+		}
+		key := title + "\x00" + normalizeTitle(track.Artist)
+		byTitle[key] = append(byTitle[key], track)
+	}
+	for _, group := range byTitle {
+		if len(group) < 2 {
+			continue
+		}
+		for _, cluster := range splitByDuration(group) {
+			clusters = append(clusters, models.DuplicateCluster{Reason: reasonTitle, Tracks: cluster})
+		}
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].Tracks[0].TrackId < clusters[j].Tracks[0].TrackId
+	})
+	return clusters, nil
+}
+
+// normalizeTitle lowercases s and drops bracketed suffixes such as
+// "(Remastered)" as well as punctuation and spacing.
+func normalizeTitle(s string) string {
+	s = bracketed.ReplaceAllString(strings.ToLower(s), " ")
+	return strings.TrimSpace(nonAlnum.ReplaceAllString(s, " "))
+}
+
+// splitByDuration groups tracks whose durations are within
+// durationTolerance of the shortest track of their group. Tracks with an
+// unknown duration are a group of their own.
+func splitByDuration(tracks []models.Track) [][]models.Track {
+	sort.SliceStable(tracks, func(i, j int) bool {
+		return tracks[i].DurationSeconds < tracks[j].DurationSeconds
+	})
+	var groups [][]models.Track
+	var unknown []models.Track
+	for _, track := range tracks {
+		if track.DurationSeconds == 0 {
+			unknown = append(unknown, track)
+			continue
+		}
+		last := len(groups) - 1
+		if last >= 0 && track.DurationSeconds-groups[last][0].DurationSeconds <= durationTolerance {
+			groups[last] = append(groups[last], track)
+			continue
+		}
+		groups = append(groups, []models.Track{track})
+	}
+	groups = append(groups, unknown)
+	result := groups[:0]
+	for _, group := range groups {
+		if len(group) > 1 {
+			result = append(result, group)
+		}
+	}
+	return result
+}
+
+// MergeTracks points every album, play and play queue referencing one of
+// duplicateIds at survivorId, then moves the duplicates to the trash. It
+// runs in a transaction, so a failure leaves nothing half merged.
+func (d *DuplicateImpl) MergeTracks(ctx context.Context, survivorId *primitive.ObjectID, duplicateIds []primitive.ObjectID) error {
+	ctx, cancel := operation(ctx, "duplicate.MergeTracks", bulk)
+	defer cancel()
+	ids := make([]primitive.ObjectID, 0, len(duplicateIds))
+	duplicates := make([]string, 0, len(duplicateIds))
+	references := bson.A{}
+	seen := map[primitive.ObjectID]bool{}
+	for _, id := range duplicateIds {
+		if id == *survivorId {
+			return &services.ValidationError{Field: "duplicate_ids", Message: "must not contain the survivor"}
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+		duplicates = append(duplicates, id.Hex())
+		references = append(references, id, id.Hex())
+	}
+	if len(duplicates) == 0 {
+		return &services.ValidationError{Field: "duplicate_ids", Message: "must not be empty"}
+	}
+
+	session, err := d.trackCollection.Database().Client().StartSession()
+	if err != nil {
+		return storeError(err, "track")
+	}
+	defer session.EndSession(ctx)
+	var result merged
+	_, err = session.WithTransaction(ctx, func(ctx mongo.SessionContext) (interface{}, error) {
+		result = merged{}
+		return nil, d.merge(ctx, survivorId, ids, duplicates, references, &result)
+	})
+	if err != nil {
+		return err
+	}
+	d.publishMerge(ctx, &result)
+	return nil
+}
+
+// publishMerge announces and records a committed merge: the duplicates
+// are deleted, each merged into the survivor, and the albums updated.
+func (d *DuplicateImpl) publishMerge(ctx context.Context, result *merged) {
+	for i := range result.duplicates {
+		d.events.Publish(models.EventTrackDeleted, map[string]string{"id": result.duplicates[i].TrackId})
+		recordAudit(ctx, d.audit, models.AuditMerge, "track", result.duplicates[i].TrackId, &result.duplicates[i], &result.survivor)
+	}
+	for i := range result.albumIds {
+		album, err := d.albumService.FindAlbum(context.WithoutCancel(ctx), &result.albumIds[i])
+		if err != nil {
+			log.Println("err publish", models.EventAlbumUpdated, err)
+			continue
+		}
+		d.events.Publish(models.EventAlbumUpdated, album)
+	}
+}
+
+// merge does the writes of MergeTracks, and fills in result.
+func (d *DuplicateImpl) merge(ctx context.Context, survivorId *primitive.ObjectID, duplicateIds []primitive.ObjectID, duplicates []string, references bson.A, result *merged) error {
+	if err := d.trackCollection.FindOne(ctx, live(bson.M{"_id": survivorId})).Decode(&result.survivor); err != nil {
+		return storeError(err, "survivor track")
+	}
+	cursor, err := d.trackCollection.Find(ctx, live(bson.M{"_id": bson.M{"$in": duplicateIds}}))
+	if err != nil {
+		return storeError(err, "track")
+	}
+	if err = cursor.All(ctx, &result.duplicates); err != nil {
+		return storeError(err, "track")
+	}
+	if len(result.duplicates) != len(duplicateIds) {
+		return services.NotFound("some duplicate tracks do not exist")
+	}
+
+	if result.albumIds, err = d.repointAlbums(ctx, &result.survivor, references); err != nil {
+		return err
+	}
+	filter := bson.M{"track_id": bson.M{"$in": duplicates}}
+	if _, err := d.playCollection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"track_id": result.survivor.TrackId}, "$currentDate": touched}); err != nil {
+		return storeError(err, "play")
+	}
+	if err := d.repointPlayback(ctx, result.survivor.TrackId, duplicates); err != nil {
+		return err
+	}
+	_, err = d.trackCollection.UpdateMany(ctx, live(bson.M{"_id": bson.M{"$in": duplicateIds}}), moveToTrash())
+	return storeError(err, "track")
+}
+
+// repointAlbums replaces embedded duplicates with the survivor, keeping
+// the position of the first occurrence and dropping repeats. It returns
+// the albums it changed.
+func (d *DuplicateImpl) repointAlbums(ctx context.Context, survivor *models.Track, references bson.A) ([]primitive.ObjectID, error) {
+	var albums []models.Album
+	cursor, err := d.albumCollection.Find(ctx, bson.M{"tracks._id": bson.M{"$in": references}})
+	if err != nil {
+		return nil, storeError(err, "album")
+	}
+	if err = cursor.All(ctx, &albums); err != nil {
+		return nil, storeError(err, "album")
+	}
+	changed := make([]primitive.ObjectID, 0, len(albums))
+	isDuplicate := map[string]bool{}
+	for _, ref := range references {
+		if hex, ok := ref.(string); ok {
+			isDuplicate[hex] = true
+		}
+	}
+	for _, album := range albums {
+		tracks := make([]models.Track, 0, len(album.Tracks))
+		hasSurvivor := false
+		for _, track := range album.Tracks {
+			if isDuplicate[track.TrackId] || track.TrackId == survivor.TrackId {
+				if hasSurvivor {
+					continue
+				}
+				hasSurvivor = true
+				track = *survivor
+			}
+			tracks = append(tracks, track)
+		}
+		albumId, err := primitive.ObjectIDFromHex(album.AlbumId)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := d.albumCollection.UpdateOne(ctx, bson.M{"_id": albumId}, bson.M{"$set": bson.M{"tracks": tracks}, "$inc": bumpVersion, "$currentDate": touched}); err != nil {
+			return nil, storeError(err, "album")
+		}
+		changed = append(changed, albumId)
+	}
+	return changed, nil
+}
+
+func (d *DuplicateImpl) repointPlayback(ctx context.Context, survivorId string, duplicates []string) error {
+	current := bson.M{"current_track_id": bson.M{"$in": duplicates}}
+	if _, err := d.playbackCollection.UpdateMany(ctx, current, bson.M{"$set": bson.M{"current_track_id": survivorId}, "$inc": bson.M{"version": 1}, "$currentDate": touched}); err != nil {
+		return storeError(err, "playback state")
+	}
+	queued := bson.M{"queue": bson.M{"$in": duplicates}}
+	update := bson.M{"$set": bson.M{"queue.$[dup]": survivorId}, "$inc": bson.M{"version": 1}, "$currentDate": touched}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{bson.M{"dup": bson.M{"$in": duplicates}}},
+	})
+	_, err := d.playbackCollection.UpdateMany(ctx, queued, update, opts)
+	return storeError(err, "playback state")
+}
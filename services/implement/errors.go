@@ -0,0 +1,32 @@
+package implements
+
+import (
+	"context"
+	"errors"
+	"musiclib/services"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// storeError classifies a driver error: a missing document of resource is
+// services.ErrNotFound, a duplicate key services.ErrConflict, an operation
+// past its deadline services.ErrTimeout, one its caller gave up on
+// services.ErrCanceled and a database that cannot be reached
+// services.ErrUnavailable. Other errors are kept.
+func storeError(err error, resource string) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return &services.Error{Kind: services.ErrNotFound, Message: resource + " not found", Err: err}
+	case errors.Is(err, context.Canceled):
+		return &services.Error{Kind: services.ErrCanceled, Message: "operation canceled", Err: err}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &services.Error{Kind: services.ErrTimeout, Message: resource + " operation timed out", Err: err}
+	case mongo.IsDuplicateKeyError(err):
+		return &services.Error{Kind: services.ErrConflict, Message: resource + " already exists", Err: err}
+	case mongo.IsNetworkError(err), mongo.IsTimeout(err), errors.Is(err, mongo.ErrClientDisconnected):
+		return &services.Error{Kind: services.ErrUnavailable, Message: "database unavailable", Err: err}
+	}
+	return err
+}
@@ -0,0 +1,235 @@
+package implements
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"musiclib/models"
+	"musiclib/services"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Server error codes of change streams that cannot be opened.
+const (
+	codeNotReplicaSet           = 40573
+	codeInvalidResumeToken      = 260
+	codeChangeStreamHistoryLost = 286
+)
+
+// maxResumingFeeds bounds the feeds resuming from a token, which each need
+// a change stream of their own.
+const maxResumingFeeds = 32
+
+type FeedImpl struct {
+	trackCollection *mongo.Collection
+	albumCollection *mongo.Collection
+	hub             *feedHub
+	resuming        chan struct{}
+}
+
+func NewFeedService(trackCollection *mongo.Collection, albumCollection *mongo.Collection) services.FeedService {
+	f := &FeedImpl{
+		trackCollection: trackCollection,
+		albumCollection: albumCollection,
+		resuming:        make(chan struct{}, maxResumingFeeds),
+	}
+	f.hub = newFeedHub(func(ctx context.Context) (services.ChangeStream, error) {
+		stream, err := f.open(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+		return stream, nil
+	})
+	return f
+}
+
+// Watch follows the changes from now on through the change stream shared by
+// all such feeds. A feed resuming from a token reads a stream of its own,
+// and only maxResumingFeeds of them may be open at once.
+func (f *FeedImpl) Watch(ctx context.Context, token string) (services.ChangeStream, error) {
+	if token == "" {
+		sub, err := f.hub.subscribe(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return sub, nil
+	}
+	select {
+	case f.resuming <- struct{}{}:
+	default:
+		return nil, &services.Error{Kind: services.ErrUnavailable, Message: "too many feeds are resuming, retry later"}
+	}
+	stream, err := f.open(ctx, token)
+	if err != nil {
+		<-f.resuming
+		return nil, err
+	}
+	stream.release = func() { <-f.resuming }
+	return stream, nil
+}
+
+// open opens one change stream on the database for both collections, so a
+// single token resumes it.
+func (f *FeedImpl) open(ctx context.Context, token string) (*changeStream, error) {
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{
+		"ns.coll":       bson.M{"$in": bson.A{f.trackCollection.Name(), f.albumCollection.Name()}},
+		"operationType": bson.M{"$in": bson.A{"insert", "update", "replace", "delete"}},
+	}}}}
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if token != "" {
+		opts.SetResumeAfter(bson.M{"_data": token})
+	}
+	stream, err := f.trackCollection.Database().Watch(ctx, pipeline, opts)
+	if err != nil {
+		return nil, watchError(err)
+	}
+	return &changeStream{stream: stream, tracks: f.trackCollection.Name()}, nil
+}
+
+func watchError(err error) error {
+	var serverErr mongo.ServerError
+	if !errors.As(err, &serverErr) {
+		return storeError(err, "change feed")
+	}
+	switch {
+	case serverErr.HasErrorCode(codeNotReplicaSet):
+		return &services.Error{Kind: services.ErrUnavailable, Message: "the change feed needs a replica set", Err: err}
+	case serverErr.HasErrorCode(codeInvalidResumeToken), serverErr.HasErrorCode(codeChangeStreamHistoryLost):
+		return &services.ValidationError{Field: "token", Message: "cannot be resumed, reload and start over"}
+	}
+	return err
+}
+
+type changeStream struct {
+	stream *mongo.ChangeStream
+	tracks string
+	// release, if set, frees the place of the stream when it is closed.
+	release func()
+}
+
+// changeEvent is the part of a change stream event the feed uses.
+type changeEvent struct {
+	OperationType string              `bson:"operationType"`
+	ClusterTime   primitive.Timestamp `bson:"clusterTime"`
+	Ns            struct {
+		Coll string `bson:"coll"`
+	} `bson:"ns"`
+	DocumentKey struct {
+		Id interface{} `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument      bson.Raw `bson:"fullDocument"`
+	UpdateDescription struct {
+		UpdatedFields bson.M   `bson:"updatedFields"`
+		RemovedFields []string `bson:"removedFields"`
+	} `bson:"updateDescription"`
+}
+
+func (c *changeStream) Next(ctx context.Context) (*models.Change, error) {
+	for c.stream.Next(ctx) {
+		var event changeEvent
+		if err := c.stream.Decode(&event); err != nil {
+			return nil, err
+		}
+		change, err := c.toChange(&event)
+		if err != nil {
+			return nil, err
+		}
+		if change != nil {
+			return change, nil
+		}
+	}
+	if err := c.stream.Err(); err != nil {
+		return nil, watchError(err)
+	}
+	return nil, ctx.Err()
+}
+
+func (c *changeStream) Close(ctx context.Context) error {
+	if c.release != nil {
+		c.release()
+		c.release = nil
+	}
+	return c.stream.Close(ctx)
+}
+
+// toChange turns a change stream event into a feed change, or nil for an
+// event the feed does not report.
+func (c *changeStream) toChange(event *changeEvent) (*models.Change, error) {
+	resource := "album"
+	if event.Ns.Coll == c.tracks {
+		resource = "track"
+	}
+	change := &models.Change{
+		Token:      c.stream.ResumeToken().Lookup("_data").StringValue(),
+		ResourceId: documentId(event.DocumentKey.Id),
+		At:         time.Unix(int64(event.ClusterTime.T), 0).UTC(),
+	}
+	switch event.OperationType {
+	case "insert":
+		change.Type = resource + ".created"
+	case "replace":
+		change.Type = resource + ".updated"
+	case "delete":
+		// A purge of a document that was already reported deleted.
+		change.Type = resource + ".deleted"
+		return change, nil
+	case "update":
+		updated := event.UpdateDescription.UpdatedFields
+		switch {
+		case updated["deleted_at"] != nil:
+			change.Type = resource + ".deleted"
+			return change, nil
+		case slices.Contains(event.UpdateDescription.RemovedFields, "deleted_at"):
+			change.Type = resource + ".restored"
+		case resource == "album" && changesTracks(updated):
+			change.Type = models.ChangeAlbumTracks
+		default:
+			change.Type = resource + ".updated"
+		}
+	default:
+		return nil, nil
+	}
+	if event.FullDocument == nil {
+		// The document was deleted again before it could be looked up.
+		return nil, nil
+	}
+	var err error
+	if resource == "track" {
+		var track models.Track
+		err = bson.Unmarshal(event.FullDocument, &track)
+		change.Data = track
+	} else {
+		var album models.Album
+		err = bson.Unmarshal(event.FullDocument, &album)
+		change.Data = album
+	}
+	return change, err
+}
+
+// changesTracks reports whether an update touched the tracks of an album,
+// as a whole or by position.
+func changesTracks(updated bson.M) bool {
+	for field := range updated {
+		if field == "tracks" || strings.HasPrefix(field, "tracks.") {
+			return true
+		}
+	}
+	return false
+}
+
+func documentId(id interface{}) string {
+	switch v := id.(type) {
+	case primitive.ObjectID:
+		return v.Hex()
+	case string:
+		return v
+	}
+	return fmt.Sprint(id)
+}
@@ -0,0 +1,130 @@
+package implements
+
+import (
+	"context"
+	"musiclib/models"
+	"musiclib/services"
+	"sync"
+)
+
+// feedBuffer is how many changes a feed may fall behind the shared change
+// stream before it is dropped.
+const feedBuffer = 256
+
+// feedHub shares one change stream among all the feeds that start from
+// now, so that the number of clients does not open as many cursors on the
+// database. The stream is opened with the first feed and closed with the
+// last.
+type feedHub struct {
+	open        func(ctx context.Context) (services.ChangeStream, error)
+	mu          sync.Mutex
+	subscribers map[*subscription]bool
+	current     *sharedStream
+}
+
+// sharedStream is a change stream opened by the hub; stop ends it.
+type sharedStream struct {
+	stop context.CancelFunc
+}
+
+func newFeedHub(open func(ctx context.Context) (services.ChangeStream, error)) *feedHub {
+	return &feedHub{open: open, subscribers: map[*subscription]bool{}}
+}
+
+// subscribe adds a feed, opening the shared stream if none is.
+func (h *feedHub) subscribe(ctx context.Context) (*subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.current == nil {
+		stream, err := h.open(ctx)
+		if err != nil {
+			return nil, err
+		}
+		streamCtx, stop := context.WithCancel(context.Background())
+		h.current = &sharedStream{stop: stop}
+		go h.run(streamCtx, h.current, stream)
+	}
+	sub := &subscription{hub: h, changes: make(chan *models.Change, feedBuffer)}
+	h.subscribers[sub] = true
+	return sub, nil
+}
+
+// run passes the changes of stream to the feeds until it fails or the last
+// feed leaves.
+func (h *feedHub) run(ctx context.Context, shared *sharedStream, stream services.ChangeStream) {
+	defer stream.Close(context.Background())
+	for {
+		change, err := stream.Next(ctx)
+		h.mu.Lock()
+		if h.current != shared {
+			h.mu.Unlock()
+			return
+		}
+		if err != nil {
+			for sub := range h.subscribers {
+				sub.fail(err)
+			}
+			h.subscribers = map[*subscription]bool{}
+			h.current = nil
+			h.mu.Unlock()
+			shared.stop()
+			return
+		}
+		for sub := range h.subscribers {
+			select {
+			case sub.changes <- change:
+			default:
+				// A slow client must not hold up the others; it resumes
+				// with the token of the last change it got.
+				sub.fail(&services.Error{Kind: services.ErrUnavailable, Message: "the feed fell behind, resume from the last token"})
+				h.remove(sub)
+			}
+		}
+		h.mu.Unlock()
+	}
+}
+
+// remove drops sub, and closes the shared stream after the last feed. The
+// caller holds the lock.
+func (h *feedHub) remove(sub *subscription) {
+	delete(h.subscribers, sub)
+	if len(h.subscribers) == 0 && h.current != nil {
+		h.current.stop()
+		h.current = nil
+	}
+}
+
+// subscription is a feed reading the shared stream.
+type subscription struct {
+	hub     *feedHub
+	changes chan *models.Change
+	// err is why changes was closed.
+	err error
+}
+
+// fail ends the feed with err. The caller holds the lock of the hub.
+func (s *subscription) fail(err error) {
+	s.err = err
+	close(s.changes)
+}
+
+func (s *subscription) Next(ctx context.Context) (*models.Change, error) {
+	select {
+	case change, ok := <-s.changes:
+		if !ok {
+			return nil, s.err
+		}
+		return change, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *subscription) Close(context.Context) error {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	if s.hub.subscribers[s] {
+		s.hub.remove(s)
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package implements
+
+import (
+	"context"
+	"musiclib/models"
+	"musiclib/services"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// defaultJobAttempts is the number of attempts of a job that does not
+	// set MaxAttempts.
+	defaultJobAttempts = 5
+	// jobHistory is the number of jobs listed.
+	jobHistory = 100
+)
+
+type JobImpl struct {
+	jobCollection *mongo.Collection
+	wake          chan struct{}
+}
+
+func NewJobService(jobCollection *mongo.Collection) services.JobService {
+	return &JobImpl{
+		jobCollection: jobCollection,
+		wake:          make(chan struct{}, 1),
+	}
+}
+
+func (j *JobImpl) signal() {
+	select {
+	case j.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (j *JobImpl) Wake() <-chan struct{} {
+	return j.wake
+}
+
+func (j *JobImpl) Enqueue(ctx context.Context, job *models.Job) error {
+	ctx, cancel := operation(ctx, "job.Enqueue", write)
+	defer cancel()
+	now := time.Now()
+	job.JobId = ""
+	job.Status = models.JobQueued
+	job.Attempts = 0
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = defaultJobAttempts
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = now
+	}
+	job.CreatedAt = now
+	if job.Key == "" {
+		result, err := j.jobCollection.InsertOne(ctx, job)
+		if err != nil {
+			return storeError(err, "job")
+		}
+		job.JobId = result.InsertedID.(primitive.ObjectID).Hex()
+		j.signal()
+		return nil
+	}
+	// The key comes from the filter when the job is inserted.
+	filter := bson.M{"key": job.Key, "status": bson.M{"$in": []string{models.JobQueued, models.JobRunning}}}
+	insert := bson.M{
+		"type":         job.Type,
+		"status":       job.Status,
+		"attempts":     job.Attempts,
+		"max_attempts": job.MaxAttempts,
+		"run_at":       job.RunAt,
+		"created_at":   job.CreatedAt,
+	}
+	if job.Payload != nil {
+		insert["payload"] = job.Payload
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := j.jobCollection.FindOneAndUpdate(ctx, filter, bson.M{"$setOnInsert": insert}, opts).Decode(job)
+	if mongo.IsDuplicateKeyError(err) {
+		// A concurrent call queued the job first; the unique index on the
+		// keys of pending jobs refused this one, so answer that job.
+		err = j.jobCollection.FindOne(ctx, filter).Decode(job)
+	}
+	if err != nil {
+		return storeError(err, "job")
+	}
+	j.signal()
+	return nil
+}
+
+func (j *JobImpl) FindJob(ctx context.Context, jobId *primitive.ObjectID) (*models.Job, error) {
+	ctx, cancel := operation(ctx, "job.FindJob", read)
+	defer cancel()
+	var job *models.Job
+	if err := j.jobCollection.FindOne(ctx, bson.M{"_id": jobId}).Decode(&job); err != nil {
+		return nil, storeError(err, "job")
+	}
+	return job, nil
+}
+
+func (j *JobImpl) FindLatestSucceeded(ctx context.Context, jobType *string) (*models.Job, error) {
+	ctx, cancel := operation(ctx, "job.FindLatestSucceeded", read)
+	defer cancel()
+	var job *models.Job
+	filter := bson.M{"type": jobType, "status": models.JobSucceeded}
+	opts := options.FindOne().SetSort(bson.M{"finished_at": -1})
+	if err := j.jobCollection.FindOne(ctx, filter, opts).Decode(&job); err != nil {
+		return nil, storeError(err, "job")
+	}
+	return job, nil
+}
+
+func (j *JobImpl) GetJobs(ctx context.Context, status *string) ([]models.Job, error) {
+	ctx, cancel := operation(ctx, "job.GetJobs", read)
+	defer cancel()
+	filter := bson.M{}
+	if *status != "" {
+		filter["status"] = *status
+	}
+	jobs := []models.Job{}
+	opts := options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(jobHistory)
+	cursor, err := j.jobCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, storeError(err, "job")
+	}
+	if err = cursor.All(ctx, &jobs); err != nil {
+		return nil, storeError(err, "job")
+	}
+	return jobs, nil
+}
+
+func (j *JobImpl) RetryJob(ctx context.Context, jobId *primitive.ObjectID) (*models.Job, error) {
+	ctx, cancel := operation(ctx, "job.RetryJob", write)
+	defer cancel()
+	filter := bson.M{"_id": jobId, "status": models.JobDead}
+	update := bson.M{
+		"$set":         bson.M{"status": models.JobQueued, "attempts": 0, "run_at": time.Now()},
+		"$unset":       bson.M{"finished_at": "", "lease_owner": "", "lease_expires_at": ""},
+		"$currentDate": touched,
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var job *models.Job
+	err := j.jobCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, services.Conflict("a job with this key is already queued or running")
+	}
+	if err == mongo.ErrNoDocuments {
+		if _, err := j.FindJob(ctx, jobId); err != nil {
+			return nil, err
+		}
+		return nil, services.Conflict("only dead jobs can be retried")
+	}
+	if err != nil {
+		return nil, storeError(err, "job")
+	}
+	j.signal()
+	return job, nil
+}
+
+func (j *JobImpl) Claim(ctx context.Context, owner string, types []string, lease time.Duration) (*models.Job, error) {
+	ctx, cancel := operation(ctx, "job.Claim", write)
+	defer cancel()
+	now := time.Now()
+	filter := bson.M{
+		"type": bson.M{"$in": types},
+		"$or": []bson.M{
+			{"status": models.JobQueued, "run_at": bson.M{"$lte": now}},
+			{"status": models.JobRunning, "lease_expires_at": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":           models.JobRunning,
+			"lease_owner":      owner,
+			"lease_expires_at": now.Add(lease),
+			"started_at":       now,
+		},
+		"$inc":         bson.M{"attempts": 1},
+		"$currentDate": touched,
+	}
+	opts := options.FindOneAndUpdate().SetSort(bson.M{"run_at": 1}).SetReturnDocument(options.After)
+	var job *models.Job
+	err := j.jobCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, services.Conflict("a job with this key is already queued or running")
+	}
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, storeError(err, "job")
+	}
+	return job, nil
+}
+
+// leased matches a job while owner holds its lease.
+func leased(jobId *primitive.ObjectID, owner string) bson.M {
+	return bson.M{"_id": jobId, "status": models.JobRunning, "lease_owner": owner}
+}
+
+func (j *JobImpl) ExtendLease(ctx context.Context, jobId *primitive.ObjectID, owner string, lease time.Duration) error {
+	ctx, cancel := operation(ctx, "job.ExtendLease", write)
+	defer cancel()
+	update := bson.M{"$set": bson.M{"lease_expires_at": time.Now().Add(lease)}}
+	result, err := j.jobCollection.UpdateOne(ctx, leased(jobId, owner), update)
+	if err != nil {
+		return storeError(err, "job")
+	}
+	if result.MatchedCount == 0 {
+		return services.NotFound("job lease lost")
+	}
+	return nil
+}
+
+func (j *JobImpl) Complete(ctx context.Context, jobId *primitive.ObjectID, owner string, result []byte) error {
+	ctx, cancel := operation(ctx, "job.Complete", write)
+	defer cancel()
+	set := bson.M{"status": models.JobSucceeded, "finished_at": time.Now(), "last_error": ""}
+	if result != nil {
+		set["result"] = result
+	}
+	update := bson.M{"$set": set, "$unset": bson.M{"lease_owner": "", "lease_expires_at": ""}, "$currentDate": touched}
+	updated, err := j.jobCollection.UpdateOne(ctx, leased(jobId, owner), update)
+	if err != nil {
+		return storeError(err, "job")
+	}
+	if updated.MatchedCount == 0 {
+		return services.NotFound("job lease lost")
+	}
+	return nil
+}
+
+func (j *JobImpl) Fail(ctx context.Context, jobId *primitive.ObjectID, owner string, message string, retryAt *time.Time) error {
+	ctx, cancel := operation(ctx, "job.Fail", write)
+	defer cancel()
+	set := bson.M{"last_error": message}
+	if retryAt != nil {
+		set["status"] = models.JobQueued
+		set["run_at"] = *retryAt
+	} else {
+		set["status"] = models.JobDead
+		set["finished_at"] = time.Now()
+	}
+	update := bson.M{"$set": set, "$unset": bson.M{"lease_owner": "", "lease_expires_at": ""}, "$currentDate": touched}
+	updated, err := j.jobCollection.UpdateOne(ctx, leased(jobId, owner), update)
+	if err != nil {
+		return storeError(err, "job")
+	}
+	if updated.MatchedCount == 0 {
+		return services.NotFound("job lease lost")
+	}
+	return nil
+}
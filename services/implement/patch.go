@@ -0,0 +1,25 @@
+package implements
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// patchFields returns the values of the given bson keys of doc, ready for a
+// $set. Keys doc does not have are skipped.
+func patchFields(doc interface{}, fields []string) (bson.M, error) {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var all bson.M
+	if err := bson.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	set := bson.M{}
+	for _, field := range fields {
+		if value, ok := all[field]; ok && field != "_id" {
+			set[field] = value
+		}
+	}
+	return set, nil
+}
@@ -0,0 +1,94 @@
+package implements
+
+import (
+	"context"
+	"musiclib/models"
+	"musiclib/services"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const maxQueueLength = 1000
+
+type PlaybackImpl struct {
+	playbackCollection *mongo.Collection
+}
+
+func NewPlaybackService(playbackCollection *mongo.Collection) services.PlaybackService {
+	return &PlaybackImpl{
+		playbackCollection: playbackCollection,
+	}
+}
+
+// GetPlaybackState returns the saved state of a user, or an empty version 0
+// state when nothing was saved yet.
+func (p *PlaybackImpl) GetPlaybackState(ctx context.Context, userId *string) (*models.PlaybackState, error) {
+	ctx, cancel := operation(ctx, "playback.GetPlaybackState", read)
+	defer cancel()
+	var state models.PlaybackState
+	err := p.playbackCollection.FindOne(ctx, bson.M{"_id": userId}).Decode(&state)
+	if err == mongo.ErrNoDocuments {
+		return &models.PlaybackState{UserId: *userId, Queue: []string{}, Repeat: "off"}, nil
+	}
+	if err != nil {
+		return nil, storeError(err, "playback state")
+	}
+	return &state, nil
+}
+
+// SavePlaybackState stores state if state.Version is still the current
+// version, and bumps the version. Otherwise it returns ErrVersionConflict.
+func (p *PlaybackImpl) SavePlaybackState(ctx context.Context, state *models.PlaybackState) error {
+	ctx, cancel := operation(ctx, "playback.SavePlaybackState", write)
+	defer cancel()
+	if err := checkPlaybackState(state); err != nil {
+		return err
+	}
+	expected := state.Version
+	state.Version = expected + 1
+	state.UpdatedAt = time.Now()
+
+	if expected == 0 {
+		_, err := p.playbackCollection.InsertOne(ctx, state)
+		if mongo.IsDuplicateKeyError(err) {
+			state.Version = expected
+			return services.ErrVersionConflict
+		}
+		return storeError(err, "playback state")
+	}
+	filter := bson.M{"_id": state.UserId, "version": expected}
+	result, err := p.playbackCollection.ReplaceOne(ctx, filter, state)
+	if err != nil {
+		return storeError(err, "playback state")
+	}
+	if result.MatchedCount != 1 {
+		state.Version = expected
+		return services.ErrVersionConflict
+	}
+	return nil
+}
+
+func checkPlaybackState(state *models.PlaybackState) error {
+	switch state.Repeat {
+	case "":
+		state.Repeat = "off"
+	case "off", "one", "all":
+	default:
+		return &services.ValidationError{Field: "repeat", Message: "must be off, one or all"}
+	}
+	if state.PositionMs < 0 {
+		return &services.ValidationError{Field: "position_ms", Message: "must not be negative"}
+	}
+	if state.Version < 0 {
+		return &services.ValidationError{Field: "version", Message: "must not be negative"}
+	}
+	if len(state.Queue) > maxQueueLength {
+		return &services.ValidationError{Field: "queue", Message: "too many tracks"}
+	}
+	if state.Queue == nil {
+		state.Queue = []string{}
+	}
+	return nil
+}
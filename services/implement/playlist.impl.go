@@ -0,0 +1,125 @@
+package implements
+
+import (
+	"context"
+	"musiclib/models"
+	"musiclib/services"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type PlaylistImpl struct {
+	playlistCollection *mongo.Collection
+	trackCollection    *mongo.Collection
+}
+
+func NewPlaylistService(playlistCollection *mongo.Collection, trackCollection *mongo.Collection) services.PlaylistService {
+	return &PlaylistImpl{
+		playlistCollection: playlistCollection,
+		trackCollection:    trackCollection,
+	}
+}
+
+func (p *PlaylistImpl) CreatePlaylist(ctx context.Context, playlist *models.Playlist) error {
+	ctx, cancel := operation(ctx, "playlist.CreatePlaylist", write)
+	defer cancel()
+	if _, _, err := compileRules(&playlist.Rules); err != nil {
+		return err
+	}
+	playlist.CreatedAt = time.Now()
+	playlist.UpdatedAt = playlist.CreatedAt
+	result, err := p.playlistCollection.InsertOne(ctx, playlist)
+	if err != nil {
+		return storeError(err, "playlist")
+	}
+	playlist.PlaylistId = result.InsertedID.(primitive.ObjectID).Hex()
+	return nil
+}
+
+func (p *PlaylistImpl) FindPlaylist(ctx context.Context, playlistId *primitive.ObjectID) (*models.Playlist, []models.Track, error) {
+	ctx, cancel := operation(ctx, "playlist.FindPlaylist", read)
+	defer cancel()
+	var playlist *models.Playlist
+	if err := p.playlistCollection.FindOne(ctx, bson.M{"_id": playlistId}).Decode(&playlist); err != nil {
+		return nil, nil, storeError(err, "playlist")
+	}
+	tracks, err := p.PreviewPlaylist(ctx, &playlist.Rules)
+	if err != nil {
+		return nil, nil, err
+	}
+	return playlist, tracks, nil
+}
+
+func (p *PlaylistImpl) GetUserPlaylists(ctx context.Context, userId *string) ([]models.Playlist, error) {
+	ctx, cancel := operation(ctx, "playlist.GetUserPlaylists", read)
+	defer cancel()
+	playlists := []models.Playlist{}
+	cursor, err := p.playlistCollection.Find(ctx, bson.M{"user_id": userId})
+	if err != nil {
+		return nil, storeError(err, "playlist")
+	}
+	if err = cursor.All(ctx, &playlists); err != nil {
+		return nil, storeError(err, "playlist")
+	}
+	return playlists, nil
+}
+
+func (p *PlaylistImpl) UpdatePlaylist(ctx context.Context, playlistId *primitive.ObjectID, userId *string, playlist *models.Playlist) error {
+	ctx, cancel := operation(ctx, "playlist.UpdatePlaylist", write)
+	defer cancel()
+	if _, _, err := compileRules(&playlist.Rules); err != nil {
+		return err
+	}
+	filter := bson.M{"_id": playlistId, "user_id": userId}
+	update := bson.M{
+		"$set": bson.M{
+			"name":       playlist.Name,
+			"rules":      playlist.Rules,
+			"updated_at": time.Now(),
+		},
+	}
+	result, err := p.playlistCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return storeError(err, "playlist")
+	}
+	if result.MatchedCount != 1 {
+		return services.NotFound("playlist not found")
+	}
+	err = p.playlistCollection.FindOne(ctx, bson.M{"_id": playlistId}).Decode(playlist)
+	return storeError(err, "playlist")
+}
+
+func (p *PlaylistImpl) DeletePlaylist(ctx context.Context, playlistId *primitive.ObjectID, userId *string) error {
+	ctx, cancel := operation(ctx, "playlist.DeletePlaylist", write)
+	defer cancel()
+	result, err := p.playlistCollection.DeleteOne(ctx, bson.M{"_id": playlistId, "user_id": userId})
+	if err != nil {
+		return storeError(err, "playlist")
+	}
+	if result.DeletedCount != 1 {
+		return services.NotFound("playlist not found")
+	}
+	return nil
+}
+
+// PreviewPlaylist evaluates rules against the tracks collection without saving them.
+func (p *PlaylistImpl) PreviewPlaylist(ctx context.Context, rules *models.SmartRules) ([]models.Track, error) {
+	ctx, cancel := operation(ctx, "playlist.PreviewPlaylist", read)
+	defer cancel()
+	filter, opts, err := compileRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	tracks := []models.Track{}
+	cursor, err := p.trackCollection.Find(ctx, live(filter), opts)
+	if err != nil {
+		return nil, storeError(err, "track")
+	}
+	if err = cursor.All(ctx, &tracks); err != nil {
+		return nil, storeError(err, "track")
+	}
+	return tracks, nil
+}
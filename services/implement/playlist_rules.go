@@ -0,0 +1,177 @@
+package implements
+
+import (
+	"fmt"
+	"musiclib/models"
+	"musiclib/services"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultRuleLimit = 50
+	maxRuleLimit     = 500
+	maxRuleCount     = 20
+)
+
+// ruleField describes a track field that smart playlist rules may use.
+type ruleField struct {
+	column  string
+	numeric bool
+}
+
+var ruleFields = map[string]ruleField{
+	"title":        {column: "music_title"},
+	"artist":       {column: "artist"},
+	"genre":        {column: "genre"},
+	"file_name":    {column: "file_name"},
+	"release_year": {column: "release_year", numeric: true},
+	"duration":     {column: "duration_seconds", numeric: true},
+}
+
+var stringOperators = map[string]bool{"eq": true, "ne": true, "in": true, "nin": true, "contains": true}
+var numericOperators = map[string]bool{"eq": true, "ne": true, "in": true, "nin": true, "gt": true, "gte": true, "lt": true, "lte": true}
+
+// compileRules validates smart playlist rules and translates them into a
+// MongoDB filter and find options. User values are only ever passed as
+// query operands; "contains" is the one regex and its input is quoted.
+func compileRules(rules *models.SmartRules) (bson.M, *options.FindOptions, error) {
+	if len(rules.Conditions) > maxRuleCount {
+		return nil, nil, &services.ValidationError{Field: "conditions", Message: fmt.Sprintf("at most %d conditions are allowed", maxRuleCount)}
+	}
+	clauses := make([]bson.M, 0, len(rules.Conditions))
+	for i, condition := range rules.Conditions {
+		clause, err := compileCondition(&condition)
+		if err != nil {
+			err.Field = fmt.Sprintf("conditions[%d].%s", i, err.Field)
+			return nil, nil, err
+		}
+		clauses = append(clauses, clause)
+	}
+
+	filter := bson.M{}
+	switch strings.ToLower(rules.Match) {
+	case "", "all":
+		if len(clauses) > 0 {
+			filter["$and"] = clauses
+		}
+	case "any":
+		if len(clauses) > 0 {
+			filter["$or"] = clauses
+		}
+	default:
+		return nil, nil, &services.ValidationError{Field: "match", Message: "must be all or any"}
+	}
+
+	opts := options.Find()
+	limit := rules.Limit
+	if limit == 0 {
+		limit = defaultRuleLimit
+	}
+	if limit < 0 || limit > maxRuleLimit {
+		return nil, nil, &services.ValidationError{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", maxRuleLimit)}
+	}
+	opts.SetLimit(int64(limit))
+	if rules.SortBy != "" {
+		field, ok := ruleFields[rules.SortBy]
+		if !ok {
+			return nil, nil, &services.ValidationError{Field: "sort_by", Message: "unknown field " + rules.SortBy}
+		}
+		order := 1
+		if rules.Descending {
+			order = -1
+		}
+		opts.SetSort(bson.D{{Key: field.column, Value: order}, {Key: "_id", Value: 1}})
+	}
+	return filter, opts, nil
+}
+
+func compileCondition(condition *models.RuleCondition) (bson.M, *services.ValidationError) {
+	field, ok := ruleFields[condition.Field]
+	if !ok {
+		return nil, &services.ValidationError{Field: "field", Message: "unknown field " + condition.Field}
+	}
+	operators := stringOperators
+	if field.numeric {
+		operators = numericOperators
+	}
+	if !operators[condition.Operator] {
+		return nil, &services.ValidationError{Field: "operator", Message: fmt.Sprintf("%q is not allowed on %s", condition.Operator, condition.Field)}
+	}
+
+	if condition.Operator == "in" || condition.Operator == "nin" {
+		values, ok := ruleList(condition.Value)
+		if !ok || len(values) == 0 {
+			return nil, &services.ValidationError{Field: "value", Message: "must be a non-empty list"}
+		}
+		operands := make(bson.A, 0, len(values))
+		for _, v := range values {
+			operand, err := ruleOperand(field, v)
+			if err != nil {
+				return nil, err
+			}
+			operands = append(operands, operand)
+		}
+		return compareField(field, "$"+condition.Operator, operands), nil
+	}
+
+	operand, err := ruleOperand(field, condition.Value)
+	if err != nil {
+		return nil, err
+	}
+	if condition.Operator == "contains" {
+		pattern := regexp.QuoteMeta(operand.(string))
+		return bson.M{field.column: primitive.Regex{Pattern: pattern, Options: "i"}}, nil
+	}
+	return compareField(field, "$"+condition.Operator, operand), nil
+}
+
+// compareField builds the comparison for one field. release_year is stored
+// as a string, so it is converted server side before comparing numbers.
+func compareField(field ruleField, operator string, operand interface{}) bson.M {
+	if field.column != "release_year" {
+		return bson.M{field.column: bson.M{operator: operand}}
+	}
+	year := bson.M{"$convert": bson.M{"input": "$release_year", "to": "int", "onError": nil, "onNull": nil}}
+	if operator == "$nin" {
+		return bson.M{"$expr": bson.M{"$not": bson.A{bson.M{"$in": bson.A{year, operand}}}}}
+	}
+	return bson.M{"$expr": bson.M{operator: bson.A{year, operand}}}
+}
+
+func ruleOperand(field ruleField, value interface{}) (interface{}, *services.ValidationError) {
+	if !field.numeric {
+		s, ok := value.(string)
+		if !ok || s == "" {
+			return nil, &services.ValidationError{Field: "value", Message: "must be a non-empty string"}
+		}
+		return s, nil
+	}
+	switch n := value.(type) {
+	case float64:
+		if n == float64(int(n)) {
+			return int(n), nil
+		}
+	case int32:
+		return int(n), nil
+	case int64:
+		return int(n), nil
+	case int:
+		return n, nil
+	}
+	return nil, &services.ValidationError{Field: "value", Message: "must be a whole number"}
+}
+
+func ruleList(value interface{}) ([]interface{}, bool) {
+	switch v := value.(type) {
+	case []interface{}:
+		return v, true
+	case primitive.A:
+		return v, true
+	}
+	return nil, false
+}
@@ -0,0 +1,164 @@
+package implements
+
+import (
+	"context"
+	"musiclib/models"
+	"musiclib/services"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ReviewImpl struct {
+	reviewCollection *mongo.Collection
+	albumCollection  *mongo.Collection
+}
+
+func NewReviewService(reviewCollection *mongo.Collection, albumCollection *mongo.Collection) services.ReviewService {
+	return &ReviewImpl{
+		reviewCollection: reviewCollection,
+		albumCollection:  albumCollection,
+	}
+}
+
+func (r *ReviewImpl) CreateReview(ctx context.Context, review *models.Review) error {
+	ctx, cancel := operation(ctx, "review.CreateReview", write)
+	defer cancel()
+	albumId, err := primitive.ObjectIDFromHex(review.AlbumId)
+	if err != nil {
+		return &services.ValidationError{Field: "album_id", Message: "must be an id"}
+	}
+	if err := r.albumCollection.FindOne(ctx, live(bson.M{"_id": albumId})).Err(); err != nil {
+		return storeError(err, "album")
+	}
+	review.CreatedAt = time.Now()
+	review.UpdatedAt = review.CreatedAt
+	// The unique (album_id, user_id) index keeps concurrent reviews of the
+	// same user apart.
+	result, err := r.reviewCollection.InsertOne(ctx, review)
+	if mongo.IsDuplicateKeyError(err) {
+		return services.Conflict("user already reviewed this album")
+	}
+	if err != nil {
+		return storeError(err, "review")
+	}
+	review.ReviewId = result.InsertedID.(primitive.ObjectID).Hex()
+	return r.refreshAlbumRating(ctx, &albumId)
+}
+
+func (r *ReviewImpl) GetAlbumReviews(ctx context.Context, albumId *primitive.ObjectID) ([]models.Review, error) {
+	ctx, cancel := operation(ctx, "review.GetAlbumReviews", read)
+	defer cancel()
+	reviews := []models.Review{}
+	cursor, err := r.reviewCollection.Find(ctx, bson.M{"album_id": albumId.Hex()})
+	if err != nil {
+		return nil, storeError(err, "review")
+	}
+	if err = cursor.All(ctx, &reviews); err != nil {
+		return nil, storeError(err, "review")
+	}
+	return reviews, nil
+}
+
+// GetReviewsByAlbums lists the reviews of any of the given albums, in one
+// query.
+func (r *ReviewImpl) GetReviewsByAlbums(ctx context.Context, albumIds []string) ([]models.Review, error) {
+	ctx, cancel := operation(ctx, "review.GetReviewsByAlbums", read)
+	defer cancel()
+	reviews := []models.Review{}
+	cursor, err := r.reviewCollection.Find(ctx, bson.M{"album_id": bson.M{"$in": albumIds}})
+	if err != nil {
+		return nil, storeError(err, "review")
+	}
+	if err = cursor.All(ctx, &reviews); err != nil {
+		return nil, storeError(err, "review")
+	}
+	return reviews, nil
+}
+
+func (r *ReviewImpl) UpdateReview(ctx context.Context, reviewId *primitive.ObjectID, userId *string, review *models.Review) error {
+	ctx, cancel := operation(ctx, "review.UpdateReview", write)
+	defer cancel()
+	existing, err := r.findOwnReview(ctx, reviewId, userId)
+	if err != nil {
+		return err
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"rating":     review.Rating,
+			"text":       review.Text,
+			"updated_at": time.Now(),
+		},
+	}
+	if _, err := r.reviewCollection.UpdateOne(ctx, bson.M{"_id": reviewId}, update); err != nil {
+		return storeError(err, "review")
+	}
+	if err := r.reviewCollection.FindOne(ctx, bson.M{"_id": reviewId}).Decode(review); err != nil {
+		return storeError(err, "review")
+	}
+	albumId, err := primitive.ObjectIDFromHex(existing.AlbumId)
+	if err != nil {
+		return err
+	}
+	return r.refreshAlbumRating(ctx, &albumId)
+}
+
+func (r *ReviewImpl) DeleteReview(ctx context.Context, reviewId *primitive.ObjectID, userId *string) error {
+	ctx, cancel := operation(ctx, "review.DeleteReview", write)
+	defer cancel()
+	existing, err := r.findOwnReview(ctx, reviewId, userId)
+	if err != nil {
+		return err
+	}
+	if _, err := r.reviewCollection.DeleteOne(ctx, bson.M{"_id": reviewId}); err != nil {
+		return storeError(err, "review")
+	}
+	albumId, err := primitive.ObjectIDFromHex(existing.AlbumId)
+	if err != nil {
+		return err
+	}
+	return r.refreshAlbumRating(ctx, &albumId)
+}
+
+// findOwnReview loads a review and makes sure it was written by userId.
+func (r *ReviewImpl) findOwnReview(ctx context.Context, reviewId *primitive.ObjectID, userId *string) (*models.Review, error) {
+	var review models.Review
+	if err := r.reviewCollection.FindOne(ctx, bson.M{"_id": reviewId}).Decode(&review); err != nil {
+		return nil, storeError(err, "review")
+	}
+	if review.UserId != *userId {
+		return nil, services.Forbidden("review belongs to another user")
+	}
+	return &review, nil
+}
+
+// refreshAlbumRating recomputes the rating average and count stored on the album.
+func (r *ReviewImpl) refreshAlbumRating(ctx context.Context, albumId *primitive.ObjectID) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"album_id": albumId.Hex()}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     nil,
+			"average": bson.M{"$avg": "$rating"},
+			"count":   bson.M{"$sum": 1},
+		}}},
+	}
+	cursor, err := r.reviewCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return storeError(err, "review")
+	}
+	var result []struct {
+		Average float64 `bson:"average"`
+		Count   int     `bson:"count"`
+	}
+	if err = cursor.All(ctx, &result); err != nil {
+		return storeError(err, "review")
+	}
+	rating := bson.M{"rating_average": 0.0, "rating_count": 0}
+	if len(result) > 0 {
+		rating = bson.M{"rating_average": result[0].Average, "rating_count": result[0].Count}
+	}
+	_, err = r.albumCollection.UpdateOne(ctx, bson.M{"_id": albumId}, bson.M{"$set": rating, "$inc": bumpVersion, "$currentDate": touched})
+	return storeError(err, "album")
+}
@@ -0,0 +1,221 @@
+package implements
+
+import (
+	"context"
+	"fmt"
+	"musiclib/models"
+	"musiclib/services"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Weights of each signal used to rank similar tracks.
+const (
+	similarArtistWeight   = 3.0
+	similarGenreWeight    = 2.0
+	similarEraWeight      = 1.0
+	similarAlbumWeight    = 1.5
+	similarListenerWeight = 0.5
+	maxListenerBonus      = 3.0
+)
+
+// maxSimilarTracks is the largest number of similar tracks returned.
+const maxSimilarTracks = 100
+
+// maxSimilarCandidates bounds the tracks ranked for one seed, so that a
+// seed sharing its genre or decade with most of the catalog does not load
+// all of it.
+const maxSimilarCandidates = 1000
+
+// similarCandidate collects the score and reasons of one candidate track.
+type similarCandidate struct {
+	score   float64
+	reasons []string
+}
+
+func (c *similarCandidate) add(weight float64, reason string) {
+	c.score += weight
+	c.reasons = append(c.reasons, reason)
+}
+
+func (t *TrackImpl) FindSimilarTracks(ctx context.Context, trackId *primitive.ObjectID, limit int) ([]models.SimilarTrack, error) {
+	ctx, cancel := operation(ctx, "track.FindSimilarTracks", read)
+	defer cancel()
+	if limit < 1 || limit > maxSimilarTracks {
+		return nil, &services.ValidationError{Field: "limit", Message: "must be between 1 and 100"}
+	}
+	track, err := t.FindTrack(ctx, trackId)
+	if err != nil {
+		return nil, err
+	}
+
+	albumMates, err := t.findAlbumMates(ctx, trackId)
+	if err != nil {
+		return nil, err
+	}
+	coListeners, err := t.findCoListenedTracks(ctx, trackId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only load tracks that share at least one signal with the seed track.
+	// Artists and genres are compared regardless of case, as they are
+	// scored.
+	or := []bson.M{}
+	if track.Artist != "" {
+		or = append(or, bson.M{"artist": exactly(track.Artist)})
+	}
+	if track.Genre != "" {
+		or = append(or, bson.M{"genre": exactly(track.Genre)})
+	}
+	if decade, ok := releaseDecade(track.ReleaseYear); ok {
+		or = append(or, bson.M{"release_year": decadePattern(decade)})
+	}
+	related := make([]primitive.ObjectID, 0, len(albumMates)+len(coListeners))
+	for id := range albumMates {
+		if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+			related = append(related, oid)
+		}
+	}
+	for id := range coListeners {
+		if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+			related = append(related, oid)
+		}
+	}
+	if len(related) > 0 {
+		or = append(or, bson.M{"_id": bson.M{"$in": related}})
+	}
+	if len(or) == 0 {
+		return []models.SimilarTrack{}, nil
+	}
+
+	var tracks []models.Track
+	opts := options.Find().SetLimit(maxSimilarCandidates)
+	cursor, err := t.trackCollection.Find(ctx, live(bson.M{"_id": bson.M{"$ne": trackId}, "$or": or}), opts)
+	if err != nil {
+		return nil, storeError(err, "track")
+	}
+	if err = cursor.All(ctx, &tracks); err != nil {
+		return nil, storeError(err, "track")
+	}
+
+	similar := make([]models.SimilarTrack, 0, len(tracks))
+	for _, candidate := range tracks {
+		c := scoreSimilarTrack(track, &candidate, albumMates[candidate.TrackId], coListeners[candidate.TrackId])
+		if c.score == 0 {
+			continue
+		}
+		similar = append(similar, models.SimilarTrack{Track: candidate, Score: c.score, Reasons: c.reasons})
+	}
+	sort.SliceStable(similar, func(i, j int) bool {
+		return similar[i].Score > similar[j].Score
+	})
+	if len(similar) > limit {
+		similar = similar[:limit]
+	}
+	return similar, nil
+}
+
+// decadePattern matches the release_year values releaseDecade puts in
+// decade, such as "1994" or "1994-05" for 1990.
+func decadePattern(decade int) primitive.Regex {
+	return primitive.Regex{Pattern: fmt.Sprintf(`^\s*%03d\d`, decade/10)}
+}
+
+func scoreSimilarTrack(seed *models.Track, candidate *models.Track, albums []string, listeners int) similarCandidate {
+	var c similarCandidate
+	if seed.Artist != "" && strings.EqualFold(seed.Artist, candidate.Artist) {
+		c.add(similarArtistWeight, fmt.Sprintf("same artist: %s", candidate.Artist))
+	}
+	if seed.Genre != "" && strings.EqualFold(seed.Genre, candidate.Genre) {
+		c.add(similarGenreWeight, fmt.Sprintf("same genre: %s", candidate.Genre))
+	}
+	seedDecade, ok := releaseDecade(seed.ReleaseYear)
+	if candidateDecade, ok2 := releaseDecade(candidate.ReleaseYear); ok && ok2 && seedDecade == candidateDecade {
+		c.add(similarEraWeight, fmt.Sprintf("released in the same era: %ds", seedDecade))
+	}
+	for _, title := range albums {
+		c.add(similarAlbumWeight, fmt.Sprintf("appears on the same album: %s", title))
+	}
+	if listeners > 0 {
+		c.add(min(float64(listeners)*similarListenerWeight, maxListenerBonus),
+			fmt.Sprintf("played by %d of the same listeners", listeners))
+	}
+	return c
+}
+
+// releaseDecade parses the leading year of a release_year value and
+// returns its decade, e.g. "1994" gives 1990.
+func releaseDecade(releaseYear string) (int, bool) {
+	releaseYear = strings.TrimSpace(releaseYear)
+	if len(releaseYear) < 4 {
+		return 0, false
+	}
+	year, err := strconv.Atoi(releaseYear[:4])
+	if err != nil {
+		return 0, false
+	}
+	return year - year%10, true
+}
+
+// findAlbumMates returns, for every track sharing an album with trackId,
+// the titles of the shared albums.
+func (t *TrackImpl) findAlbumMates(ctx context.Context, trackId *primitive.ObjectID) (map[string][]string, error) {
+	var albums []models.Album
+	filter := live(bson.M{"tracks._id": bson.M{"$in": bson.A{trackId.Hex(), *trackId}}})
+	cursor, err := t.albumCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, storeError(err, "album")
+	}
+	if err = cursor.All(ctx, &albums); err != nil {
+		return nil, storeError(err, "album")
+	}
+	mates := map[string][]string{}
+	for _, album := range albums {
+		for _, track := range album.Tracks {
+			if track.TrackId == "" || track.TrackId == trackId.Hex() {
+				continue
+			}
+			mates[track.TrackId] = append(mates[track.TrackId], album.Title)
+		}
+	}
+	return mates, nil
+}
+
+// findCoListenedTracks counts, for every other track, how many users who
+// played trackId also played it. It is empty until plays are recorded.
+func (t *TrackImpl) findCoListenedTracks(ctx context.Context, trackId *primitive.ObjectID) (map[string]int, error) {
+	listeners, err := t.playCollection.Distinct(ctx, "user_id", bson.M{"track_id": trackId.Hex()})
+	if err != nil {
+		return nil, storeError(err, "play")
+	}
+	counts := map[string]int{}
+	if len(listeners) == 0 {
+		return counts, nil
+	}
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"user_id": bson.M{"$in": listeners}, "track_id": bson.M{"$ne": trackId.Hex()}}},
+		bson.M{"$group": bson.M{"_id": "$track_id", "users": bson.M{"$addToSet": "$user_id"}}},
+		bson.M{"$project": bson.M{"listeners": bson.M{"$size": "$users"}}},
+	}
+	cursor, err := t.playCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, storeError(err, "play")
+	}
+	var result []struct {
+		TrackId   string `bson:"_id"`
+		Listeners int    `bson:"listeners"`
+	}
+	if err = cursor.All(ctx, &result); err != nil {
+		return nil, storeError(err, "play")
+	}
+	for _, r := range result {
+		counts[r.TrackId] = r.Listeners
+	}
+	return counts, nil
+}
@@ -0,0 +1,62 @@
+package implements
+
+import (
+	"context"
+	"time"
+)
+
+// Kinds of operations, which have their own default timeout.
+type opKind int
+
+const (
+	read opKind = iota
+	write
+	// bulk operations go through whole collections, such as the chart
+	// computation or the purge of the trash.
+	bulk
+)
+
+// Timeouts bound the service operations. An operation runs within the
+// context of its caller, and gives up after the timeout of its kind unless
+// it has a timeout of its own. A timeout of 0 means none.
+type Timeouts struct {
+	Read  time.Duration
+	Write time.Duration
+	Bulk  time.Duration
+	// Operations are the timeouts of single operations, by resource and
+	// method, such as "album.FindTracksAndAlbums".
+	Operations map[string]time.Duration
+}
+
+var DefaultTimeouts = Timeouts{
+	Read:  5 * time.Second,
+	Write: 10 * time.Second,
+	Bulk:  5 * time.Minute,
+}
+
+var timeouts = DefaultTimeouts
+
+// SetTimeouts sets the timeouts of the operations started from now on.
+func SetTimeouts(t Timeouts) {
+	timeouts = t
+}
+
+// operation is the context of the operation name of kind, started from
+// ctx.
+func operation(ctx context.Context, name string, kind opKind) (context.Context, context.CancelFunc) {
+	timeout, ok := timeouts.Operations[name]
+	if !ok {
+		switch kind {
+		case read:
+			timeout = timeouts.Read
+		case write:
+			timeout = timeouts.Write
+		case bulk:
+			timeout = timeouts.Bulk
+		}
+	}
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
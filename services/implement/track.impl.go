@@ -2,8 +2,16 @@ package implements
 
 import (
 	"context"
+	"log"
+	"musiclib/helper"
 	"musiclib/models"
 	"musiclib/services"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -12,43 +20,389 @@ import (
 
 type TrackImpl struct {
 	trackCollection *mongo.Collection
-	ctx             context.Context
+	albumCollection *mongo.Collection
+	playCollection  *mongo.Collection
+	events          services.EventPublisher
+	audit           services.AuditService
 }
 
-func (t *TrackImpl) CreateTrack(track *models.Track) error {
-	_, err := t.trackCollection.InsertOne(t.ctx, track)
-	return err
+func (t *TrackImpl) CreateTrack(ctx context.Context, track *models.Track) error {
+	ctx, cancel := operation(ctx, "track.CreateTrack", write)
+	defer cancel()
+	track.DurationSeconds, _ = helper.ParseDuration(track.Duration)
+	result, err := t.trackCollection.InsertOne(ctx, track)
+	if err != nil {
+		return storeError(err, "track")
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		track.TrackId = id.Hex()
+	}
+	t.events.Publish(models.EventTrackCreated, track)
+	recordAudit(ctx, t.audit, models.AuditCreate, "track", track.TrackId, nil, track)
+	return nil
 }
-func NewTrackService(trackCollection *mongo.Collection, ctx context.Context) services.TrackService {
+func NewTrackService(trackCollection *mongo.Collection, albumCollection *mongo.Collection, playCollection *mongo.Collection, events services.EventPublisher, audit services.AuditService) services.TrackService {
 	return &TrackImpl{
 		trackCollection: trackCollection,
-		ctx:             ctx,
+		albumCollection: albumCollection,
+		playCollection:  playCollection,
+		events:          events,
+		audit:           audit,
 	}
 }
-func (t *TrackImpl) GetTracks() ([]models.Track, error) {
+
+// publishTrack announces event with the current state of the track, and
+// records action on it in the audit log, from before. The change is
+// already made, so it is announced even if ctx is canceled.
+func (t *TrackImpl) publishTrack(ctx context.Context, event string, action string, trackId *primitive.ObjectID, before *models.Track) {
+	track, err := t.FindTrack(context.WithoutCancel(ctx), trackId)
+	if err != nil {
+		log.Println("err publish", event, err)
+		return
+	}
+	t.events.Publish(event, track)
+	recordAudit(ctx, t.audit, action, "track", trackId.Hex(), before, track)
+}
+func (t *TrackImpl) GetTracks(ctx context.Context) ([]models.Track, error) {
+	ctx, cancel := operation(ctx, "track.GetTracks", read)
+	defer cancel()
 	var tracks []models.Track
-	cursor, err := t.trackCollection.Find(t.ctx, bson.M{})
+	cursor, err := t.trackCollection.Find(ctx, live(bson.M{}))
 	if err != nil {
-		return nil, err
+		return nil, storeError(err, "track")
 	}
-	if err = cursor.All(t.ctx, &tracks); err != nil {
-		return nil, err
+	if err = cursor.All(ctx, &tracks); err != nil {
+		return nil, storeError(err, "track")
 	}
 	return tracks, nil
 }
-func (t *TrackImpl) UpdateTrack(trackId *primitive.ObjectID, track *models.Track) error {
-	filter := bson.M{"_id": trackId}
-	update := bson.M{"$set": track}
-	_, err := t.trackCollection.UpdateOne(t.ctx, filter, update)
-	return err
+
+// EachTrack calls fn with every track, reading them one at a time, until
+// fn fails.
+func (t *TrackImpl) EachTrack(ctx context.Context, fn func(*models.Track) error) error {
+	ctx, cancel := operation(ctx, "track.EachTrack", bulk)
+	defer cancel()
+	cursor, err := t.trackCollection.Find(ctx, live(bson.M{}))
+	if err != nil {
+		return storeError(err, "track")
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var track models.Track
+		if err := cursor.Decode(&track); err != nil {
+			return storeError(err, "track")
+		}
+		if err := fn(&track); err != nil {
+			return err
+		}
+	}
+	return storeError(cursor.Err(), "track")
+}
+
+// UpdateTrack replaces the fields of a track. When version is set the
+// track must still be at that version.
+func (t *TrackImpl) UpdateTrack(ctx context.Context, trackId *primitive.ObjectID, track *models.Track, version *int64) error {
+	ctx, cancel := operation(ctx, "track.UpdateTrack", write)
+	defer cancel()
+	track.DurationSeconds, _ = helper.ParseDuration(track.Duration)
+	track.Version = 0
+	filter := withVersion(live(bson.M{"_id": trackId}), version)
+	update := bson.M{"$set": track, "$inc": bumpVersion, "$currentDate": touched}
+	if track.DurationSeconds == 0 {
+		// omitempty leaves the seconds of the old duration in place.
+		update["$unset"] = bson.M{"duration_seconds": ""}
+	}
+	var before models.Track
+	if err := updateVersioned(ctx, t.trackCollection, filter, update, trackId, "track", &before); err != nil {
+		return err
+	}
+	t.publishTrack(ctx, models.EventTrackUpdated, models.AuditUpdate, trackId, &before)
+	return nil
+}
+
+// PatchTrack changes only the given fields of a track, named by their bson
+// keys, to their value in track. When version is set the track must still
+// be at that version.
+func (t *TrackImpl) PatchTrack(ctx context.Context, trackId *primitive.ObjectID, track *models.Track, fields []string, version *int64) error {
+	ctx, cancel := operation(ctx, "track.PatchTrack", write)
+	defer cancel()
+	set, err := patchFields(track, fields)
+	if err != nil {
+		return err
+	}
+	update := bson.M{"$set": set, "$inc": bumpVersion, "$currentDate": touched}
+	if _, ok := set["duration"]; ok {
+		if seconds, ok := helper.ParseDuration(track.Duration); ok {
+			set["duration_seconds"] = seconds
+		} else {
+			update["$unset"] = bson.M{"duration_seconds": ""}
+		}
+	}
+	filter := withVersion(live(bson.M{"_id": trackId}), version)
+	var before models.Track
+	if err := updateVersioned(ctx, t.trackCollection, filter, update, trackId, "track", &before); err != nil {
+		return err
+	}
+	t.publishTrack(ctx, models.EventTrackUpdated, models.AuditUpdate, trackId, &before)
+	return nil
+}
+
+// DeleteTrack moves a track to the trash. When version is set the track
+// must still be at that version.
+func (t *TrackImpl) DeleteTrack(ctx context.Context, trackId *primitive.ObjectID, version *int64) error {
+	ctx, cancel := operation(ctx, "track.DeleteTrack", write)
+	defer cancel()
+	filter := withVersion(live(bson.M{"_id": trackId}), version)
+	var before models.Track
+	if err := updateVersioned(ctx, t.trackCollection, filter, moveToTrash(), trackId, "track", &before); err != nil {
+		return err
+	}
+	t.events.Publish(models.EventTrackDeleted, map[string]string{"id": trackId.Hex()})
+	recordAudit(ctx, t.audit, models.AuditDelete, "track", trackId.Hex(), &before, nil)
+	return nil
+}
+
+// GetDeletedTracks lists the tracks in the trash, most recently deleted
+// first.
+func (t *TrackImpl) GetDeletedTracks(ctx context.Context) ([]models.Track, error) {
+	ctx, cancel := operation(ctx, "track.GetDeletedTracks", read)
+	defer cancel()
+	tracks := []models.Track{}
+	if err := findTrash(ctx, t.trackCollection, &tracks); err != nil {
+		return nil, storeError(err, "track")
+	}
+	return tracks, nil
+}
+
+// RestoreTrack takes a track out of the trash.
+func (t *TrackImpl) RestoreTrack(ctx context.Context, trackId *primitive.ObjectID) error {
+	ctx, cancel := operation(ctx, "track.RestoreTrack", write)
+	defer cancel()
+	result, err := t.trackCollection.UpdateOne(ctx, trashed(bson.M{"_id": trackId}), restoreFromTrash)
+	if err != nil {
+		return storeError(err, "track")
+	}
+	if result.MatchedCount == 0 {
+		return services.NotFound("track not in trash")
+	}
+	t.publishTrack(ctx, models.EventTrackRestored, models.AuditRestore, trackId, nil)
+	return nil
+}
+
+// PurgeTracks permanently removes the tracks moved to the trash before
+// before.
+func (t *TrackImpl) PurgeTracks(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := operation(ctx, "track.PurgeTracks", bulk)
+	defer cancel()
+	count, err := purgeTrash(ctx, t.trackCollection, before)
+	return count, storeError(err, "track")
+}
+
+func (t *TrackImpl) FindTrack(ctx context.Context, trackId *primitive.ObjectID) (*models.Track, error) {
+	ctx, cancel := operation(ctx, "track.FindTrack", read)
+	defer cancel()
+	var track *models.Track
+	filter := live(bson.M{"_id": trackId})
+	err := t.trackCollection.FindOne(ctx, filter).Decode(&track)
+	return track, storeError(err, "track")
+}
+
+// FindTracks looks up the tracks with the given ids in one query. Ids
+// without a track are left out.
+func (t *TrackImpl) FindTracks(ctx context.Context, trackIds []primitive.ObjectID) ([]models.Track, error) {
+	ctx, cancel := operation(ctx, "track.FindTracks", read)
+	defer cancel()
+	tracks := []models.Track{}
+	cursor, err := t.trackCollection.Find(ctx, live(bson.M{"_id": bson.M{"$in": trackIds}}))
+	if err != nil {
+		return nil, storeError(err, "track")
+	}
+	if err = cursor.All(ctx, &tracks); err != nil {
+		return nil, storeError(err, "track")
+	}
+	return tracks, nil
+}
+
+func (t *TrackImpl) RecordPlay(ctx context.Context, play *models.Play) error {
+	ctx, cancel := operation(ctx, "track.RecordPlay", write)
+	defer cancel()
+	trackId, err := primitive.ObjectIDFromHex(play.TrackId)
+	if err != nil {
+		return err
+	}
+	if err := t.trackCollection.FindOne(ctx, live(bson.M{"_id": trackId})).Err(); err != nil {
+		return storeError(err, "track")
+	}
+	play.PlayedAt = time.Now()
+	result, err := t.playCollection.InsertOne(ctx, play)
+	if err != nil {
+		return storeError(err, "play")
+	}
+	play.PlayId = result.InsertedID.(primitive.ObjectID).Hex()
+	return nil
+}
+
+// FindTrackByFile finds the track stored at path, or failing that a track
+// with the same content hash whose file is gone (a file that was moved). A
+// copy of a file that is still in place is not the same track.
+func (t *TrackImpl) FindTrackByFile(ctx context.Context, path *string, hash *string) (*models.Track, error) {
+	ctx, cancel := operation(ctx, "track.FindTrackByFile", read)
+	defer cancel()
+	var track *models.Track
+	err := t.trackCollection.FindOne(ctx, live(bson.M{"file_name": path})).Decode(&track)
+	if err != mongo.ErrNoDocuments || *hash == "" {
+		return track, storeError(err, "track")
+	}
+	var tracks []models.Track
+	cursor, err := t.trackCollection.Find(ctx, live(bson.M{"file_hash": hash}))
+	if err != nil {
+		return nil, storeError(err, "track")
+	}
+	if err = cursor.All(ctx, &tracks); err != nil {
+		return nil, storeError(err, "track")
+	}
+	for i := range tracks {
+		if tracks[i].Unavailable {
+			return &tracks[i], nil
+		}
+		if _, err := os.Stat(tracks[i].FileName); os.IsNotExist(err) {
+			return &tracks[i], nil
+		}
+	}
+	return nil, services.NotFound("track not found")
 }
-func (t *TrackImpl) DeleteTrack(trackId *primitive.ObjectID) error {
-	_, err := t.trackCollection.DeleteOne(t.ctx, bson.M{"_id": trackId})
+
+func (t *TrackImpl) MarkFileAvailable(ctx context.Context, path *string) error {
+	ctx, cancel := operation(ctx, "track.MarkFileAvailable", write)
+	defer cancel()
+	filter := bson.M{"file_name": path, "unavailable": true}
+	_, err := t.markFiles(ctx, filter, bson.M{"$unset": bson.M{"unavailable": ""}, "$inc": bumpVersion, "$currentDate": touched})
 	return err
 }
-func (t *TrackImpl) FindTrack(trackId *primitive.ObjectID) (*models.Track, error) {
+
+// MarkFilesUnavailable flags the tracks stored at path, or anywhere below it
+// when path was a directory, as unavailable.
+func (t *TrackImpl) MarkFilesUnavailable(ctx context.Context, path *string) (int64, error) {
+	ctx, cancel := operation(ctx, "track.MarkFilesUnavailable", write)
+	defer cancel()
+	below := "^" + regexp.QuoteMeta(strings.TrimSuffix(*path, "/")+"/")
+	filter := bson.M{
+		"$or": []bson.M{
+			{"file_name": path},
+			{"file_name": primitive.Regex{Pattern: below}},
+		},
+		"unavailable": bson.M{"$ne": true},
+	}
+	return t.markFiles(ctx, filter, bson.M{"$set": bson.M{"unavailable": true}, "$inc": bumpVersion, "$currentDate": touched})
+}
+
+// markFiles applies update to the tracks matching filter, which flips
+// their unavailable flag, and records each track it changed in the audit
+// log.
+func (t *TrackImpl) markFiles(ctx context.Context, filter bson.M, update bson.M) (int64, error) {
+	var before []models.Track
+	cursor, err := t.trackCollection.Find(ctx, filter)
+	if err != nil {
+		return 0, storeError(err, "track")
+	}
+	if err = cursor.All(ctx, &before); err != nil {
+		return 0, storeError(err, "track")
+	}
+	if len(before) == 0 {
+		return 0, nil
+	}
+	ids := make([]primitive.ObjectID, 0, len(before))
+	was := make(map[string]*models.Track, len(before))
+	for i := range before {
+		if id, err := primitive.ObjectIDFromHex(before[i].TrackId); err == nil {
+			ids = append(ids, id)
+			was[before[i].TrackId] = &before[i]
+		}
+	}
+	// Tracks changed since they were read no longer match filter and
+	// are left alone.
+	filter["_id"] = bson.M{"$in": ids}
+	result, err := t.trackCollection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, storeError(err, "track")
+	}
+	var after []models.Track
+	cursor, err = t.trackCollection.Find(context.WithoutCancel(ctx), bson.M{"_id": bson.M{"$in": ids}})
+	if err == nil {
+		err = cursor.All(context.WithoutCancel(ctx), &after)
+	}
+	if err != nil {
+		log.Println("err record audit entries of", len(ids), "tracks", err)
+		return result.ModifiedCount, nil
+	}
+	for i := range after {
+		if old := was[after[i].TrackId]; old != nil && old.Unavailable != after[i].Unavailable {
+			recordAudit(ctx, t.audit, models.AuditUpdate, "track", after[i].TrackId, old, &after[i])
+		}
+	}
+	return result.ModifiedCount, nil
+}
+
+// MatchPlaylistEntry finds the track an imported playlist entry refers to:
+// first by path or stream URL, then by title, artist and duration.
+func (t *TrackImpl) MatchPlaylistEntry(ctx context.Context, entry *models.PlaylistEntry) (*models.Track, error) {
+	ctx, cancel := operation(ctx, "track.MatchPlaylistEntry", read)
+	defer cancel()
 	var track *models.Track
-	filter := bson.M{"_id": trackId}
-	err := t.trackCollection.FindOne(t.ctx, filter).Decode(&track)
-	return track, err
+	if location := entryPath(entry.Location); location != "" {
+		filter := bson.M{"file_name": location}
+		if id, err := primitive.ObjectIDFromHex(path.Base(location)); err == nil {
+			filter = bson.M{"$or": []bson.M{{"file_name": location}, {"_id": id}}}
+		}
+		err := t.trackCollection.FindOne(ctx, live(filter)).Decode(&track)
+		if err != mongo.ErrNoDocuments {
+			return track, storeError(err, "track")
+		}
+	}
+	if entry.Title == "" {
+		return nil, services.NotFound("no matching track")
+	}
+	filter := bson.M{"music_title": exactly(entry.Title)}
+	if entry.Artist != "" {
+		filter["artist"] = exactly(entry.Artist)
+	}
+	var tracks []models.Track
+	cursor, err := t.trackCollection.Find(ctx, live(filter))
+	if err != nil {
+		return nil, storeError(err, "track")
+	}
+	if err = cursor.All(ctx, &tracks); err != nil {
+		return nil, storeError(err, "track")
+	}
+	for i := range tracks {
+		known := entry.DurationSeconds > 0 && tracks[i].DurationSeconds > 0
+		if !known || abs(tracks[i].DurationSeconds-entry.DurationSeconds) <= durationTolerance {
+			return &tracks[i], nil
+		}
+	}
+	return nil, services.NotFound("no matching track")
+}
+
+// entryPath turns a playlist location into a plain path.
+func entryPath(location string) string {
+	u, err := url.Parse(location)
+	if err != nil {
+		return location
+	}
+	if u.Scheme == "file" || u.Scheme == "http" || u.Scheme == "https" {
+		return u.Path
+	}
+	return location
+}
+
+// exactly matches s case-insensitively.
+func exactly(s string) primitive.Regex {
+	return primitive.Regex{Pattern: "^" + regexp.QuoteMeta(s) + "$", Options: "i"}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
 }
@@ -0,0 +1,51 @@
+package implements
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// live restricts filter to documents that are not in the trash.
+func live(filter bson.M) bson.M {
+	filter["deleted_at"] = bson.M{"$exists": false}
+	return filter
+}
+
+// trashed matches the documents in the trash.
+func trashed(filter bson.M) bson.M {
+	filter["deleted_at"] = bson.M{"$exists": true}
+	return filter
+}
+
+// moveToTrash is the update of a soft delete.
+func moveToTrash() bson.M {
+	return bson.M{"$set": bson.M{"deleted_at": time.Now()}, "$inc": bumpVersion, "$currentDate": touched}
+}
+
+// restoreFromTrash is the update undoing moveToTrash.
+var restoreFromTrash = bson.M{"$unset": bson.M{"deleted_at": ""}, "$inc": bumpVersion, "$currentDate": touched}
+
+// findTrash decodes the documents of collection in the trash into result,
+// most recently deleted first.
+func findTrash(ctx context.Context, collection *mongo.Collection, result interface{}) error {
+	opts := options.Find().SetSort(bson.M{"deleted_at": -1})
+	cursor, err := collection.Find(ctx, trashed(bson.M{}), opts)
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, result)
+}
+
+// purgeTrash permanently removes the documents of collection deleted
+// before before.
+func purgeTrash(ctx context.Context, collection *mongo.Collection, before time.Time) (int64, error) {
+	result, err := collection.DeleteMany(ctx, bson.M{"deleted_at": bson.M{"$lt": before}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
@@ -3,9 +3,11 @@ package implements
 import (
 	"context"
 	"errors"
+	"log"
 	"musiclib/helper"
 	"musiclib/models"
 	"musiclib/services"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -14,45 +16,88 @@ import (
 
 type UserServiceImpl struct {
 	userCollection *mongo.Collection
-	ctx            context.Context
+	audit          services.AuditService
 }
 
-func NewUserService(userCollection *mongo.Collection, ctx context.Context) services.UserService {
+func NewUserService(userCollection *mongo.Collection, audit services.AuditService) services.UserService {
 	return &UserServiceImpl{
 		userCollection: userCollection,
-		ctx:            ctx,
+		audit:          audit,
 	}
 }
 
-func (u *UserServiceImpl) CreateUser(user *models.User) error {
-	us, err := u.GetUserFromUsername(&user.Username)
+// auditUser records action on the user in the audit log, from before to
+// its current state.
+func (u *UserServiceImpl) auditUser(ctx context.Context, action string, userId *primitive.ObjectID, before *models.User) {
+	hex := userId.Hex()
+	user, err := u.GetUser(context.WithoutCancel(ctx), &hex)
+	if err != nil {
+		log.Println("err record audit entry", action, "user", hex, err)
+		return
+	}
+	recordAudit(ctx, u.audit, action, "user", hex, before, user)
+}
+
+func (u *UserServiceImpl) CreateUser(ctx context.Context, user *models.User) error {
+	ctx, cancel := operation(ctx, "user.CreateUser", write)
+	defer cancel()
+	us, err := u.GetUserFromUsername(ctx, &user.Username)
 	if us != nil {
-		return errors.New("user already exists")
+		return services.Conflict("user already exists")
 	}
-	if err != nil && err != mongo.ErrNoDocuments {
+	if err != nil && !errors.Is(err, services.ErrNotFound) {
 		return err
 	}
-	_, err = u.userCollection.InsertOne(u.ctx, user)
-	return err
+	result, err := u.userCollection.InsertOne(ctx, user)
+	if err != nil {
+		return storeError(err, "user")
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		user.UserId = id.Hex()
+	}
+	recordAudit(ctx, u.audit, models.AuditCreate, "user", user.UserId, nil, user)
+	return nil
 }
 
-func (u *UserServiceImpl) GetUser(userId *string) (*models.User, error) {
+func (u *UserServiceImpl) GetUser(ctx context.Context, userId *string) (*models.User, error) {
+	ctx, cancel := operation(ctx, "user.GetUser", read)
+	defer cancel()
 	var user *models.User
 	id, err := primitive.ObjectIDFromHex(*userId)
 	if err != nil {
-		return nil, err
+		return nil, &services.ValidationError{Field: "id", Message: "must be an id"}
 	}
-	query := bson.D{bson.E{Key: "_id", Value: id}}
-	err = u.userCollection.FindOne(u.ctx, query).Decode(&user)
-	return user, err
+	query := live(bson.M{"_id": id})
+	err = u.userCollection.FindOne(ctx, query).Decode(&user)
+	return user, storeError(err, "user")
 }
 
-func (u *UserServiceImpl) UpdateUser(user *models.User) error {
+// FindUsers looks up the users with the given ids in one query. Ids
+// without a user are left out.
+func (u *UserServiceImpl) FindUsers(ctx context.Context, userIds []primitive.ObjectID) ([]models.User, error) {
+	ctx, cancel := operation(ctx, "user.FindUsers", read)
+	defer cancel()
+	users := []models.User{}
+	cursor, err := u.userCollection.Find(ctx, live(bson.M{"_id": bson.M{"$in": userIds}}))
+	if err != nil {
+		return nil, storeError(err, "user")
+	}
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, storeError(err, "user")
+	}
+	return users, nil
+}
+
+// UpdateUser replaces the username and password of a user. When version is
+// set the user must still be at that version.
+func (u *UserServiceImpl) UpdateUser(ctx context.Context, user *models.User, version *int64) error {
+	ctx, cancel := operation(ctx, "user.UpdateUser", write)
+	defer cancel()
 	id, err := primitive.ObjectIDFromHex(user.UserId)
 	if err != nil {
-		return err
+		return &services.ValidationError{Field: "id", Message: "must be an id"}
 	}
-	filter := bson.D{bson.E{Key: "_id", Value: id}}
+	filter := withVersion(live(bson.M{"_id": id}), version)
 	update := bson.D{
 		bson.E{Key: "$set",
 			Value: bson.D{
@@ -60,30 +105,69 @@ func (u *UserServiceImpl) UpdateUser(user *models.User) error {
 				bson.E{Key: "password", Value: user.Password},
 			},
 		},
+		bson.E{Key: "$inc", Value: bumpVersion},
+		bson.E{Key: "$currentDate", Value: touched},
 	}
-	result, _ := u.userCollection.UpdateOne(u.ctx, filter, update)
-	if result.MatchedCount != 1 {
-		return errors.New("no matched document found for update")
+	var before models.User
+	if err := updateVersioned(ctx, u.userCollection, filter, update, id, "user", &before); err != nil {
+		return err
+	}
+	u.auditUser(ctx, models.AuditUpdate, &id, &before)
+	return nil
+}
+
+// PatchUser changes only the given fields of a user, named by their bson
+// keys. The password must already be hashed. When version is set the user
+// must still be at that version.
+func (u *UserServiceImpl) PatchUser(ctx context.Context, user *models.User, fields []string, version *int64) error {
+	ctx, cancel := operation(ctx, "user.PatchUser", write)
+	defer cancel()
+	id, err := primitive.ObjectIDFromHex(user.UserId)
+	if err != nil {
+		return &services.ValidationError{Field: "id", Message: "must be an id"}
 	}
+	set, err := patchFields(user, fields)
+	if err != nil {
+		return err
+	}
+	if _, ok := set["username"]; ok {
+		existing, err := u.GetUserFromUsername(ctx, &user.Username)
+		if err != nil && !errors.Is(err, services.ErrNotFound) {
+			return err
+		}
+		if existing != nil && existing.UserId != user.UserId {
+			return services.Conflict("user already exists")
+		}
+	}
+	filter := withVersion(live(bson.M{"_id": id}), version)
+	var before models.User
+	update := bson.M{"$set": set, "$inc": bumpVersion, "$currentDate": touched}
+	if err := updateVersioned(ctx, u.userCollection, filter, update, id, "user", &before); err != nil {
+		return err
+	}
+	u.auditUser(ctx, models.AuditUpdate, &id, &before)
 	return nil
 }
 
-func (u *UserServiceImpl) ChangePassword(username *string, OldPassword *string, NewPassword *string) error {
+func (u *UserServiceImpl) ChangePassword(ctx context.Context, username *string, OldPassword *string, NewPassword *string) error {
+	ctx, cancel := operation(ctx, "user.ChangePassword", write)
+	defer cancel()
 	// Retrieve the user by ID
-	filter := bson.D{bson.E{Key: "username", Value: username}}
+	filter := live(bson.M{"username": username})
 	var existingUser *models.User
-	err := u.userCollection.FindOne(u.ctx, filter).Decode(&existingUser)
+	err := u.userCollection.FindOne(ctx, filter).Decode(&existingUser)
 	if err != nil {
-		return err
+		return storeError(err, "user")
 	}
 	// Compare password with password hash
 	if !helper.CheckPassword(existingUser.Password, *OldPassword) {
-		return errors.New("wrong password")
+		return services.Forbidden("wrong password")
 	}
 	hashedPassword, err := helper.HashPassword(*NewPassword)
 	if err != nil {
 		return err
 	}
+	before := *existingUser
 	existingUser.Password = string(hashedPassword)
 
 	update := bson.D{
@@ -93,27 +177,111 @@ func (u *UserServiceImpl) ChangePassword(username *string, OldPassword *string,
 				bson.E{Key: "password", Value: existingUser.Password},
 			},
 		},
+		bson.E{Key: "$inc", Value: bumpVersion},
+		bson.E{Key: "$currentDate", Value: touched},
 	}
 
-	result, _ := u.userCollection.UpdateOne(u.ctx, filter, update)
+	result, err := u.userCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return storeError(err, "user")
+	}
 	if result.MatchedCount != 1 {
-		return errors.New("no matched document found for update")
+		return services.NotFound("user not found")
+	}
+	if id, err := primitive.ObjectIDFromHex(before.UserId); err == nil {
+		u.auditUser(ctx, models.AuditUpdate, &id, &before)
+	}
+	return nil
+}
+
+// SetSubsonicPassword stores the sealed app password a user signs in to the
+// Subsonic API with. An empty password revokes it.
+func (u *UserServiceImpl) SetSubsonicPassword(ctx context.Context, userId *primitive.ObjectID, sealed *string) error {
+	ctx, cancel := operation(ctx, "user.SetSubsonicPassword", write)
+	defer cancel()
+	update := bson.M{"$set": bson.M{"subsonic_password": sealed}, "$inc": bumpVersion, "$currentDate": touched}
+	if *sealed == "" {
+		update = bson.M{"$unset": bson.M{"subsonic_password": ""}, "$inc": bumpVersion, "$currentDate": touched}
 	}
+	var before models.User
+	if err := u.userCollection.FindOneAndUpdate(ctx, live(bson.M{"_id": userId}), update).Decode(&before); err != nil {
+		return storeError(err, "user")
+	}
+	u.auditUser(ctx, models.AuditUpdate, userId, &before)
 	return nil
 }
 
-func (u *UserServiceImpl) DeleteUser(userId *primitive.ObjectID) error {
-	filter := bson.D{bson.E{Key: "_id", Value: userId}}
-	result, _ := u.userCollection.DeleteOne(u.ctx, filter)
-	if result.DeletedCount != 1 {
-		return errors.New("no matched document found for delete")
+// DeleteUser moves a user to the trash, which also stops them from logging
+// in. When version is set the user must still be at that version.
+func (u *UserServiceImpl) DeleteUser(ctx context.Context, userId *primitive.ObjectID, version *int64) error {
+	ctx, cancel := operation(ctx, "user.DeleteUser", write)
+	defer cancel()
+	filter := withVersion(live(bson.M{"_id": userId}), version)
+	var before models.User
+	if err := updateVersioned(ctx, u.userCollection, filter, moveToTrash(), userId, "user", &before); err != nil {
+		return err
+	}
+	recordAudit(ctx, u.audit, models.AuditDelete, "user", userId.Hex(), &before, nil)
+	return nil
+}
+
+// GetDeletedUsers lists the users in the trash, most recently deleted
+// first.
+func (u *UserServiceImpl) GetDeletedUsers(ctx context.Context) ([]models.User, error) {
+	ctx, cancel := operation(ctx, "user.GetDeletedUsers", read)
+	defer cancel()
+	users := []models.User{}
+	if err := findTrash(ctx, u.userCollection, &users); err != nil {
+		return nil, storeError(err, "user")
+	}
+	return users, nil
+}
+
+// RestoreUser takes a user out of the trash, unless their username was
+// taken in the meantime.
+func (u *UserServiceImpl) RestoreUser(ctx context.Context, userId *primitive.ObjectID) error {
+	ctx, cancel := operation(ctx, "user.RestoreUser", write)
+	defer cancel()
+	var user *models.User
+	err := u.userCollection.FindOne(ctx, trashed(bson.M{"_id": userId})).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return services.NotFound("user not in trash")
+	}
+	if err != nil {
+		return storeError(err, "user")
+	}
+	existing, err := u.GetUserFromUsername(ctx, &user.Username)
+	if err != nil && !errors.Is(err, services.ErrNotFound) {
+		return err
 	}
+	if existing != nil {
+		return services.Conflict("username is taken by another user")
+	}
+	result, err := u.userCollection.UpdateOne(ctx, trashed(bson.M{"_id": userId}), restoreFromTrash)
+	if err != nil {
+		return storeError(err, "user")
+	}
+	if result.MatchedCount != 1 {
+		return services.NotFound("user not in trash")
+	}
+	u.auditUser(ctx, models.AuditRestore, userId, nil)
 	return nil
 }
 
-func (u *UserServiceImpl) GetUserFromUsername(username *string) (*models.User, error) {
+// PurgeUsers permanently removes the users moved to the trash before
+// before.
+func (u *UserServiceImpl) PurgeUsers(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := operation(ctx, "user.PurgeUsers", bulk)
+	defer cancel()
+	count, err := purgeTrash(ctx, u.userCollection, before)
+	return count, storeError(err, "user")
+}
+
+func (u *UserServiceImpl) GetUserFromUsername(ctx context.Context, username *string) (*models.User, error) {
+	ctx, cancel := operation(ctx, "user.GetUserFromUsername", read)
+	defer cancel()
 	var user *models.User
-	query := bson.D{bson.E{Key: "username", Value: username}}
-	err := u.userCollection.FindOne(u.ctx, query).Decode(&user)
-	return user, err
+	query := live(bson.M{"username": username})
+	err := u.userCollection.FindOne(ctx, query).Decode(&user)
+	return user, storeError(err, "user")
 }
@@ -0,0 +1,55 @@
+package implements
+
+import (
+	"context"
+	"musiclib/services"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// bumpVersion is the $inc of every write to a versioned document.
+var bumpVersion = bson.M{"version": 1}
+
+// touched is the $currentDate that goes with bumpVersion and stamps the
+// document with the time of the write in updated_at, so that backups can
+// tell what changed since.
+var touched = bson.M{"updated_at": true}
+
+// withVersion restricts filter to the expected version, if any. Documents
+// written before versions existed have no version field and count as 0.
+func withVersion(filter bson.M, version *int64) bson.M {
+	switch {
+	case version == nil:
+	case *version == 0:
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	default:
+		filter["version"] = *version
+	}
+	return filter
+}
+
+// versionMiss explains a write to the document id that matched nothing:
+// either the document is gone or in the trash, or its version has moved
+// on.
+func versionMiss(ctx context.Context, collection *mongo.Collection, id interface{}, resource string) error {
+	count, err := collection.CountDocuments(ctx, live(bson.M{"_id": id}))
+	if err != nil {
+		return storeError(err, resource)
+	}
+	if count == 0 {
+		return services.NotFound(resource + " not found")
+	}
+	return &services.Error{Kind: services.ErrPreconditionFailed, Message: resource + " was changed since version was read"}
+}
+
+// updateVersioned applies update to the document id matching filter and
+// decodes the document as it was before the write into before, for the
+// audit log. A miss is explained by versionMiss.
+func updateVersioned(ctx context.Context, collection *mongo.Collection, filter bson.M, update interface{}, id interface{}, resource string, before interface{}) error {
+	err := collection.FindOneAndUpdate(ctx, filter, update).Decode(before)
+	if err == mongo.ErrNoDocuments {
+		return versionMiss(ctx, collection, id, resource)
+	}
+	return storeError(err, resource)
+}
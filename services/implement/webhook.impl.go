@@ -0,0 +1,356 @@
+package implements
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"musiclib/models"
+	"musiclib/services"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// maxDeliveryAttempts is the number of attempts before a delivery fails.
+	maxDeliveryAttempts = 8
+	// firstRetryDelay is the wait after the first failed attempt; it doubles
+	// with every further attempt.
+	firstRetryDelay = 30 * time.Second
+	// deliveryTimeout bounds the request of a single attempt.
+	deliveryTimeout = 10 * time.Second
+	// claimMargin is added to deliveryTimeout for how long an attempt holds
+	// on to a delivery, so no other process sends it at the same time while
+	// this one still looks up the webhook or records the outcome.
+	claimMargin = 20 * time.Second
+	// deliveryHistory is the number of deliveries listed per webhook.
+	deliveryHistory = 100
+)
+
+type WebhookImpl struct {
+	webhookCollection  *mongo.Collection
+	deliveryCollection *mongo.Collection
+	client             *http.Client
+	wake               chan struct{}
+}
+
+func NewWebhookService(webhookCollection *mongo.Collection, deliveryCollection *mongo.Collection) services.WebhookService {
+	return &WebhookImpl{
+		webhookCollection:  webhookCollection,
+		deliveryCollection: deliveryCollection,
+		client:             &http.Client{Timeout: deliveryTimeout},
+		wake:               make(chan struct{}, 1),
+	}
+}
+
+// Publish queues a delivery of event for every webhook subscribed to it.
+// The change it announces is already made, so failures are only logged.
+func (w *WebhookImpl) Publish(event string, data interface{}) {
+	ctx, cancel := operation(context.Background(), "webhook.Publish", write)
+	defer cancel()
+	if err := w.publish(ctx, event, data); err != nil {
+		log.Println("err publish", event, err)
+	}
+}
+
+func (w *WebhookImpl) publish(ctx context.Context, event string, data interface{}) error {
+	var webhooks []models.Webhook
+	cursor, err := w.webhookCollection.Find(ctx, bson.M{"events": event})
+	if err != nil {
+		return storeError(err, "webhook")
+	}
+	if err = cursor.All(ctx, &webhooks); err != nil {
+		return storeError(err, "webhook")
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+	now := time.Now()
+	payload, err := json.Marshal(struct {
+		Event      string      `json:"event"`
+		OccurredAt time.Time   `json:"occurred_at"`
+		Data       interface{} `json:"data"`
+	}{event, now, data})
+	if err != nil {
+		return err
+	}
+	deliveries := make([]interface{}, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookId:     webhook.WebhookId,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+	if _, err := w.deliveryCollection.InsertMany(ctx, deliveries); err != nil {
+		return storeError(err, "delivery")
+	}
+	w.signal()
+	return nil
+}
+
+func (w *WebhookImpl) signal() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (w *WebhookImpl) Wake() <-chan struct{} {
+	return w.wake
+}
+
+// CreateWebhook saves a webhook with a new random secret.
+func (w *WebhookImpl) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	ctx, cancel := operation(ctx, "webhook.CreateWebhook", write)
+	defer cancel()
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+	webhook.WebhookId = ""
+	webhook.Secret = hex.EncodeToString(secret)
+	webhook.CreatedAt = time.Now()
+	result, err := w.webhookCollection.InsertOne(ctx, webhook)
+	if err != nil {
+		return storeError(err, "webhook")
+	}
+	webhook.WebhookId = result.InsertedID.(primitive.ObjectID).Hex()
+	return nil
+}
+
+// GetWebhooks lists the webhooks without their secrets.
+func (w *WebhookImpl) GetWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	ctx, cancel := operation(ctx, "webhook.GetWebhooks", read)
+	defer cancel()
+	webhooks := []models.Webhook{}
+	opts := options.Find().SetProjection(bson.M{"secret": 0}).SetSort(bson.M{"created_at": 1})
+	cursor, err := w.webhookCollection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, storeError(err, "webhook")
+	}
+	if err = cursor.All(ctx, &webhooks); err != nil {
+		return nil, storeError(err, "webhook")
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook removes a webhook. Its deliveries are kept; the pending
+// ones fail on their next attempt.
+func (w *WebhookImpl) DeleteWebhook(ctx context.Context, webhookId *primitive.ObjectID) error {
+	ctx, cancel := operation(ctx, "webhook.DeleteWebhook", write)
+	defer cancel()
+	result, err := w.webhookCollection.DeleteOne(ctx, bson.M{"_id": webhookId})
+	if err != nil {
+		return storeError(err, "webhook")
+	}
+	if result.DeletedCount == 0 {
+		return services.NotFound("webhook not found")
+	}
+	return nil
+}
+
+// GetDeliveries lists the latest deliveries of a webhook, newest first.
+func (w *WebhookImpl) GetDeliveries(ctx context.Context, webhookId *primitive.ObjectID) ([]models.WebhookDelivery, error) {
+	ctx, cancel := operation(ctx, "webhook.GetDeliveries", read)
+	defer cancel()
+	if err := w.webhookCollection.FindOne(ctx, bson.M{"_id": webhookId}).Err(); err != nil {
+		return nil, storeError(err, "webhook")
+	}
+	deliveries := []models.WebhookDelivery{}
+	opts := options.Find().SetSort(bson.M{"created_at": -1}).SetLimit(deliveryHistory)
+	cursor, err := w.deliveryCollection.Find(ctx, bson.M{"webhook_id": webhookId.Hex()}, opts)
+	if err != nil {
+		return nil, storeError(err, "delivery")
+	}
+	if err = cursor.All(ctx, &deliveries); err != nil {
+		return nil, storeError(err, "delivery")
+	}
+	return deliveries, nil
+}
+
+// ReplayDelivery queues the payload of a past delivery again, as a new
+// delivery to the same webhook.
+func (w *WebhookImpl) ReplayDelivery(ctx context.Context, deliveryId *primitive.ObjectID) (*models.WebhookDelivery, error) {
+	ctx, cancel := operation(ctx, "webhook.ReplayDelivery", write)
+	defer cancel()
+	var original models.WebhookDelivery
+	if err := w.deliveryCollection.FindOne(ctx, bson.M{"_id": deliveryId}).Decode(&original); err != nil {
+		return nil, storeError(err, "delivery")
+	}
+	webhookId, err := primitive.ObjectIDFromHex(original.WebhookId)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.webhookCollection.FindOne(ctx, bson.M{"_id": webhookId}).Err(); err != nil {
+		return nil, storeError(err, "webhook")
+	}
+	now := time.Now()
+	replay := models.WebhookDelivery{
+		WebhookId:     original.WebhookId,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: now,
+		ReplayOf:      original.DeliveryId,
+		CreatedAt:     now,
+	}
+	result, err := w.deliveryCollection.InsertOne(ctx, replay)
+	if err != nil {
+		return nil, storeError(err, "delivery")
+	}
+	replay.DeliveryId = result.InsertedID.(primitive.ObjectID).Hex()
+	w.signal()
+	return &replay, nil
+}
+
+// DeliverPending sends every delivery that is due. Each webhook gets its
+// deliveries one after the other, in its own goroutine, so a slow endpoint
+// only holds up its own deliveries.
+func (w *WebhookImpl) DeliverPending(ctx context.Context) error {
+	ctx, cancel := operation(ctx, "webhook.DeliverPending", bulk)
+	defer cancel()
+	for {
+		filter := bson.M{"status": models.DeliveryPending, "next_attempt_at": bson.M{"$lte": time.Now()}}
+		webhookIds, err := w.deliveryCollection.Distinct(ctx, "webhook_id", filter)
+		if err != nil {
+			return storeError(err, "delivery")
+		}
+		if len(webhookIds) == 0 {
+			return nil
+		}
+		var wg sync.WaitGroup
+		errs := make([]error, len(webhookIds))
+		for i, webhookId := range webhookIds {
+			webhookId, ok := webhookId.(string)
+			if !ok {
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = w.deliverTo(ctx, webhookId)
+			}()
+		}
+		wg.Wait()
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+	}
+}
+
+// deliverTo sends the due deliveries of one webhook, oldest first.
+func (w *WebhookImpl) deliverTo(ctx context.Context, webhookId string) error {
+	for {
+		// Claim the next due delivery by moving its next attempt past the
+		// end of this one, webhook lookup included.
+		now := time.Now()
+		filter := bson.M{"webhook_id": webhookId, "status": models.DeliveryPending, "next_attempt_at": bson.M{"$lte": now}}
+		claim := bson.M{"$set": bson.M{"next_attempt_at": now.Add(deliveryTimeout + claimMargin)}}
+		opts := options.FindOneAndUpdate().SetSort(bson.M{"next_attempt_at": 1})
+		var delivery models.WebhookDelivery
+		err := w.deliveryCollection.FindOneAndUpdate(ctx, filter, claim, opts).Decode(&delivery)
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		if err != nil {
+			return storeError(err, "delivery")
+		}
+		if err := w.attempt(ctx, &delivery); err != nil {
+			return err
+		}
+	}
+}
+
+// attempt sends a delivery once and records the outcome.
+func (w *WebhookImpl) attempt(ctx context.Context, delivery *models.WebhookDelivery) error {
+	delivery.Attempts++
+	status, err := w.send(ctx, delivery)
+	set := bson.M{"attempts": delivery.Attempts, "response_status": status}
+	switch {
+	case err == nil:
+		set["status"] = models.DeliveryDelivered
+		set["delivered_at"] = time.Now()
+		set["last_error"] = ""
+	case delivery.Attempts >= maxDeliveryAttempts:
+		set["status"] = models.DeliveryFailed
+		set["last_error"] = err.Error()
+	default:
+		set["next_attempt_at"] = time.Now().Add(firstRetryDelay << (delivery.Attempts - 1))
+		set["last_error"] = err.Error()
+	}
+	id, err := primitive.ObjectIDFromHex(delivery.DeliveryId)
+	if err != nil {
+		return err
+	}
+	_, err = w.deliveryCollection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set, "$currentDate": touched})
+	return storeError(err, "delivery")
+}
+
+// send posts the payload of a delivery to its webhook, and returns the
+// response status. Anything but a 2xx is an error.
+func (w *WebhookImpl) send(ctx context.Context, delivery *models.WebhookDelivery) (int, error) {
+	webhookId, err := primitive.ObjectIDFromHex(delivery.WebhookId)
+	if err != nil {
+		return 0, err
+	}
+	var webhook models.Webhook
+	err = w.webhookCollection.FindOne(ctx, bson.M{"_id": webhookId}).Decode(&webhook)
+	if err == mongo.ErrNoDocuments {
+		// There is nothing left to retry.
+		delivery.Attempts = maxDeliveryAttempts
+		return 0, errors.New("webhook was deleted")
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "musiclib-webhooks")
+	request.Header.Set("X-Musiclib-Event", delivery.Event)
+	request.Header.Set("X-Musiclib-Delivery", delivery.DeliveryId)
+	request.Header.Set("X-Musiclib-Timestamp", timestamp)
+	request.Header.Set("X-Musiclib-Signature", "sha256="+sign(webhook.Secret, timestamp, delivery.Payload))
+	response, err := w.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 1<<16))
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("endpoint answered %s", response.Status)
+	}
+	return response.StatusCode, nil
+}
+
+// sign is the HMAC-SHA256 of "timestamp.payload" with the webhook secret,
+// hex encoded. Receivers recompute it to check a delivery.
+func sign(secret string, timestamp string, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
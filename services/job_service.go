@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"musiclib/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type JobService interface {
+	// Enqueue queues a job. A job with a Key is not queued again while
+	// another job with that key is queued or running; that job is returned
+	// in its place.
+	Enqueue(context.Context, *models.Job) error
+	FindJob(context.Context, *primitive.ObjectID) (*models.Job, error)
+	// FindLatestSucceeded returns the job of a type that succeeded last.
+	FindLatestSucceeded(context.Context, *string) (*models.Job, error)
+	// GetJobs lists the latest jobs, of a status if it is not empty.
+	GetJobs(context.Context, *string) ([]models.Job, error)
+	// RetryJob queues a dead job again with fresh attempts. It fails with
+	// ErrConflict while another job with its key is queued or running.
+	RetryJob(context.Context, *primitive.ObjectID) (*models.Job, error)
+	// Claim leases the next due job of one of the types to owner for the
+	// lease duration, or returns nil if there is none. Running jobs whose
+	// lease expired are due again.
+	Claim(context.Context, string, []string, time.Duration) (*models.Job, error)
+	// ExtendLease renews the lease of owner on a running job. It returns
+	// ErrNotFound once owner has lost the job.
+	ExtendLease(context.Context, *primitive.ObjectID, string, time.Duration) error
+	// Complete records the result of a job owner ran.
+	Complete(context.Context, *primitive.ObjectID, string, []byte) error
+	// Fail records a failed attempt of owner: the job is queued again at
+	// retryAt, or dead if retryAt is nil.
+	Fail(context.Context, *primitive.ObjectID, string, string, *time.Time) error
+	// Wake is signalled when jobs were queued.
+	Wake() <-chan struct{}
+}
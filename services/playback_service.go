@@ -0,0 +1,11 @@
+package services
+
+import (
+	"context"
+	"musiclib/models"
+)
+
+type PlaybackService interface {
+	GetPlaybackState(context.Context, *string) (*models.PlaybackState, error)
+	SavePlaybackState(context.Context, *models.PlaybackState) error
+}
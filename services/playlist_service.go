@@ -0,0 +1,17 @@
+package services
+
+import (
+	"context"
+	"musiclib/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PlaylistService interface {
+	CreatePlaylist(context.Context, *models.Playlist) error
+	FindPlaylist(context.Context, *primitive.ObjectID) (*models.Playlist, []models.Track, error)
+	GetUserPlaylists(context.Context, *string) ([]models.Playlist, error)
+	UpdatePlaylist(context.Context, *primitive.ObjectID, *string, *models.Playlist) error
+	DeletePlaylist(context.Context, *primitive.ObjectID, *string) error
+	PreviewPlaylist(context.Context, *models.SmartRules) ([]models.Track, error)
+}
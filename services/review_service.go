@@ -0,0 +1,16 @@
+package services
+
+import (
+	"context"
+	"musiclib/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ReviewService interface {
+	CreateReview(context.Context, *models.Review) error
+	GetAlbumReviews(context.Context, *primitive.ObjectID) ([]models.Review, error)
+	GetReviewsByAlbums(context.Context, []string) ([]models.Review, error)
+	UpdateReview(context.Context, *primitive.ObjectID, *string, *models.Review) error
+	DeleteReview(context.Context, *primitive.ObjectID, *string) error
+}
@@ -1,15 +1,29 @@
 package services
 
 import (
+	"context"
 	"musiclib/models"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type TrackService interface {
-	CreateTrack(*models.Track) error
-	GetTracks() ([]models.Track, error)
-	FindTrack(*primitive.ObjectID) (*models.Track, error)
-	UpdateTrack(*primitive.ObjectID, *models.Track) error
-	DeleteTrack(*primitive.ObjectID) error
+	CreateTrack(context.Context, *models.Track) error
+	GetTracks(context.Context) ([]models.Track, error)
+	EachTrack(context.Context, func(*models.Track) error) error
+	FindTrack(context.Context, *primitive.ObjectID) (*models.Track, error)
+	FindTracks(context.Context, []primitive.ObjectID) ([]models.Track, error)
+	UpdateTrack(context.Context, *primitive.ObjectID, *models.Track, *int64) error
+	PatchTrack(context.Context, *primitive.ObjectID, *models.Track, []string, *int64) error
+	DeleteTrack(context.Context, *primitive.ObjectID, *int64) error
+	GetDeletedTracks(context.Context) ([]models.Track, error)
+	RestoreTrack(context.Context, *primitive.ObjectID) error
+	PurgeTracks(context.Context, time.Time) (int64, error)
+	FindSimilarTracks(context.Context, *primitive.ObjectID, int) ([]models.SimilarTrack, error)
+	RecordPlay(context.Context, *models.Play) error
+	FindTrackByFile(context.Context, *string, *string) (*models.Track, error)
+	MarkFileAvailable(context.Context, *string) error
+	MarkFilesUnavailable(context.Context, *string) (int64, error)
+	MatchPlaylistEntry(context.Context, *models.PlaylistEntry) (*models.Track, error)
 }
@@ -1,16 +1,24 @@
 package services
 
 import (
+	"context"
 	"musiclib/models"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type UserService interface {
-	CreateUser(*models.User) error
-	GetUser(*string) (*models.User, error)
-	UpdateUser(*models.User) error
-	ChangePassword(*string, *string, *string) error
-	DeleteUser(*primitive.ObjectID) error
-	GetUserFromUsername(*string) (*models.User, error)
+	CreateUser(context.Context, *models.User) error
+	GetUser(context.Context, *string) (*models.User, error)
+	FindUsers(context.Context, []primitive.ObjectID) ([]models.User, error)
+	UpdateUser(context.Context, *models.User, *int64) error
+	PatchUser(context.Context, *models.User, []string, *int64) error
+	ChangePassword(context.Context, *string, *string, *string) error
+	SetSubsonicPassword(context.Context, *primitive.ObjectID, *string) error
+	DeleteUser(context.Context, *primitive.ObjectID, *int64) error
+	GetDeletedUsers(context.Context) ([]models.User, error)
+	RestoreUser(context.Context, *primitive.ObjectID) error
+	PurgeUsers(context.Context, time.Time) (int64, error)
+	GetUserFromUsername(context.Context, *string) (*models.User, error)
 }
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"context"
+	"musiclib/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EventPublisher is how the catalog services announce their changes. data
+// is sent as the data field of the event.
+type EventPublisher interface {
+	Publish(event string, data interface{})
+}
+
+type WebhookService interface {
+	EventPublisher
+	CreateWebhook(context.Context, *models.Webhook) error
+	GetWebhooks(context.Context) ([]models.Webhook, error)
+	DeleteWebhook(context.Context, *primitive.ObjectID) error
+	GetDeliveries(context.Context, *primitive.ObjectID) ([]models.WebhookDelivery, error)
+	ReplayDelivery(context.Context, *primitive.ObjectID) (*models.WebhookDelivery, error)
+	// DeliverPending sends every delivery that is due.
+	DeliverPending(context.Context) error
+	// Wake is signalled when new deliveries were queued.
+	Wake() <-chan struct{}
+}
@@ -0,0 +1,327 @@
+package subsonic
+
+import (
+	"encoding/hex"
+	"log"
+	"mime"
+	"musiclib/models"
+	"musiclib/services"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// variousArtists is the artist of an album whose tracks have different
+// artists.
+const variousArtists = "Various Artists"
+
+// artistId is the id of the artist name; artists are not stored, so the
+// name itself is the id.
+func artistId(name string) string {
+	return "ar-" + hex.EncodeToString([]byte(name))
+}
+
+func artistName(id string) (string, bool) {
+	encoded, ok := strings.CutPrefix(id, "ar-")
+	if !ok {
+		return "", false
+	}
+	name, err := hex.DecodeString(encoded)
+	if err != nil {
+		return "", false
+	}
+	return string(name), true
+}
+
+// albumArtist is the artist of the tracks of album, or variousArtists.
+func albumArtist(album *models.Album) string {
+	if len(album.Tracks) == 0 {
+		return ""
+	}
+	artist := album.Tracks[0].Artist
+	for _, track := range album.Tracks[1:] {
+		if track.Artist != artist {
+			return variousArtists
+		}
+	}
+	return artist
+}
+
+// created is when the document id was made.
+func created(id string) string {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ""
+	}
+	return objectId.Timestamp().UTC().Format(time.RFC3339)
+}
+
+func year(release string) int {
+	if len(release) > 4 {
+		release = release[:4]
+	}
+	y, _ := strconv.Atoi(release)
+	return y
+}
+
+func toAlbum(album *models.Album) Album {
+	result := Album{
+		Id:        album.AlbumId,
+		Name:      album.Title,
+		CoverArt:  album.AlbumId,
+		SongCount: len(album.Tracks),
+		Created:   created(album.AlbumId),
+	}
+	if artist := albumArtist(album); artist != "" {
+		result.Artist = artist
+		result.ArtistId = artistId(artist)
+	}
+	for i, track := range album.Tracks {
+		result.Duration += track.DurationSeconds
+		if i == 0 {
+			result.Year = year(track.ReleaseYear)
+			result.Genre = track.Genre
+		}
+	}
+	return result
+}
+
+// toSong maps track; album is the album it is listed under, if any.
+func toSong(track *models.Track, album *models.Album, number int) Song {
+	suffix := strings.TrimPrefix(strings.ToLower(filepath.Ext(track.FileName)), ".")
+	song := Song{
+		Id:          track.TrackId,
+		Title:       track.Title,
+		Artist:      track.Artist,
+		Track:       number,
+		Year:        year(track.ReleaseYear),
+		Genre:       track.Genre,
+		ContentType: mime.TypeByExtension("." + suffix),
+		Suffix:      suffix,
+		Duration:    track.DurationSeconds,
+		ArtistId:    artistId(track.Artist),
+		Type:        "music",
+	}
+	if song.ContentType == "" {
+		song.ContentType = "application/octet-stream"
+	}
+	if album != nil {
+		song.Parent = album.AlbumId
+		song.Album = album.Title
+		song.AlbumId = album.AlbumId
+		song.CoverArt = album.AlbumId
+		song.Path = album.Title + "/" + track.Title
+		if suffix != "" {
+			song.Path += "." + suffix
+		}
+	}
+	return song
+}
+
+// artists groups albums by their artist, in name order.
+func artists(albums []models.Album) ([]Artist, map[string][]models.Album) {
+	byArtist := map[string][]models.Album{}
+	for _, album := range albums {
+		if artist := albumArtist(&album); artist != "" {
+			byArtist[artist] = append(byArtist[artist], album)
+		}
+	}
+	result := make([]Artist, 0, len(byArtist))
+	for name, albums := range byArtist {
+		result = append(result, Artist{
+			Id:         artistId(name),
+			Name:       name,
+			AlbumCount: len(albums),
+			CoverArt:   albums[0].AlbumId,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Name) < strings.ToLower(result[j].Name)
+	})
+	return result, byArtist
+}
+
+func (c *Controller) getMusicFolders(ctx *gin.Context) {
+	response := newResponse()
+	response.MusicFolders = &MusicFolders{MusicFolder: []MusicFolder{{Id: 1, Name: "Music"}}}
+	write(ctx, response)
+}
+
+func (c *Controller) getArtists(ctx *gin.Context) {
+	sortBy := services.AlbumSortTitle
+	albums, err := c.albumService.GetAlbums(ctx.Request.Context(), &sortBy)
+	if err != nil {
+		serviceFail(ctx, err)
+		return
+	}
+	all, _ := artists(albums)
+	byLetter := map[string][]Artist{}
+	for _, artist := range all {
+		letter := "#"
+		if first, _ := utf8.DecodeRuneInString(artist.Name); unicode.IsLetter(first) {
+			letter = string(unicode.ToUpper(first))
+		}
+		byLetter[letter] = append(byLetter[letter], artist)
+	}
+	index := make([]Index, 0, len(byLetter))
+	for letter, artists := range byLetter {
+		index = append(index, Index{Name: letter, Artist: artists})
+	}
+	sort.Slice(index, func(i, j int) bool { return index[i].Name < index[j].Name })
+	response := newResponse()
+	response.Artists = &Artists{IgnoredArticles: "", Index: index}
+	write(ctx, response)
+}
+
+func (c *Controller) getArtist(ctx *gin.Context) {
+	name, ok := artistName(ctx.Request.FormValue("id"))
+	if !ok {
+		fail(ctx, errNotFound, "artist not found")
+		return
+	}
+	sortBy := services.AlbumSortTitle
+	albums, err := c.albumService.GetAlbums(ctx.Request.Context(), &sortBy)
+	if err != nil {
+		serviceFail(ctx, err)
+		return
+	}
+	all, byArtist := artists(albums)
+	for _, artist := range all {
+		if artist.Name != name {
+			continue
+		}
+		result := &ArtistWithAlbums{Artist: artist, Album: []Album{}}
+		for _, album := range byArtist[name] {
+			result.Album = append(result.Album, toAlbum(&album))
+		}
+		response := newResponse()
+		response.Artist = result
+		write(ctx, response)
+		return
+	}
+	fail(ctx, errNotFound, "artist not found")
+}
+
+func (c *Controller) getAlbum(ctx *gin.Context) {
+	id, ok := objectId(ctx, "id")
+	if !ok {
+		return
+	}
+	album, err := c.albumService.FindAlbum(ctx.Request.Context(), id)
+	if err != nil {
+		serviceFail(ctx, err)
+		return
+	}
+	result := &AlbumWithSongs{Album: toAlbum(album), Song: []Song{}}
+	for i, track := range album.Tracks {
+		result.Song = append(result.Song, toSong(&track, album, i+1))
+	}
+	response := newResponse()
+	response.Album = result
+	write(ctx, response)
+}
+
+// page reads the count and offset parameters named prefix+"Count" and
+// prefix+"Offset".
+func page(ctx *gin.Context, prefix string) (int, int) {
+	count, err := strconv.Atoi(ctx.Request.FormValue(prefix + "Count"))
+	if err != nil || count < 0 {
+		count = 20
+	}
+	offset, err := strconv.Atoi(ctx.Request.FormValue(prefix + "Offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	return count, offset
+}
+
+func paged[T any](items []T, count int, offset int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	items = items[offset:]
+	if count < len(items) {
+		items = items[:count]
+	}
+	return items
+}
+
+// search3 matches the query against artists, album titles and track
+// titles. An empty query, or "", lists everything, as clients do to sync
+// the library.
+func (c *Controller) search3(ctx *gin.Context) {
+	query := strings.Trim(strings.TrimSpace(ctx.Request.FormValue("query")), `"`)
+	sortBy := services.AlbumSortTitle
+	allAlbums, err := c.albumService.GetAlbums(ctx.Request.Context(), &sortBy)
+	if err != nil {
+		serviceFail(ctx, err)
+		return
+	}
+	var albums []models.Album
+	var tracks []models.Track
+	if query == "" {
+		albums = allAlbums
+		if tracks, err = c.trackService.GetTracks(ctx.Request.Context()); err != nil {
+			serviceFail(ctx, err)
+			return
+		}
+	} else {
+		pattern := regexp.QuoteMeta(query)
+		if albums, tracks, err = c.albumService.FindTracksAndAlbums(ctx.Request.Context(), &pattern); err != nil {
+			serviceFail(ctx, err)
+			return
+		}
+	}
+
+	all, _ := artists(allAlbums)
+	matchedArtists := []Artist{}
+	for _, artist := range all {
+		if strings.Contains(strings.ToLower(artist.Name), strings.ToLower(query)) {
+			matchedArtists = append(matchedArtists, artist)
+		}
+	}
+	trackIds := make([]string, 0, len(tracks))
+	for _, track := range tracks {
+		trackIds = append(trackIds, track.TrackId)
+	}
+	parents := map[string]*models.Album{}
+	if len(trackIds) > 0 {
+		trackAlbums, err := c.albumService.FindAlbumsByTracks(ctx.Request.Context(), trackIds)
+		if err != nil {
+			log.Println("err subsonic search albums of tracks", err)
+		}
+		for i := range trackAlbums {
+			for _, track := range trackAlbums[i].Tracks {
+				if _, ok := parents[track.TrackId]; !ok {
+					parents[track.TrackId] = &trackAlbums[i]
+				}
+			}
+		}
+	}
+
+	artistCount, artistOffset := page(ctx, "artist")
+	albumCount, albumOffset := page(ctx, "album")
+	songCount, songOffset := page(ctx, "song")
+	result := &SearchResult3{
+		Artist: paged(matchedArtists, artistCount, artistOffset),
+		Album:  []Album{},
+		Song:   []Song{},
+	}
+	for _, album := range paged(albums, albumCount, albumOffset) {
+		result.Album = append(result.Album, toAlbum(&album))
+	}
+	for _, track := range paged(tracks, songCount, songOffset) {
+		result.Song = append(result.Song, toSong(&track, parents[track.TrackId], 0))
+	}
+	response := newResponse()
+	response.SearchResult3 = result
+	write(ctx, response)
+}
@@ -0,0 +1,235 @@
+// Package subsonic serves the Subsonic API, with the OpenSubsonic
+// additions, on top of the services, so Subsonic clients can use musiclib
+// as their server. Artists are the artists of the tracks, there is one
+// music folder, and playlists are the smart playlists of the user.
+package subsonic
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log"
+	"musiclib/helper"
+	"musiclib/models"
+	"musiclib/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const userKey = "subsonicUser"
+
+type Controller struct {
+	trackService    services.TrackService
+	albumService    services.AlbumService
+	userService     services.UserService
+	playlistService services.PlaylistService
+	// secret seals the app passwords.
+	secret []byte
+}
+
+func NewController(trackService services.TrackService, albumService services.AlbumService, userService services.UserService, playlistService services.PlaylistService, secret []byte) *Controller {
+	return &Controller{
+		trackService:    trackService,
+		albumService:    albumService,
+		userService:     userService,
+		playlistService: playlistService,
+		secret:          secret,
+	}
+}
+
+// authenticate checks the credentials every Subsonic request carries: u
+// with either p, the app password in clear or as enc:<hex>, or t and s, the
+// MD5 of the app password followed by the salt s. The account password is
+// never accepted, since p ends up in URLs and logs.
+func (c *Controller) authenticate(ctx *gin.Context) {
+	username := ctx.Request.FormValue("u")
+	if username == "" {
+		fail(ctx, errMissingParam, "required parameter is missing: u")
+		return
+	}
+	user, err := c.userService.GetUserFromUsername(ctx.Request.Context(), &username)
+	if errors.Is(err, services.ErrNotFound) {
+		fail(ctx, errWrongCredential, "wrong username or password")
+		return
+	}
+	if err != nil {
+		log.Println("err subsonic auth", err)
+		fail(ctx, errGeneric, "could not check the credentials")
+		return
+	}
+	appPassword := ""
+	if user.SubsonicPassword != "" {
+		if appPassword, err = helper.Open(c.secret, user.SubsonicPassword); err != nil {
+			log.Println("err open subsonic password", user.UserId, err)
+		}
+	}
+
+	password, token, salt := ctx.Request.FormValue("p"), ctx.Request.FormValue("t"), ctx.Request.FormValue("s")
+	if password == "" && (token == "" || salt == "") {
+		fail(ctx, errMissingParam, "required parameter is missing: p, or t and s")
+		return
+	}
+	if appPassword == "" {
+		fail(ctx, errWrongCredential, "Subsonic clients sign in with an app password, create one with POST /v1/subsonic/password")
+		return
+	}
+	if password != "" {
+		if encoded, ok := strings.CutPrefix(password, "enc:"); ok {
+			decoded, err := hex.DecodeString(encoded)
+			if err != nil {
+				fail(ctx, errWrongCredential, "wrong username or password")
+				return
+			}
+			password = string(decoded)
+		}
+		if subtle.ConstantTimeCompare([]byte(password), []byte(appPassword)) != 1 {
+			fail(ctx, errWrongCredential, "wrong username or password")
+			return
+		}
+	} else {
+		sum := md5.Sum([]byte(appPassword + salt))
+		if subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(strings.ToLower(token))) != 1 {
+			fail(ctx, errWrongCredential, "wrong username or password")
+			return
+		}
+	}
+	ctx.Set(userKey, user)
+	ctx.Next()
+}
+
+func currentUser(ctx *gin.Context) *models.User {
+	return ctx.MustGet(userKey).(*models.User)
+}
+
+// handlers are the Subsonic methods served, by name.
+func (c *Controller) handlers() map[string]gin.HandlerFunc {
+	return map[string]gin.HandlerFunc{
+		"ping":                      c.ping,
+		"getLicense":                c.getLicense,
+		"getOpenSubsonicExtensions": c.getOpenSubsonicExtensions,
+		"getMusicFolders":           c.getMusicFolders,
+		"getArtists":                c.getArtists,
+		"getArtist":                 c.getArtist,
+		"getAlbum":                  c.getAlbum,
+		"search3":                   c.search3,
+		"stream":                    c.stream,
+		"download":                  c.stream,
+		"getCoverArt":               c.getCoverArt,
+		"scrobble":                  c.scrobble,
+		"getPlaylists":              c.getPlaylists,
+		"getPlaylist":               c.getPlaylist,
+		"createPlaylist":            c.createPlaylist,
+		"updatePlaylist":            c.updatePlaylist,
+		"deletePlaylist":            c.deletePlaylist,
+	}
+}
+
+// RegisterSubsonicRouter serves the Subsonic methods under /rest, as
+// /rest/<method> or /rest/<method>.view, with GET or a form POST.
+func (c *Controller) RegisterSubsonicRouter(r *gin.Engine) {
+	handlers := c.handlers()
+	dispatch := func(ctx *gin.Context) {
+		name := strings.TrimSuffix(ctx.Param("method"), ".view")
+		handler, ok := handlers[name]
+		if !ok {
+			fail(ctx, errNotFound, "unknown method "+name)
+			return
+		}
+		handler(ctx)
+	}
+	router := r.Group("/rest", c.authenticate)
+	router.GET("/:method", dispatch)
+	router.POST("/:method", dispatch)
+}
+
+func (c *Controller) ping(ctx *gin.Context) {
+	write(ctx, newResponse())
+}
+
+func (c *Controller) getLicense(ctx *gin.Context) {
+	response := newResponse()
+	response.License = &License{Valid: true}
+	write(ctx, response)
+}
+
+func (c *Controller) getOpenSubsonicExtensions(ctx *gin.Context) {
+	response := newResponse()
+	response.OpenSubsonicExtensions = []Extension{{Name: "formPost", Versions: []int{1}}}
+	write(ctx, response)
+}
+
+// CreatePassword godoc
+// @Summary      CreatePassword
+// @Description  Create the app password the current user signs in to the Subsonic API (/rest) with, replacing the previous one. It is shown only once.
+// @Tags         subsonic
+// @Produce      json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Router       /subsonic/password [post]
+func (c *Controller) CreatePassword(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(currentUserId(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	secret := make([]byte, 12)
+	if _, err := rand.Read(secret); err != nil {
+		ctx.Error(err)
+		return
+	}
+	password := hex.EncodeToString(secret)
+	sealed, err := helper.Seal(c.secret, password)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	if err := c.userService.SetSubsonicPassword(ctx.Request.Context(), &id, &sealed); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"subsonic_password": password})
+}
+
+// DeletePassword godoc
+// @Summary      DeletePassword
+// @Description  Revoke the Subsonic app password of the current user
+// @Tags         subsonic
+// @Produce      json
+// @Security ApiKeyAuth
+// @param Authorization header string true "Authorization"
+// @Router       /subsonic/password [delete]
+func (c *Controller) DeletePassword(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(currentUserId(ctx))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	revoked := ""
+	if err := c.userService.SetSubsonicPassword(ctx.Request.Context(), &id, &revoked); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"message": "subsonic password revoked"})
+}
+
+// currentUserId is the user of the JWT middleware.
+func currentUserId(ctx *gin.Context) string {
+	user, ok := ctx.Get("userId")
+	if !ok {
+		return ""
+	}
+	return user.(*models.User).UserId
+}
+
+// RegisterPasswordRouter registers the app password routes, which need the
+// JWT middleware of rt.
+func (c *Controller) RegisterPasswordRouter(rt *gin.RouterGroup) {
+	router := rt.Group("/subsonic")
+	router.POST("/password", c.CreatePassword)
+	router.DELETE("/password", c.DeletePassword)
+}
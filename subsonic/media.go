@@ -0,0 +1,147 @@
+package subsonic
+
+import (
+	"errors"
+	"log"
+	"musiclib/helper"
+	"musiclib/models"
+	"musiclib/services"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// serviceFail answers the Subsonic error of a service error.
+func serviceFail(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		fail(ctx, errNotFound, err.Error())
+	case errors.Is(err, services.ErrForbidden):
+		fail(ctx, errNotAuthorized, err.Error())
+	case errors.Is(err, services.ErrCanceled):
+		ctx.Abort()
+	case errors.Is(err, services.ErrTimeout):
+		log.Println("err subsonic", ctx.Param("method"), err)
+		fail(ctx, errGeneric, err.Error())
+	default:
+		log.Println("err subsonic", ctx.Param("method"), err)
+		fail(ctx, errGeneric, "internal error")
+	}
+}
+
+// objectId reads the id parameter name, answering an error if it is
+// missing or not an id.
+func objectId(ctx *gin.Context, name string) (*primitive.ObjectID, bool) {
+	value := ctx.Request.FormValue(name)
+	if value == "" {
+		fail(ctx, errMissingParam, "required parameter is missing: "+name)
+		return nil, false
+	}
+	id, err := primitive.ObjectIDFromHex(value)
+	if err != nil {
+		fail(ctx, errNotFound, "no item with id "+value)
+		return nil, false
+	}
+	return &id, true
+}
+
+// stream serves the file of a track as it is stored; transcoding
+// parameters are ignored.
+func (c *Controller) stream(ctx *gin.Context) {
+	id, ok := objectId(ctx, "id")
+	if !ok {
+		return
+	}
+	track, err := c.trackService.FindTrack(ctx.Request.Context(), id)
+	if err != nil {
+		serviceFail(ctx, err)
+		return
+	}
+	if track.Unavailable {
+		fail(ctx, errNotFound, "track file is unavailable")
+		return
+	}
+	// Only files in the library are served, whatever the track says.
+	path, ok := helper.LibraryFile(track.FileName)
+	if !ok {
+		fail(ctx, errNotFound, "track file not found")
+		return
+	}
+	ctx.File(path)
+}
+
+// getCoverArt serves the cover of an album, the id coverArt carries. A
+// cover that is an http(s) URL is redirected to; a file is served only
+// from the library.
+func (c *Controller) getCoverArt(ctx *gin.Context) {
+	id, ok := objectId(ctx, "id")
+	if !ok {
+		return
+	}
+	album, err := c.albumService.FindAlbum(ctx.Request.Context(), id)
+	if errors.Is(err, services.ErrNotFound) {
+		// Clients also ask with the id of a song.
+		var albums []models.Album
+		if albums, err = c.albumService.FindAlbumsByTracks(ctx.Request.Context(), []string{id.Hex()}); err == nil {
+			if len(albums) == 0 {
+				fail(ctx, errNotFound, "cover art not found")
+				return
+			}
+			album = &albums[0]
+		}
+	}
+	if err != nil {
+		serviceFail(ctx, err)
+		return
+	}
+	cover := album.AlbumCover
+	if strings.HasPrefix(cover, "http://") || strings.HasPrefix(cover, "https://") {
+		ctx.Redirect(http.StatusFound, cover)
+		return
+	}
+	if cover == "" {
+		fail(ctx, errNotFound, "album has no cover art")
+		return
+	}
+	path, ok := helper.LibraryFile(cover)
+	if !ok {
+		fail(ctx, errNotFound, "cover art not found")
+		return
+	}
+	ctx.File(path)
+}
+
+// scrobble records a play of each id when submission is true, the
+// default; "now playing" notifications are accepted and dropped. Plays are
+// recorded at the time they are received, whatever time says.
+func (c *Controller) scrobble(ctx *gin.Context) {
+	if err := ctx.Request.ParseForm(); err != nil {
+		fail(ctx, errGeneric, err.Error())
+		return
+	}
+	ids := ctx.Request.Form["id"]
+	if len(ids) == 0 {
+		fail(ctx, errMissingParam, "required parameter is missing: id")
+		return
+	}
+	if ctx.Request.FormValue("submission") == "false" {
+		write(ctx, newResponse())
+		return
+	}
+	user := currentUser(ctx)
+	for _, value := range ids {
+		id, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			fail(ctx, errNotFound, "no item with id "+value)
+			return
+		}
+		play := models.Play{TrackId: id.Hex(), UserId: user.UserId}
+		if err := c.trackService.RecordPlay(ctx.Request.Context(), &play); err != nil {
+			serviceFail(ctx, err)
+			return
+		}
+	}
+	write(ctx, newResponse())
+}
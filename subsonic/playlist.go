@@ -0,0 +1,93 @@
+package subsonic
+
+import (
+	"log"
+	"musiclib/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// toPlaylist maps playlist with the tracks its rules select. Playlists can
+// be read by anyone, so they are all public.
+func toPlaylist(playlist *models.Playlist, tracks []models.Track, owner string) Playlist {
+	result := Playlist{
+		Id:        playlist.PlaylistId,
+		Name:      playlist.Name,
+		Owner:     owner,
+		Public:    true,
+		SongCount: len(tracks),
+		Created:   playlist.CreatedAt.UTC().Format(time.RFC3339),
+		Changed:   playlist.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+	for _, track := range tracks {
+		result.Duration += track.DurationSeconds
+	}
+	return result
+}
+
+func (c *Controller) getPlaylists(ctx *gin.Context) {
+	user := currentUser(ctx)
+	playlists, err := c.playlistService.GetUserPlaylists(ctx.Request.Context(), &user.UserId)
+	if err != nil {
+		serviceFail(ctx, err)
+		return
+	}
+	result := &Playlists{Playlist: []Playlist{}}
+	for _, playlist := range playlists {
+		tracks, err := c.playlistService.PreviewPlaylist(ctx.Request.Context(), &playlist.Rules)
+		if err != nil {
+			log.Println("err subsonic playlist tracks", playlist.PlaylistId, err)
+		}
+		result.Playlist = append(result.Playlist, toPlaylist(&playlist, tracks, user.Username))
+	}
+	response := newResponse()
+	response.Playlists = result
+	write(ctx, response)
+}
+
+func (c *Controller) getPlaylist(ctx *gin.Context) {
+	id, ok := objectId(ctx, "id")
+	if !ok {
+		return
+	}
+	playlist, tracks, err := c.playlistService.FindPlaylist(ctx.Request.Context(), id)
+	if err != nil {
+		serviceFail(ctx, err)
+		return
+	}
+	owner := ""
+	if user := currentUser(ctx); user.UserId == playlist.UserId {
+		owner = user.Username
+	}
+	result := &PlaylistWithSongs{Playlist: toPlaylist(playlist, tracks, owner), Entry: []Song{}}
+	for _, track := range tracks {
+		result.Entry = append(result.Entry, toSong(&track, nil, 0))
+	}
+	response := newResponse()
+	response.Playlist = result
+	write(ctx, response)
+}
+
+// createPlaylist and updatePlaylist are refused: playlists are smart
+// playlists, whose tracks are picked by rules rather than listed.
+func (c *Controller) createPlaylist(ctx *gin.Context) {
+	fail(ctx, errGeneric, "playlists are smart playlists, create them with POST /v1/playlist/create")
+}
+
+func (c *Controller) updatePlaylist(ctx *gin.Context) {
+	fail(ctx, errGeneric, "playlists are smart playlists, edit their rules with PUT /v1/playlist/update/{id}")
+}
+
+func (c *Controller) deletePlaylist(ctx *gin.Context) {
+	id, ok := objectId(ctx, "id")
+	if !ok {
+		return
+	}
+	user := currentUser(ctx)
+	if err := c.playlistService.DeletePlaylist(ctx.Request.Context(), id, &user.UserId); err != nil {
+		serviceFail(ctx, err)
+		return
+	}
+	write(ctx, newResponse())
+}
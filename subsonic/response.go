@@ -0,0 +1,200 @@
+package subsonic
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version is the Subsonic API version served.
+const Version = "1.16.1"
+
+// Error codes of the Subsonic API.
+const (
+	errGeneric         = 0
+	errMissingParam    = 10
+	errWrongCredential = 40
+	errNotAuthorized   = 50
+	errNotFound        = 70
+)
+
+// Response is the subsonic-response envelope. It is written as XML, or as
+// JSON when the client asks with f=json or f=jsonp; attributes in XML are
+// fields in JSON and repeated elements are arrays.
+type Response struct {
+	XMLName       xml.Name `xml:"subsonic-response" json:"-"`
+	Xmlns         string   `xml:"xmlns,attr" json:"-"`
+	Status        string   `xml:"status,attr" json:"status"`
+	Version       string   `xml:"version,attr" json:"version"`
+	Type          string   `xml:"type,attr" json:"type"`
+	ServerVersion string   `xml:"serverVersion,attr" json:"serverVersion"`
+	OpenSubsonic  bool     `xml:"openSubsonic,attr" json:"openSubsonic"`
+
+	Error                  *Error             `xml:"error,omitempty" json:"error,omitempty"`
+	License                *License           `xml:"license,omitempty" json:"license,omitempty"`
+	OpenSubsonicExtensions []Extension        `xml:"openSubsonicExtensions,omitempty" json:"openSubsonicExtensions,omitempty"`
+	MusicFolders           *MusicFolders      `xml:"musicFolders,omitempty" json:"musicFolders,omitempty"`
+	Artists                *Artists           `xml:"artists,omitempty" json:"artists,omitempty"`
+	Artist                 *ArtistWithAlbums  `xml:"artist,omitempty" json:"artist,omitempty"`
+	Album                  *AlbumWithSongs    `xml:"album,omitempty" json:"album,omitempty"`
+	SearchResult3          *SearchResult3     `xml:"searchResult3,omitempty" json:"searchResult3,omitempty"`
+	Playlists              *Playlists         `xml:"playlists,omitempty" json:"playlists,omitempty"`
+	Playlist               *PlaylistWithSongs `xml:"playlist,omitempty" json:"playlist,omitempty"`
+}
+
+type Error struct {
+	Code    int    `xml:"code,attr" json:"code"`
+	Message string `xml:"message,attr" json:"message"`
+}
+
+type License struct {
+	Valid bool `xml:"valid,attr" json:"valid"`
+}
+
+type Extension struct {
+	Name     string `xml:"name,attr" json:"name"`
+	Versions []int  `xml:"versions" json:"versions"`
+}
+
+type MusicFolders struct {
+	MusicFolder []MusicFolder `xml:"musicFolder" json:"musicFolder"`
+}
+
+type MusicFolder struct {
+	Id   int    `xml:"id,attr" json:"id"`
+	Name string `xml:"name,attr" json:"name"`
+}
+
+type Artists struct {
+	IgnoredArticles string  `xml:"ignoredArticles,attr" json:"ignoredArticles"`
+	Index           []Index `xml:"index" json:"index"`
+}
+
+type Index struct {
+	Name   string   `xml:"name,attr" json:"name"`
+	Artist []Artist `xml:"artist" json:"artist"`
+}
+
+type Artist struct {
+	Id         string `xml:"id,attr" json:"id"`
+	Name       string `xml:"name,attr" json:"name"`
+	AlbumCount int    `xml:"albumCount,attr" json:"albumCount"`
+	CoverArt   string `xml:"coverArt,attr,omitempty" json:"coverArt,omitempty"`
+}
+
+type ArtistWithAlbums struct {
+	Artist
+	Album []Album `xml:"album" json:"album"`
+}
+
+type Album struct {
+	Id        string `xml:"id,attr" json:"id"`
+	Name      string `xml:"name,attr" json:"name"`
+	Artist    string `xml:"artist,attr,omitempty" json:"artist,omitempty"`
+	ArtistId  string `xml:"artistId,attr,omitempty" json:"artistId,omitempty"`
+	CoverArt  string `xml:"coverArt,attr,omitempty" json:"coverArt,omitempty"`
+	SongCount int    `xml:"songCount,attr" json:"songCount"`
+	Duration  int    `xml:"duration,attr" json:"duration"`
+	Created   string `xml:"created,attr" json:"created"`
+	Year      int    `xml:"year,attr,omitempty" json:"year,omitempty"`
+	Genre     string `xml:"genre,attr,omitempty" json:"genre,omitempty"`
+}
+
+type AlbumWithSongs struct {
+	Album
+	Song []Song `xml:"song" json:"song"`
+}
+
+// Song is a Child of the Subsonic API that is a track.
+type Song struct {
+	Id          string `xml:"id,attr" json:"id"`
+	Parent      string `xml:"parent,attr,omitempty" json:"parent,omitempty"`
+	IsDir       bool   `xml:"isDir,attr" json:"isDir"`
+	Title       string `xml:"title,attr" json:"title"`
+	Album       string `xml:"album,attr,omitempty" json:"album,omitempty"`
+	Artist      string `xml:"artist,attr,omitempty" json:"artist,omitempty"`
+	Track       int    `xml:"track,attr,omitempty" json:"track,omitempty"`
+	Year        int    `xml:"year,attr,omitempty" json:"year,omitempty"`
+	Genre       string `xml:"genre,attr,omitempty" json:"genre,omitempty"`
+	CoverArt    string `xml:"coverArt,attr,omitempty" json:"coverArt,omitempty"`
+	Size        int64  `xml:"size,attr,omitempty" json:"size,omitempty"`
+	ContentType string `xml:"contentType,attr,omitempty" json:"contentType,omitempty"`
+	Suffix      string `xml:"suffix,attr,omitempty" json:"suffix,omitempty"`
+	Duration    int    `xml:"duration,attr,omitempty" json:"duration,omitempty"`
+	Path        string `xml:"path,attr,omitempty" json:"path,omitempty"`
+	AlbumId     string `xml:"albumId,attr,omitempty" json:"albumId,omitempty"`
+	ArtistId    string `xml:"artistId,attr,omitempty" json:"artistId,omitempty"`
+	Type        string `xml:"type,attr" json:"type"`
+}
+
+type SearchResult3 struct {
+	Artist []Artist `xml:"artist" json:"artist"`
+	Album  []Album  `xml:"album" json:"album"`
+	Song   []Song   `xml:"song" json:"song"`
+}
+
+type Playlists struct {
+	Playlist []Playlist `xml:"playlist" json:"playlist"`
+}
+
+type Playlist struct {
+	Id        string `xml:"id,attr" json:"id"`
+	Name      string `xml:"name,attr" json:"name"`
+	Owner     string `xml:"owner,attr,omitempty" json:"owner,omitempty"`
+	Public    bool   `xml:"public,attr" json:"public"`
+	SongCount int    `xml:"songCount,attr" json:"songCount"`
+	Duration  int    `xml:"duration,attr" json:"duration"`
+	Created   string `xml:"created,attr" json:"created"`
+	Changed   string `xml:"changed,attr" json:"changed"`
+}
+
+type PlaylistWithSongs struct {
+	Playlist
+	Entry []Song `xml:"entry" json:"entry"`
+}
+
+func newResponse() *Response {
+	return &Response{
+		Xmlns:         "http://subsonic.org/restapi",
+		Status:        "ok",
+		Version:       Version,
+		Type:          "musiclib",
+		ServerVersion: "1.0",
+		OpenSubsonic:  true,
+	}
+}
+
+// callbackName is what a JSONP callback may be called, so it cannot
+// inject script.
+var callbackName = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$.]*$`)
+
+// write answers response in the format the client asked for. Subsonic
+// clients expect 200 even for failures.
+func write(ctx *gin.Context, response *Response) {
+	switch ctx.Request.FormValue("f") {
+	case "json":
+		ctx.JSON(http.StatusOK, gin.H{"subsonic-response": response})
+	case "jsonp":
+		callback := ctx.Request.FormValue("callback")
+		data, err := json.Marshal(gin.H{"subsonic-response": response})
+		if err != nil || !callbackName.MatchString(callback) {
+			ctx.JSON(http.StatusOK, gin.H{"subsonic-response": response})
+			return
+		}
+		ctx.Data(http.StatusOK, "application/javascript; charset=utf-8", []byte(callback+"("+string(data)+");"))
+	default:
+		ctx.XML(http.StatusOK, response)
+	}
+}
+
+// fail answers a failed response with code and message.
+func fail(ctx *gin.Context, code int, message string) {
+	response := newResponse()
+	response.Status = "failed"
+	response.Error = &Error{Code: code, Message: message}
+	write(ctx, response)
+	ctx.Abort()
+}